@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// groupDocs sets the Doc of each group to the synopsis of its package
+// comment, for -apiDocComment. Groups declared by //fsrouter:group without
+// a directory of their own keep an empty Doc.
+func groupDocs(fsys fs.FS, groups []group) error {
+	for i := range groups {
+		synopsis, err := packageSynopsis(fsys, groups[i].Name)
+		if err != nil {
+			return err
+		}
+		groups[i].Doc = synopsis
+	}
+	return nil
+}
+
+// packageSynopsis returns the first sentence of the package comment of the
+// Go files in dir, preferring doc.go, or "" when there is none.
+func packageSynopsis(fsys fs.FS, dir string) (string, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var files []string
+	for _, e := range entries {
+		if name := e.Name(); !e.IsDir() && strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") {
+			files = append(files, path.Join(dir, name))
+		}
+	}
+	if i := slices.Index(files, path.Join(dir, "doc.go")); i > 0 {
+		files[0], files[i] = files[i], files[0]
+	}
+	for _, p := range files {
+		src, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return "", err
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), p, src, parser.PackageClauseOnly|parser.ParseComments)
+		if err != nil {
+			return "", err
+		}
+		if file.Doc != nil {
+			if synopsis := new(doc.Package).Synopsis(file.Doc.Text()); synopsis != "" {
+				return synopsis, nil
+			}
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const optionsTemplate = `
+// optionsHandler answers OPTIONS requests for a path that has no OPTIONS
+// handler, listing the methods it serves in the Allow header
+func optionsHandler(allow string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+{{if .CORS}}
+// corsPathMethods are the Allow and Access-Control-Allow-Methods values of
+// each route path template, used by corsMiddleware to answer preflight
+// requests with the methods of the requested path
+var corsPathMethods = map[string]struct{ allow, cors string }{
+{{range .OptionsPaths}}	{{printf "%q" .Path}}: {{"{"}}{{printf "%q" .Allow}}, {{printf "%q" .CORS}}},
+{{end}}}
+{{end}}`
+
+// optionsPath is the method set of one route path, as answered to OPTIONS
+// requests.
+type optionsPath struct {
+	// Path is the path template mux reports for the path's routes.
+	Path string
+	// Allow lists every method served, CORS those also allowed by -cors.
+	Allow string
+	CORS  string
+}
+
+// autoOptions returns routes with an OPTIONS route added after the last
+// route of each path lacking an OPTIONS handler, keeping registration order,
+// and the method set of every path. A path with a fallback gets no OPTIONS
+// route, since the fallback answers OPTIONS itself. corsMethods are the
+// methods -cors allows, or nil without CORS. encoded escapes the paths like
+// -encodedPath does for the registered ones.
+func autoOptions(routes []route, corsMethods []string, encoded bool) ([]route, []optionsPath) {
+	methods := make(map[string][]string)
+	last := make(map[string]int)
+	var paths []string
+	for i, rt := range routes {
+		if _, seen := last[rt.RoutePath]; !seen {
+			paths = append(paths, rt.RoutePath)
+		}
+		if rt.Method != "" {
+			methods[rt.RoutePath] = append(methods[rt.RoutePath], rt.Method)
+		}
+		last[rt.RoutePath] = i
+	}
+
+	var optionsPaths []optionsPath
+	allow := make(map[string]string)
+	for _, p := range paths {
+		ms := methods[p]
+		if !slices.Contains(ms, "OPTIONS") {
+			ms = append(ms, "OPTIONS")
+		}
+		slices.Sort(ms)
+		ms = slices.Compact(ms)
+		op := optionsPath{Path: p, Allow: strings.Join(ms, ", ")}
+		allow[p] = op.Allow
+		if encoded {
+			op.Path = escapeNonASCII(p)
+		}
+		if corsMethods != nil {
+			op.CORS = strings.Join(slices.DeleteFunc(slices.Clone(ms), func(m string) bool {
+				return !slices.Contains(corsMethods, m)
+			}), ", ")
+		}
+		optionsPaths = append(optionsPaths, op)
+	}
+
+	var out []route
+	for i, rt := range routes {
+		out = append(out, rt)
+		if last[rt.RoutePath] != i || slices.Contains(methods[rt.RoutePath], "OPTIONS") || hasFallback(routes, rt.RoutePath) {
+			continue
+		}
+		handler := "optionsHandler(" + strconv.Quote(allow[rt.RoutePath]) + ")"
+		out = append(out, route{
+			Method:      "OPTIONS",
+			RoutePath:   rt.RoutePath,
+			SubPath:     rt.SubPath,
+			Dir:         rt.Dir,
+			Group:       rt.Group,
+			Router:      rt.Router,
+			Guard:       pathGuard(routes, rt.RoutePath),
+			Handler:     handler,
+			HandlerExpr: handler,
+		})
+	}
+	return out, optionsPaths
+}
+
+// pathGuard returns the guard shared by all routes of path, or "" when any
+// of them is registered unconditionally or their guards differ.
+func pathGuard(routes []route, path string) string {
+	guard, first := "", true
+	for _, rt := range routes {
+		if rt.RoutePath != path {
+			continue
+		}
+		if first {
+			guard, first = rt.Guard, false
+		} else if rt.Guard != guard {
+			return ""
+		}
+	}
+	return guard
+}
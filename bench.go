@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+const benchTemplate = `// Code generated by fsrouter; DO NOT EDIT.
+package {{.Package}}
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// benchRequests are one representative request per generated route
+var benchRequests = []struct {
+	name   string
+	method string
+	path   string
+	header []string
+}{
+{{range .Requests}}	{ {{printf "%q" .Name}}, {{printf "%q" .Method}}, {{printf "%q" .Path}}, {{if .Header}}[]string{ {{range $i, $h := .Header}}{{if $i}}, {{end}}{{printf "%q" $h}}{{end}} }{{else}}nil{{end}} },
+{{end}}}
+
+{{if .Env}}// setBenchEnv sets the environment variables the env-gated routes are
+// registered with
+func setBenchEnv(b *testing.B) {
+{{range .Env}}	b.Setenv({{printf "%q" .}}, "1")
+{{end}}}
+
+{{end}}// benchmarkRoutes routes each request in a sub-benchmark of its own
+func benchmarkRoutes(b *testing.B, r http.Handler) {
+	for _, br := range benchRequests {
+		b.Run(br.name, func(b *testing.B) {
+			req := httptest.NewRequest(br.method, br.path, nil)
+			for i := 0; i+1 < len(br.header); i += 2 {
+				req.Header.Set(br.header[i], br.header[i+1])
+			}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r.ServeHTTP(httptest.NewRecorder(), req)
+			}
+		})
+	}
+}
+
+// BenchmarkRoutes measures each route of RegisterRoutes, middleware and
+// handler included
+func BenchmarkRoutes(b *testing.B) {
+{{if .Env}}	setBenchEnv(b)
+{{end}}	benchmarkRoutes(b, RegisterRoutes({{.Call}}))
+}
+{{if .Raw}}
+// BenchmarkRoutesRaw measures each route of RegisterRoutesRaw, without
+// middleware
+func BenchmarkRoutesRaw(b *testing.B) {
+{{if .Env}}	setBenchEnv(b)
+{{end}}	benchmarkRoutes(b, RegisterRoutesRaw({{.Call}}))
+}
+{{end}}
+// BenchmarkAllRoutes sends one request to every route per iteration, the
+// cost of routing that grows with the API
+func BenchmarkAllRoutes(b *testing.B) {
+{{if .Env}}	setBenchEnv(b)
+{{end}}	r := RegisterRoutes({{.Call}})
+	reqs := make([]*http.Request, len(benchRequests))
+	for i, br := range benchRequests {
+		reqs[i] = httptest.NewRequest(br.method, br.path, nil)
+		for j := 0; j+1 < len(br.header); j += 2 {
+			reqs[i].Header.Set(br.header[j], br.header[j+1])
+		}
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, req := range reqs {
+			r.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	}
+}
+`
+
+// benchRequest is a request the -genBench harness sends to one route.
+type benchRequest struct {
+	Name   string
+	Method string
+	Path   string
+	Header []string
+}
+
+// benchSamples are the values filled in for typed parameters, so the
+// generated parsers accept them.
+var benchSamples = map[string]string{
+	"int": "1", "int64": "1", "uint64": "1", "float64": "1.5", "bool": "true",
+}
+
+// benchFallbackMethods are tried in order for a fallback route, which
+// answers the first one the other handlers of its path do not register.
+var benchFallbackMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+
+// writeBench generates a go test -bench harness sending a request to each
+// route, and returns the number of requests. out must be a _test.go file
+// next to the router, in package pkg. stripPrefix is prepended to the
+// paths, and raw adds a benchmark of RegisterRoutesRaw. Tagged routes are
+// requested by passing all their tags to RegisterRoutes.
+func writeBench(w *outputWriter, out, routerOut, pkg string, routes []route, stripPrefix string, raw bool) (int, error) {
+	if !strings.HasSuffix(out, "_test.go") {
+		return 0, fmt.Errorf("%s must be a _test.go file", out)
+	}
+	dir, _ := filepath.Abs(filepath.Dir(out))
+	routerDir, _ := filepath.Abs(filepath.Dir(routerOut))
+	if dir != routerDir {
+		return 0, fmt.Errorf("%s must be in the directory of %s, whose package it tests", out, routerOut)
+	}
+
+	methods := make(map[string][]string)
+	for _, rt := range routes {
+		methods[rt.RoutePath] = append(methods[rt.RoutePath], rt.Method)
+	}
+	var requests []benchRequest
+	var tags, env []string
+	for _, rt := range routes {
+		req := benchRequest{Name: rt.methodLabel() + " " + rt.RoutePath, Method: rt.Method, Path: stripPrefix + benchPath(rt), Header: rt.Headers}
+		if len(rt.Schemes) > 0 && !slices.Contains(rt.Schemes, "http") {
+			// httptest marks requests for an https URL as TLS, which is
+			// what mux's Schemes matcher checks.
+			req.Path = "https://example.com" + req.Path
+			req.Name += " https"
+		}
+		if rt.Method == "" {
+			req.Method = benchFallbackMethods[0]
+			for _, m := range benchFallbackMethods {
+				if !slices.Contains(methods[rt.RoutePath], m) {
+					req.Method = m
+					break
+				}
+			}
+		}
+		for i := 0; i+1 < len(req.Header); i += 2 {
+			if req.Header[i+1] == "" {
+				req.Header = slices.Clone(req.Header)
+				req.Header[i+1] = "x"
+			}
+			req.Name += " " + req.Header[i] + "=" + req.Header[i+1]
+		}
+		for _, tag := range rt.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		if rt.EnabledIf != "" && !slices.Contains(env, rt.EnabledIf) {
+			env = append(env, rt.EnabledIf)
+		}
+		requests = append(requests, req)
+	}
+	slices.Sort(tags)
+	slices.Sort(env)
+
+	var quoted []string
+	for _, tag := range tags {
+		quoted = append(quoted, fmt.Sprintf("%q", tag))
+	}
+
+	var buf bytes.Buffer
+	tmpl := template.Must(template.New("bench").Parse(benchTemplate))
+	err := tmpl.Execute(&buf, struct {
+		Package  string
+		Requests []benchRequest
+		Env      []string
+		Call     string
+		Raw      bool
+	}{
+		Package:  pkg,
+		Requests: requests,
+		Env:      env,
+		Call:     strings.Join(quoted, ", "),
+		Raw:      raw,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return 0, fmt.Errorf("formatting generated benchmarks: %w", err)
+	}
+	return len(requests), w.write(out, src)
+}
+
+// benchPath fills in the {param} segments of a route path: typed
+// parameters get a value of their type, catch-alls two segments and other
+// parameters the word sample.
+func benchPath(rt route) string {
+	segs := strings.Split(rt.RoutePath, "/")
+	for i, seg := range segs {
+		if !strings.HasPrefix(seg, "{") {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.Trim(seg, "{}"), ":")
+		segs[i] = "sample"
+		if isCatchAllSegment(seg) {
+			segs[i] = "a/b"
+		}
+		for _, p := range rt.Params {
+			if p.Name == name {
+				segs[i] = benchSamples[p.Type]
+			}
+		}
+	}
+	return strings.Join(segs, "/")
+}
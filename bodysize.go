@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxBodySizeName is the middleware factory generated for -maxBodySize
+// and for group or method middleware calling it.
+const maxBodySizeName = "maxBodySize"
+
+const maxBodySizeTemplate = `
+// maxBodySize limits request bodies to limit bytes, answering 413 Request
+// Entity Too Large up front when the declared Content-Length exceeds it.
+// Larger bodies without one fail to read with an *http.MaxBytesError
+func maxBodySize(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+`
+
+// sizeUnits are the suffixes a size flag may carry, as powers of two.
+var sizeUnits = []struct {
+	suffix string
+	shift  int
+}{
+	{"KB", 10},
+	{"MB", 20},
+	{"GB", 30},
+	{"B", 0},
+}
+
+// parseMaxBodySize parses a -maxBodySize into the maxBodySize call
+// applying it, e.g. maxBodySize(1 << 20).
+func parseMaxBodySize(value string) (string, error) {
+	size, err := parseSize(value)
+	if err != nil {
+		return "", err
+	}
+	return maxBodySizeName + "(" + size + ")", nil
+}
+
+// parseSize parses a human-readable size such as 1MB or 512KB, where units
+// are powers of 1024, into a Go constant expression such as 1 << 20.
+func parseSize(value string) (string, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	shift := 0
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s, shift = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.shift
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 || n > 1<<(62-shift) {
+		return "", fmt.Errorf("invalid size %q, want a positive whole number of bytes, KB, MB or GB such as 1MB", value)
+	}
+	if shift == 0 {
+		return strconv.FormatInt(n, 10), nil
+	}
+	return fmt.Sprintf("%d << %d", n, shift), nil
+}
+
+// isMaxBodySizeCall reports whether a middleware entry is a call of the
+// generated maxBodySize factory.
+func isMaxBodySizeCall(mw string) bool {
+	return strings.HasPrefix(mw, maxBodySizeName+"(")
+}
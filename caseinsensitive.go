@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// caseInsensitiveImports are the standard library packages used by
+// caseInsensitiveTemplate.
+var caseInsensitiveImports = []string{"context", "strings"}
+
+const caseInsensitiveTemplate = `
+// OriginalPathHeader carries the request path as sent, set by
+// caseInsensitive
+const OriginalPathHeader = "X-Original-Path"
+
+// OriginalPathKey is the context key under which caseInsensitive stores the
+// request path as sent, before lowercasing
+type OriginalPathKey struct{}
+
+// OriginalPathFromContext returns the request path as sent by the client, or
+// "" outside caseInsensitive
+func OriginalPathFromContext(ctx context.Context) string {
+	path, _ := ctx.Value(OriginalPathKey{}).(string)
+	return path
+}
+
+// caseInsensitive returns a router that lowercases request paths before h
+// routes them, since mux matches paths case-sensitively. The original path
+// is kept in the request context and the X-Original-Path request header
+func caseInsensitive(h http.Handler, notFound http.Handler) *mux.Router {
+	outer := mux.NewRouter()
+	outer.SkipClean(true)
+	outer.NotFoundHandler = notFound
+	outer.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		original := r.URL.Path
+		r = r.WithContext(context.WithValue(r.Context(), OriginalPathKey{}, original))
+		u := *r.URL
+		u.Path = strings.ToLower(u.Path)
+		u.RawPath = strings.ToLower(u.RawPath)
+		r.URL = &u
+		r.Header = r.Header.Clone()
+		r.Header.Set(OriginalPathHeader, original)
+		h.ServeHTTP(w, r)
+	})
+	return outer
+}
+`
+
+// checkLowercasePaths reports the first registered path with an uppercase
+// letter outside its {param} segments, which -caseInsensitive could never
+// match once request paths are lowercased.
+func checkLowercasePaths(routes []route, redirects []redirect, stripPrefix, specPath, root string) error {
+	for _, rt := range routes {
+		if hasUpperLiteral(rt.RoutePath) {
+			return fmt.Errorf("route %s %s from %s has uppercase letters, rename the directory or give it a lowercase _path", rt.methodLabel(), rt.RoutePath, filepath.Join(root, filepath.FromSlash(rt.Dir)))
+		}
+	}
+	for _, rd := range redirects {
+		if hasUpperLiteral(rd.From) {
+			return fmt.Errorf("%s: redirect from %s has uppercase letters", rd.Source, rd.From)
+		}
+	}
+	if hasUpperLiteral(stripPrefix) {
+		return fmt.Errorf("stripPrefix %s has uppercase letters", stripPrefix)
+	}
+	if hasUpperLiteral(specPath) {
+		return fmt.Errorf("serveSpec %s has uppercase letters", specPath)
+	}
+	return nil
+}
+
+// hasUpperLiteral reports whether a route path has an uppercase letter
+// outside {param} segments.
+func hasUpperLiteral(path string) bool {
+	for _, seg := range strings.Split(path, "/") {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			continue
+		}
+		if strings.IndexFunc(seg, unicode.IsUpper) >= 0 {
+			return true
+		}
+	}
+	return false
+}
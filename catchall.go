@@ -0,0 +1,63 @@
+package main
+
+import "strings"
+
+// catchAllPrefix starts the name of a catch-all directory such as
+// [...path], whose parameter matches the rest of the path, slashes
+// included. A trailing ? as in [...path?] makes it match as little as
+// possible, which only matters when a later catch-all could take the same
+// segments, since mux matches route paths to the end.
+const catchAllPrefix = "..."
+
+// catchAllSlashName is the wrapper generated for //fsrouter:catchAll slash.
+const catchAllSlashName = "catchAllSlash"
+
+const catchAllSlashTemplate = `
+// catchAllSlash prefixes the value of the catch-all variable name with the
+// slash mux matches before it, so handlers see /a/b rather than a/b
+func catchAllSlash(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			vars := make(map[string]string)
+			for k, v := range mux.Vars(r) {
+				vars[k] = v
+			}
+			vars[name] = "/" + vars[name]
+			next.ServeHTTP(w, mux.SetURLVars(r, vars))
+		})
+	}
+}
+`
+
+// catchAll returns the parameter name and mux pattern of a catch-all
+// parameter named in a directory segment: ...path gives path and .*, and
+// ...path? gives path and .*?. ok is false for other parameters.
+func catchAll(name string) (param, pattern string, ok bool) {
+	rest, ok := strings.CutPrefix(name, catchAllPrefix)
+	if !ok {
+		return "", "", false
+	}
+	if param, lazy := strings.CutSuffix(rest, "?"); lazy {
+		return param, ".*?", true
+	}
+	return rest, ".*", true
+}
+
+// isCatchAllSegment reports whether a route path segment is a catch-all
+// {param}, such as {path:.*}.
+func isCatchAllSegment(seg string) bool {
+	return strings.HasPrefix(seg, "{") && (strings.HasSuffix(seg, ":.*}") || strings.HasSuffix(seg, ":.*?}"))
+}
+
+// lastCatchAll returns the name of the last catch-all parameter of a route
+// path, or "" when it has none.
+func lastCatchAll(path string) string {
+	segs := strings.Split(path, "/")
+	for i := len(segs) - 1; i >= 0; i-- {
+		if isCatchAllSegment(segs[i]) {
+			name, _, _ := strings.Cut(segs[i][1:], ":")
+			return name
+		}
+	}
+	return ""
+}
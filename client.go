@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+const clientTemplate = `// Code generated by fsrouter; DO NOT EDIT.
+package {{.Package}}
+
+import (
+	"context"
+	"io"
+	"net/http"
+{{if .UsesParams}}	"net/url"
+{{end}}	"strings"
+)
+
+// Client calls the API routes discovered by fsrouter.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewClient returns a Client for the API served at baseURL.
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: http.DefaultClient}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+{{range .Methods}}
+// {{.Name}} calls {{.Method}} {{.Route}}.
+func (c *Client) {{.Name}}(ctx context.Context{{range .Params}}, {{.}} string{{end}}{{if .Body}}, body io.Reader{{end}}) (*http.Response, error) {
+	return c.do(ctx, {{printf "%q" .Method}}, {{.PathExpr}}, {{if .Body}}body{{else}}nil{{end}})
+}
+{{end}}`
+
+// clientMethod is one generated Client method.
+type clientMethod struct {
+	Name     string
+	Method   string
+	Route    string
+	Params   []string
+	PathExpr string
+	Body     bool
+}
+
+// clientParamCases are the values -clientParamCase accepts, the default
+// first.
+var clientParamCases = []string{"camel", "snake", "raw"}
+
+// writeClient generates a typed HTTP client with one method per route,
+// naming path parameter arguments according to paramCase, and returns the
+// number of methods.
+func writeClient(w *outputWriter, out, pkg string, routes []route, paramCase string) (int, error) {
+	var methods []clientMethod
+	owners := make(map[string]string)
+	usesParams := false
+
+	for _, rt := range routes {
+		// A fallback has no method of its own to call, and internal routes
+		// are not for clients.
+		if rt.Method == "" || rt.Internal {
+			continue
+		}
+		m, err := newClientMethod(rt, paramCase)
+		if err != nil {
+			return 0, err
+		}
+		if prev, ok := owners[m.Name]; ok {
+			return 0, fmt.Errorf("client method %s would be generated for both %s and %s %s", m.Name, prev, rt.Method, rt.RoutePath)
+		}
+		owners[m.Name] = rt.Method + " " + rt.RoutePath
+		usesParams = usesParams || len(m.Params) > 0
+		methods = append(methods, m)
+	}
+
+	var buf bytes.Buffer
+	tmpl := template.Must(template.New("client").Parse(clientTemplate))
+	err := tmpl.Execute(&buf, struct {
+		Package    string
+		UsesParams bool
+		Methods    []clientMethod
+	}{
+		Package:    pkg,
+		UsesParams: usesParams,
+		Methods:    methods,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return 0, fmt.Errorf("formatting generated client: %w", err)
+	}
+	return len(methods), w.write(out, src)
+}
+
+// newClientMethod derives the client method for a route, e.g.
+// GET /users/{userId} becomes GetUsersUserID(ctx, userID) with the camel
+// paramCase.
+func newClientMethod(rt route, paramCase string) (clientMethod, error) {
+	m := clientMethod{
+		Method: rt.Method,
+		Route:  rt.RoutePath,
+		Body:   hasBody(rt.Method),
+	}
+
+	name := exportedName(strings.ToLower(rt.Method))
+	var parts []string
+	literal := ""
+	for _, seg := range strings.Split(strings.Trim(rt.RoutePath, "/"), "/") {
+		if seg == "" {
+			continue
+		}
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			param, _, _ := strings.Cut(seg[1:len(seg)-1], ":")
+			name += exportedName(param)
+			arg, err := clientParamName(param, paramCase)
+			if err != nil {
+				return clientMethod{}, fmt.Errorf("%s %s: %w", rt.Method, rt.RoutePath, err)
+			}
+			m.Params = append(m.Params, arg)
+			parts = append(parts, strconv.Quote(literal+"/"), "url.PathEscape("+arg+")")
+			literal = ""
+			continue
+		}
+		name += exportedName(seg)
+		literal += "/" + seg
+	}
+	if len(m.Params) == 0 && literal == "" {
+		name += "Root"
+		literal = "/"
+	}
+	if literal != "" {
+		parts = append(parts, strconv.Quote(literal))
+	}
+
+	m.Name = name
+	m.PathExpr = strings.Join(parts, " + ")
+	return m, nil
+}
+
+// commonInitialisms are words written in upper case in Go identifiers.
+var commonInitialisms = map[string]bool{
+	"api": true, "id": true, "ip": true, "json": true, "http": true,
+	"uri": true, "url": true, "uuid": true, "xml": true,
+}
+
+// identWords splits s into words at non-alphanumeric characters and
+// lower-to-upper case transitions, e.g. "user_profileId" becomes
+// ["user", "profile", "Id"].
+func identWords(s string) []string {
+	var words []string
+	var word []rune
+	prev := rune(0)
+	for _, c := range s {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = nil
+			}
+			prev = 0
+			continue
+		}
+		if unicode.IsUpper(c) && unicode.IsLower(prev) && len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+		word = append(word, c)
+		prev = c
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+// exportedName converts a path segment or param name to an exported Go
+// identifier part, e.g. "userId" becomes "UserID".
+func exportedName(s string) string {
+	var b strings.Builder
+	for _, w := range identWords(s) {
+		if commonInitialisms[strings.ToLower(w)] {
+			b.WriteString(strings.ToUpper(w))
+			continue
+		}
+		r := []rune(w)
+		b.WriteString(string(unicode.ToUpper(r[0])) + string(r[1:]))
+	}
+	return b.String()
+}
+
+// clientParamName converts a path param to a client method argument. In
+// camel case "user_id" becomes "userID", in snake case "userId" becomes
+// "user_id", and raw keeps the name, which must then be a Go identifier.
+func clientParamName(param, paramCase string) (string, error) {
+	words := identWords(param)
+	var name string
+	switch {
+	case paramCase == "raw":
+		if !token.IsIdentifier(param) {
+			return "", fmt.Errorf("parameter %q is not a valid Go identifier for -clientParamCase=raw", param)
+		}
+		name = param
+	case len(words) == 0:
+		return "param", nil
+	case paramCase == "snake":
+		name = strings.ToLower(strings.Join(words, "_"))
+	default:
+		name = strings.ToLower(words[0]) + exportedName(strings.Join(words[1:], "_"))
+	}
+	if unicode.IsDigit([]rune(name)[0]) {
+		name = "p" + name
+	}
+	switch {
+	case token.IsKeyword(name), name == "ctx", name == "body", name == "c":
+		name += "Param"
+	}
+	return name, nil
+}
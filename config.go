@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// configFileName is the project config discovered above the api directory.
+const configFileName = "fsrouter.yaml"
+
+// configSetting is one flag value of a config file, with its line number.
+type configSetting struct {
+	Flag  string
+	Value string
+	Line  int
+}
+
+// findConfig returns the fsrouter.yaml in dir or the closest of its
+// parents, like git finding .git. The search stops at the directory of the
+// go.mod holding dir, so one module's config is never applied to another.
+// It returns "" when no config is found.
+func findConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for d := dir; ; d = filepath.Dir(d) {
+		config := filepath.Join(d, configFileName)
+		if _, err := os.Stat(config); err == nil {
+			return config, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil || filepath.Dir(d) == d {
+			return "", nil
+		}
+	}
+}
+
+// parseConfig reads the settings of a config file: a flat YAML mapping from
+// flag names to scalar values, which may be quoted. JSON flag values such
+// as groupMiddlewares are written as single-quoted strings.
+func parseConfig(src string) ([]configSetting, error) {
+	var settings []configSetting
+	seen := make(map[string]bool)
+	for i, line := range strings.Split(src, "\n") {
+		content := strings.TrimRight(line, " \t\r")
+		trimmed := strings.TrimLeft(content, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		if trimmed != content {
+			return nil, fmt.Errorf("line %d: settings are a flat mapping of flag names to values, nested values are not supported", i+1)
+		}
+		key, rest, ok := strings.Cut(content, ":")
+		if !ok || key == "" || strings.ContainsAny(key, " \t\"'") {
+			return nil, fmt.Errorf("line %d: expected flag: value", i+1)
+		}
+		value, err := configValue(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", i+1, key, err)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("line %d: %s is set twice", i+1, key)
+		}
+		seen[key] = true
+		settings = append(settings, configSetting{Flag: key, Value: value, Line: i + 1})
+	}
+	return settings, nil
+}
+
+// configValue parses the scalar after a key's colon. Double-quoted values
+// use Go escapes, single-quoted ones double a quote to escape it, and
+// plain values end at a comment.
+func configValue(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, `"`):
+		quoted, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return "", fmt.Errorf("unterminated double-quoted value")
+		}
+		if err := configTrailer(s[len(quoted):]); err != nil {
+			return "", err
+		}
+		return strconv.Unquote(quoted)
+	case strings.HasPrefix(s, "'"):
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			if s[i] != '\'' {
+				b.WriteByte(s[i])
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '\'' {
+				b.WriteByte('\'')
+				i++
+				continue
+			}
+			return b.String(), configTrailer(s[i+1:])
+		}
+		return "", fmt.Errorf("unterminated single-quoted value")
+	}
+	return yamlValue(s), nil
+}
+
+// configTrailer reports text after a quoted value other than a comment.
+func configTrailer(s string) error {
+	if s = strings.TrimSpace(s); s != "" && !strings.HasPrefix(s, "#") {
+		return fmt.Errorf("unexpected %q after the quoted value", s)
+	}
+	return nil
+}
+
+// applyConfig sets the flags of settings that were not given on the
+// command line and returns their names. The api flag decides where the
+// config is found and the config flag which one is read, so neither may be
+// set by the file.
+func applyConfig(flags *flag.FlagSet, settings []configSetting) (map[string]bool, error) {
+	applied := make(map[string]bool)
+	given := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	for _, s := range settings {
+		switch {
+		case s.Flag == "api" || s.Flag == "config":
+			return nil, fmt.Errorf("line %d: %s cannot be set in a config file", s.Line, s.Flag)
+		case flags.Lookup(s.Flag) == nil:
+			return nil, fmt.Errorf("line %d: unknown flag %s", s.Line, s.Flag)
+		case given[s.Flag]:
+			continue
+		}
+		if err := flags.Set(s.Flag, s.Value); err != nil {
+			return nil, fmt.Errorf("line %d: invalid value %q for %s: %v", s.Line, s.Value, s.Flag, err)
+		}
+		applied[s.Flag] = true
+	}
+	return applied, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name, src string
+		want      []configSetting
+		// err is a substring of the error, "" for none
+		err string
+	}{
+		{"plain", "---\n# routes\nout: routes_gen.go # generated\n\nautoOptions: true\n", []configSetting{
+			{Flag: "out", Value: "routes_gen.go", Line: 3},
+			{Flag: "autoOptions", Value: "true", Line: 5},
+		}, ""},
+		{"single-quoted", `groupMiddlewares: '{"users":"auth"}'` + "\nnotFoundBody: 'it''s gone' # custom\n", []configSetting{
+			{Flag: "groupMiddlewares", Value: `{"users":"auth"}`, Line: 1},
+			{Flag: "notFoundBody", Value: "it's gone", Line: 2},
+		}, ""},
+		{"double-quoted", `notFoundBody: "say \"%s\"\n" # trailing comment` + "\n", []configSetting{
+			{Flag: "notFoundBody", Value: "say \"%s\"\n", Line: 1},
+		}, ""},
+		{"text after a quoted value", `out: "a.go" b.go`, nil, `line 1: out: unexpected "b.go" after the quoted value`},
+		{"unterminated single quote", "out: 'a.go\n", nil, "line 1: out: unterminated single-quoted value"},
+		{"unterminated double quote", `out: "a.go`, nil, "line 1: out: unterminated double-quoted value"},
+		{"indented", "cors:\n  origins: '*'\n", nil, "line 2: settings are a flat mapping"},
+		{"no colon", "autoOptions\n", nil, "line 1: expected flag: value"},
+		{"duplicate", "out: a.go\ngzip: true\nout: b.go\n", nil, "line 3: out is set twice"},
+	} {
+		got, err := parseConfig(tc.src)
+		switch {
+		case tc.err != "":
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: got error %v, want one containing %q", tc.name, err, tc.err)
+			}
+		case err != nil:
+			t.Errorf("%s: %v", tc.name, err)
+		case !slices.Equal(got, tc.want):
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	newFlags := func(args ...string) *flag.FlagSet {
+		flags := flag.NewFlagSet("fsrouter", flag.ContinueOnError)
+		flags.String("api", "api", "")
+		flags.String("config", "", "")
+		flags.String("out", "routes_gen.go", "")
+		flags.Bool("gzip", false, "")
+		if err := flags.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return flags
+	}
+
+	flags := newFlags("-out=cli.go")
+	applied, err := applyConfig(flags, []configSetting{
+		{Flag: "out", Value: "config.go", Line: 1},
+		{Flag: "gzip", Value: "true", Line: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := flags.Lookup("out").Value.String(); out != "cli.go" {
+		t.Errorf("out = %s, want the command line's cli.go", out)
+	}
+	if gzip := flags.Lookup("gzip").Value.String(); gzip != "true" {
+		t.Errorf("gzip = %s, want the config's true", gzip)
+	}
+	if !applied["gzip"] || applied["out"] {
+		t.Errorf("applied %v, want only gzip", applied)
+	}
+
+	for _, tc := range []struct {
+		setting configSetting
+		err     string
+	}{
+		{configSetting{Flag: "api", Value: "src/api", Line: 4}, "line 4: api cannot be set in a config file"},
+		{configSetting{Flag: "config", Value: "other.yaml", Line: 2}, "line 2: config cannot be set in a config file"},
+		{configSetting{Flag: "nope", Value: "1", Line: 3}, "line 3: unknown flag nope"},
+		{configSetting{Flag: "gzip", Value: "sometimes", Line: 5}, `line 5: invalid value "sometimes" for gzip`},
+	} {
+		if _, err := applyConfig(newFlags(), []configSetting{tc.setting}); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: got error %v, want one containing %q", tc.setting.Flag, err, tc.err)
+		}
+	}
+}
+
+func TestFindConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		configFileName:                 "out: outer.go\n",
+		"app/go.mod":                   "module example.com/app\n",
+		"app/api/users/get.go":         handlerFile("users", "Get", "users"),
+		"app/" + configFileName:        "out: app.go\n",
+		"other/go.mod":                 "module example.com/other\n",
+		"other/api/get.go":             handlerFile("api", "Get", "other"),
+		"app/nested/" + configFileName: "out: nested.go\n",
+		"app/nested/api/users/get.go":  handlerFile("users", "Get", "users"),
+	})
+	for _, tc := range []struct{ dir, want string }{
+		{"app/api/users", "app/" + configFileName},
+		{"app/nested/api", "app/nested/" + configFileName},
+		{"other/api", ""},
+	} {
+		got, err := findConfig(filepath.Join(dir, filepath.FromSlash(tc.dir)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := ""
+		if tc.want != "" {
+			want = filepath.Join(dir, filepath.FromSlash(tc.want))
+		}
+		if got != want {
+			t.Errorf("findConfig(%s) = %q, want %q", tc.dir, got, want)
+		}
+	}
+}
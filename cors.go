@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// corsMiddlewareName is the generated CORS middleware, which -groupMiddlewares
+// may reference to apply CORS to specific groups instead of globally.
+const corsMiddlewareName = "corsMiddleware"
+
+// CORSOptions configures the generated CORS middleware, parsed from -cors.
+type CORSOptions struct {
+	Origins       []string `json:"origins"`
+	Methods       []string `json:"methods"`
+	Headers       []string `json:"headers"`
+	ExposeHeaders []string `json:"exposeHeaders"`
+	Credentials   bool     `json:"credentials"`
+	MaxAge        int      `json:"maxAge"`
+}
+
+// corsConfig is CORSOptions prepared for the generated file.
+type corsConfig struct {
+	AllowAll      bool
+	Origins       []string
+	Methods       string
+	Headers       string
+	ExposeHeaders string
+	Credentials   bool
+	MaxAge        string
+}
+
+const corsTemplate = `
+{{if not .CORS.AllowAll}}// corsAllowedOrigins are the origins allowed by corsMiddleware
+var corsAllowedOrigins = map[string]bool{
+{{range .CORS.Origins}}	{{printf "%q" .}}: true,
+{{end}}}
+{{end}}
+// corsMiddleware sets the Access-Control-* headers for allowed origins and
+// answers preflight requests without calling the handler
+func corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == ""{{if not .CORS.AllowAll}} || !corsAllowedOrigins[origin]{{end}} {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		h.Set("Access-Control-Allow-Origin", {{if and .CORS.AllowAll (not .CORS.Credentials)}}"*"{{else}}origin{{end}})
+{{if .CORS.Credentials}}		h.Set("Access-Control-Allow-Credentials", "true")
+{{end}}{{if .CORS.ExposeHeaders}}		h.Set("Access-Control-Expose-Headers", {{printf "%q" .CORS.ExposeHeaders}})
+{{end}}
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+{{if .OptionsPaths}}			allowed := {{printf "%q" .CORS.Methods}}
+			if route := mux.CurrentRoute(r); route != nil {
+				if tmpl, err := route.GetPathTemplate(); err == nil {
+					if m, ok := corsPathMethods[tmpl]; ok {
+						h.Set("Allow", m.allow)
+						allowed = m.cors
+					}
+				}
+			}
+			h.Set("Access-Control-Allow-Methods", allowed)
+{{else}}			h.Set("Access-Control-Allow-Methods", {{printf "%q" .CORS.Methods}})
+{{end}}{{if .CORS.Headers}}			h.Set("Access-Control-Allow-Headers", {{printf "%q" .CORS.Headers}})
+{{else}}			if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+				h.Set("Access-Control-Allow-Headers", requested)
+			}
+{{end}}{{if .CORS.MaxAge}}			h.Set("Access-Control-Max-Age", {{printf "%q" .CORS.MaxAge}})
+{{end}}			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// corsPreflight matches preflight requests that no handler serves, so that
+// corsMiddleware runs for them. Its route requires the
+// Access-Control-Request-Method header, so other unmatched requests still
+// reach the 404 and fallback handlers
+func corsPreflight(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+`
+
+// defaultCORSMethods are allowed in preflight responses when -cors does not
+// list methods.
+var defaultCORSMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+
+// parseCORS parses and validates the -cors JSON.
+func parseCORS(raw string) (*corsConfig, error) {
+	var opts CORSOptions
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&opts); err != nil {
+		return nil, err
+	}
+	if len(opts.Origins) == 0 {
+		return nil, fmt.Errorf("origins must list at least one origin or \"*\"")
+	}
+	if opts.MaxAge < 0 {
+		return nil, fmt.Errorf("maxAge must not be negative, got %d", opts.MaxAge)
+	}
+
+	cfg := &corsConfig{
+		AllowAll:      slices.Contains(opts.Origins, "*"),
+		Origins:       opts.Origins,
+		Headers:       strings.Join(opts.Headers, ", "),
+		ExposeHeaders: strings.Join(opts.ExposeHeaders, ", "),
+		Credentials:   opts.Credentials,
+	}
+
+	methods := opts.Methods
+	if len(methods) == 0 {
+		methods = slices.Clone(defaultCORSMethods)
+	}
+	for i, m := range methods {
+		methods[i] = strings.ToUpper(m)
+		if strings.ContainsAny(m, " ,") {
+			return nil, fmt.Errorf("invalid method %q", m)
+		}
+	}
+	if !slices.Contains(methods, http.MethodOptions) {
+		methods = append(methods, http.MethodOptions)
+	}
+	cfg.Methods = strings.Join(methods, ", ")
+
+	if opts.MaxAge > 0 {
+		cfg.MaxAge = strconv.Itoa(opts.MaxAge)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// dedupeHandlers points routes whose handler file is a symlink to another
+// handler file at that file's package, so the handler is referenced once
+// and the symlink's package needs no import of its own. root is the -api
+// directory. It returns the number of routes changed.
+func dedupeHandlers(routes []route, root string) (int, error) {
+	owners := make(map[string]int)
+	targets := make([]string, len(routes))
+	links := make([]bool, len(routes))
+	for i, rt := range routes {
+		p := filepath.Join(root, filepath.FromSlash(rt.File))
+		info, err := os.Lstat(p)
+		if err != nil {
+			return 0, err
+		}
+		if targets[i], err = filepath.EvalSymlinks(p); err != nil {
+			return 0, err
+		}
+		links[i] = info.Mode()&fs.ModeSymlink != 0
+		// A file that is no symlink owns the handler, so links point at it
+		// whatever the walk order.
+		if j, ok := owners[targets[i]]; !ok || links[j] && !links[i] {
+			owners[targets[i]] = i
+		}
+	}
+
+	changed := 0
+	for i := range routes {
+		owner := routes[owners[targets[i]]]
+		if owner.ImportPath == routes[i].ImportPath {
+			continue
+		}
+		routes[i].ImportPath, routes[i].Alias = owner.ImportPath, owner.Alias
+		changed++
+	}
+	return changed, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// devBuildTag is the build tag gating the development-only file.
+const devBuildTag = "dev"
+
+const devTemplate = `// Code generated by fsrouter; DO NOT EDIT.
+
+//go:build {{.Tag}}
+
+package {{.Package}}
+
+import (
+{{if .UsesTime}}	"time"
+
+{{end}}{{if .MiddlewarePkg}}	middleware "{{.MiddlewarePkg}}"
+{{end}}	"github.com/gorilla/mux"
+)
+
+func init() {
+	devMiddlewareHook = func(r *mux.Router) {
+{{range .Middlewares}}		r.Use({{.}})
+{{end}}	}
+}
+`
+
+// devOutPath derives the development-only file's path from -out, e.g.
+// routes_gen.go becomes routes_dev_gen.go.
+func devOutPath(out string) string {
+	if strings.HasSuffix(out, "_gen.go") {
+		return strings.TrimSuffix(out, "_gen.go") + "_dev_gen.go"
+	}
+	return strings.TrimSuffix(out, ".go") + "_dev.go"
+}
+
+// writeDevFile generates the dev-tagged file that installs the development
+// middleware through the devMiddlewareHook declared by the main file.
+func writeDevFile(w *outputWriter, out, pkg, middlewarePkg string, middlewares []string) error {
+	if !usesPackage("middleware", middlewares) {
+		middlewarePkg = ""
+	}
+
+	var buf bytes.Buffer
+	tmpl := template.Must(template.New("dev").Parse(devTemplate))
+	err := tmpl.Execute(&buf, struct {
+		Tag           string
+		Package       string
+		MiddlewarePkg string
+		UsesTime      bool
+		Middlewares   []string
+	}{
+		Tag:           devBuildTag,
+		Package:       pkg,
+		MiddlewarePkg: middlewarePkg,
+		UsesTime:      usesPackage("time", middlewares),
+		Middlewares:   middlewares,
+	})
+	if err != nil {
+		return err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("formatting generated dev file: %w", err)
+	}
+	return w.write(out, src)
+}
+
+// removeStaleDevFile deletes a previously generated dev file once
+// -devMiddlewares is no longer set, since it refers to a hook the main file
+// no longer declares. Files not generated by fsrouter are left alone.
+func removeStaleDevFile(w *outputWriter, out string) error {
+	data, err := os.ReadFile(out)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(data, []byte("// Code generated by fsrouter; DO NOT EDIT.")) {
+		return nil
+	}
+	return w.remove(out)
+}
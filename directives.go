@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// directivePrefix marks a comment line in a handler file as an fsrouter
+// directive, e.g. "//fsrouter:timeout 5s".
+const directivePrefix = "//fsrouter:"
+
+// directive is a single //fsrouter:name value comment in a handler file.
+type directive struct {
+	Name  string
+	Value string
+	Pos   token.Position
+}
+
+// knownDirectives lists the directive names handler files may use.
+var knownDirectives = map[string]bool{
+	"timeout":        true,
+	"group":          true,
+	"tag":            true,
+	"produces":       true,
+	"skipMiddleware": true,
+	"enabledIf":      true,
+	"deprecated":     true,
+	"header":         true,
+	"cache":          true,
+	"paramName":      true,
+	"scopes":         true,
+	"internal":       true,
+	"catchAll":       true,
+	"scheme":         true,
+}
+
+// parseDirectives returns the fsrouter directives of a parsed handler file
+// in source order.
+func parseDirectives(fset *token.FileSet, file *ast.File) ([]directive, error) {
+	var directives []directive
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			if !strings.HasPrefix(c.Text, directivePrefix) {
+				continue
+			}
+			name, value, _ := strings.Cut(strings.TrimPrefix(c.Text, directivePrefix), " ")
+			pos := fset.Position(c.Pos())
+			if !knownDirectives[name] {
+				return nil, fmt.Errorf("%s: unknown directive %s%s", pos, directivePrefix, name)
+			}
+			directives = append(directives, directive{Name: name, Value: strings.TrimSpace(value), Pos: pos})
+		}
+	}
+	return directives, nil
+}
+
+// parseDeprecated parses the hints of a //fsrouter:deprecated directive:
+// "use <path or URL>" names the successor for the Link header and
+// "sunset <YYYY-MM-DD>" the date for the Sunset header, returned as an
+// HTTP date. Both are optional.
+func parseDeprecated(value string) (successor, sunset string, err error) {
+	fields := strings.Fields(value)
+	for i := 0; i < len(fields); i += 2 {
+		if i+1 == len(fields) {
+			return "", "", fmt.Errorf("%s needs a value", fields[i])
+		}
+		switch hint, arg := fields[i], fields[i+1]; {
+		case hint == "use" && successor == "":
+			if !strings.HasPrefix(arg, "/") && !strings.HasPrefix(arg, "http://") && !strings.HasPrefix(arg, "https://") || strings.ContainsAny(arg, "<>\"") {
+				return "", "", fmt.Errorf("use %s must be a path or an http(s) URL", arg)
+			}
+			successor = arg
+		case hint == "sunset" && sunset == "":
+			date, err := time.Parse(time.DateOnly, arg)
+			if err != nil {
+				return "", "", fmt.Errorf("sunset %s must be a date such as 2027-01-31", arg)
+			}
+			sunset = date.Format(http.TimeFormat)
+		default:
+			return "", "", fmt.Errorf("unexpected %q, want use <path> and sunset <date>, each at most once", hint)
+		}
+	}
+	return successor, sunset, nil
+}
+
+// parseHeaderMatch parses a //fsrouter:header value, Name=value or a bare
+// Name matching any value, into its canonical header name and value.
+func parseHeaderMatch(value string) (name, val string, err error) {
+	name, val, _ = strings.Cut(value, "=")
+	name, val = strings.TrimSpace(name), strings.TrimSpace(val)
+	if name == "" || strings.IndexFunc(name, func(c rune) bool { return !isTokenChar(c) }) >= 0 {
+		return "", "", fmt.Errorf("invalid header %q, want Name=value such as X-Api-Version=2", value)
+	}
+	return http.CanonicalHeaderKey(name), val, nil
+}
+
+// schemes are the URL schemes //fsrouter:scheme and -scheme accept.
+var schemes = []string{"http", "https"}
+
+// parseSchemes parses a //fsrouter:scheme or -scheme value, schemes
+// separated by spaces or commas, into lowercase schemes without repeats.
+func parseSchemes(value string) ([]string, error) {
+	var list []string
+	for _, s := range strings.Fields(strings.ReplaceAll(value, ",", " ")) {
+		s = strings.ToLower(s)
+		if !slices.Contains(schemes, s) {
+			return nil, fmt.Errorf("invalid scheme %q, want http or https", s)
+		}
+		if !slices.Contains(list, s) {
+			list = append(list, s)
+		}
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("scheme needs http or https")
+	}
+	return list, nil
+}
+
+// Matchers returns the .Headers and .Schemes calls chained onto the route's
+// registration, or "" without //fsrouter:header and //fsrouter:scheme
+// directives.
+func (rt route) Matchers() string {
+	var b strings.Builder
+	for _, m := range []struct {
+		name string
+		args []string
+	}{{"Headers", rt.Headers}, {"Schemes", rt.Schemes}} {
+		if len(m.args) == 0 {
+			continue
+		}
+		args := make([]string, len(m.args))
+		for i, s := range m.args {
+			args[i] = strconv.Quote(s)
+		}
+		b.WriteString("." + m.name + "(" + strings.Join(args, ", ") + ")")
+	}
+	return b.String()
+}
+
+// deltaSecondsDirectives are the Cache-Control directives whose value is a
+// number of seconds.
+var deltaSecondsDirectives = map[string]bool{
+	"max-age": true, "s-maxage": true, "stale-while-revalidate": true,
+	"stale-if-error": true, "max-stale": true, "min-fresh": true,
+}
+
+// checkCacheControl lightly validates a //fsrouter:cache value as a
+// Cache-Control header: comma-separated directives, each a token optionally
+// followed by =token or ="quoted", numeric where the directive takes
+// seconds, and none repeated.
+func checkCacheControl(value string) error {
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(value, ",") {
+		name, arg, hasArg := strings.Cut(strings.TrimSpace(item), "=")
+		name = strings.ToLower(name)
+		if name == "" || strings.IndexFunc(name, func(c rune) bool { return !isTokenChar(c) }) >= 0 {
+			return fmt.Errorf("invalid directive %q", strings.TrimSpace(item))
+		}
+		if seen[name] {
+			return fmt.Errorf("%s is given twice", name)
+		}
+		seen[name] = true
+		switch {
+		case deltaSecondsDirectives[name] && name != "max-stale" && !hasArg:
+			return fmt.Errorf("%s needs a number of seconds", name)
+		case !hasArg:
+		case deltaSecondsDirectives[name]:
+			if arg == "" || strings.IndexFunc(arg, func(c rune) bool { return c < '0' || c > '9' }) >= 0 {
+				return fmt.Errorf("%s=%s must be a number of seconds", name, arg)
+			}
+		case len(arg) >= 2 && arg[0] == '"' && arg[len(arg)-1] == '"':
+			if strings.ContainsAny(arg[1:len(arg)-1], "\"\\") {
+				return fmt.Errorf("invalid quoted value in %s", name)
+			}
+		case arg == "" || strings.IndexFunc(arg, func(c rune) bool { return !isTokenChar(c) }) >= 0:
+			return fmt.Errorf("invalid value in %s=%s", name, arg)
+		}
+	}
+	return nil
+}
+
+// isScope reports whether s is an OAuth scope token: printable ASCII other
+// than space, double quote and backslash, as in RFC 6749.
+func isScope(s string) bool {
+	return s != "" && strings.IndexFunc(s, func(c rune) bool { return c <= ' ' || c > '~' || c == '"' || c == '\\' }) < 0
+}
+
+// isParamName reports whether s can name a path parameter: letters, digits,
+// underscores and hyphens.
+func isParamName(s string) bool {
+	return s != "" && strings.IndexFunc(s, func(c rune) bool { return c != '_' && c != '-' && !unicode.IsLetter(c) && !unicode.IsDigit(c) }) < 0
+}
+
+// isTokenChar reports whether c may appear in an HTTP header name.
+func isTokenChar(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("!#$%&'*+-.^_`|~", c)
+}
@@ -9,6 +9,13 @@ A Go code generator that mirrors your `api/` filesystem into HTTP routes using [
 
 ```bash
 go install github.com/aquaticcalf/fsrouter@latest
+fsrouter -version
+```
+
+Release builds set the version at build time:
+
+```bash
+go build -ldflags "-X main.version=v1.2.3" github.com/aquaticcalf/fsrouter
 ```
 
 ## Usage
@@ -52,12 +59,14 @@ go generate ./...
 go build ./...
 ```
 
-Routes will be wired up from `api/` files like `get.go`, `post.go`, etc.
+Routes will be wired up from `api/` files like `get.go`, `post.go`, etc. An empty `api/` directory still generates a compilable file whose `RegisterRoutes` returns a router without routes, so the generate step can be set up before the first handler exists; fsrouter prints a warning in that case.
+
+Each handler file must export a function named after its method, matched case-insensitively (`Get` or `GET` in `get.go`), with the signature `func(w http.ResponseWriter, r *http.Request)`. Other exported functions in the file, such as shared helpers, are ignored. A missing or differently shaped handler function is reported at generation time rather than as a compile error in the generated file. File names map to methods regardless of case too, so `Get.go` next to `get.go`, which a case-insensitive file system could not hold, is an error rather than a second registration. A file without any exported function, such as an unfinished one, is skipped with a warning naming the expected handler instead, or reported as an error with `-strict`. A file named after no standard method registers its upper-cased name only when it declares the matching function, so `helpers.go` without a `Helpers` function holds helpers for the handlers next to it and is not a route. `_test.go` files are never handlers. With `-handlerStyle=jsonapi`, handlers may also return a value to be written as JSON; see [JSON Handlers](#json-handlers). With `-handlerReceiver`, they may be methods of a struct; see [Method Handlers](#method-handlers).
 
 ## Features
 
 - Automatic route registration from file system
-- Dynamic parameters with `[param]` folder syntax
+- Dynamic parameters with `[param]` folder syntax, and `[...param]` catch-alls
 - Extensive Middleware Support
   - Multiple global middlewares for all routes
   - Group-specific middlewares for route groups
@@ -65,75 +74,1378 @@ Routes will be wired up from `api/` files like `get.go`, `post.go`, etc.
 - Route grouping via first-level directories
   - Each top-level directory becomes a subrouter
   - Example: `/api/users/...` becomes a group
+  - Deeper directories become nested groups when configured in `-groupMiddlewares`, including `[param]` segments in the prefix
+- Per-route behavior declared with `//fsrouter:` directives in handler files
+- JSON request body validation from co-located `request.schema.json` files
+- Redirects declared with `redirect.txt` files or the `-redirects` flag
+- Typed HTTP client generation with `-genClient`
 - Custom 404 handler support
   - Specify with `-notFound=package.Handler`
-  - Default JSON 404 handler included
+  - Default JSON 404 handler included, with configurable status and body
+
+## Commands
+
+| Command | Description |
+|---------|-------------|
+| `fsrouter generate [flags]` | Generate the router and companion files (the default when no command is given) |
+| `fsrouter check [flags]` | Exit with an error if any generated file is out of date, writing nothing |
+| `fsrouter explain -route="METHOD /path" [flags]` | Print the middleware chain, handler and matchers of a route (see Explaining a Route) |
+| `fsrouter scaffold [flags]` | Create stub handlers from an OpenAPI spec (see Scaffolding From OpenAPI) |
+
+`check` takes the same flags as `generate`, so a CI step can reuse the `go:generate` line:
+
+```bash
+fsrouter check -api=./api -out=routes_gen.go -importPREFIX=yourmodule/api
+```
+
+It compares the router, the dev file and the client with what would be generated and lists the files that differ.
+
+Add `-verify` to either command to also see what differs. Nothing is written, and a unified diff from each file on disk to its regenerated contents is printed to stdout before exiting with status 1:
+
+```
+$ fsrouter -verify -api=./api -out=routes_gen.go -importPREFIX=yourmodule/api
+--- routes_gen.go
++++ routes_gen.go (generated)
+@@ -77,3 +77,4 @@
+ 	orgsRouter.HandleFunc("/acme/projects", orgs_acme_projects.Get).Methods("GET")
+ 	orgsRouter.HandleFunc("", orgs.Get).Methods("GET")
++	orgsRouter.HandleFunc("/{orgId}", orgs_orgId.Get).Methods("GET")
+ 	usersRouter.HandleFunc("", users.Get).Methods("GET")
+```
+
+Missing files are diffed against `/dev/null`. The output can be applied with `patch -p0`.
+
+`-out=-` writes the generated router to stdout instead of a file, for piping into other tools, as in `fsrouter -importPREFIX=yourmodule/api -out=- | diff routes_gen.go -`. Summaries then go to stderr with the warnings, so stdout holds only Go source. It cannot be combined with `check`, `-verify`, `-devMiddlewares` or `-tagGroups`, which need files on disk.
+
+For a stricter build, `-failOnWarnings` makes `generate` and `check` exit with status 1 when any warning was printed, such as an empty api directory or a `-middleware` package nothing refers to. The files are still generated, or compared with `check`, first; the error comes last with the number of warnings.
+
+Every command accepts `-logFormat=json`, which prints warnings, errors and summaries as one JSON object per line for CI systems to parse. Each object has a `level` (`info`, `warning` or `error`), a `msg` and, when the message concerns a file or directory, its `path`:
+
+```
+{"level":"warning","msg":"no handler files found in api, RegisterRoutes will return a router without routes","path":"api"}
+{"level":"info","msg":"Generated routes_gen.go with 0 routes in 0 groups","path":"routes_gen.go"}
+```
+
+Generated code is the same in either format.
+
+## Handlers in Another Module
+
+`-importPREFIX` must be the import path of the `-api` directory, which fsrouter checks against the nearest `go.mod` above it. In a monorepo keeping shared handlers in a sibling module, that is the sibling's module path, not the one of the generated file:
+
+```
+repo/
+  shared/
+    go.mod          # module example.com/shared
+    api/
+      users/get.go
+  app/
+    go.mod          # module example.com/app, requiring example.com/shared
+```
+
+```bash
+cd app && fsrouter -api=../shared/api -importPREFIX=example.com/shared/api
+```
+
+A prefix that does not match, such as `example.com/app/api` here, is an error naming the module the directory belongs to and the prefix expected. So is an `-api` directory outside the generated file's module without a `go.mod` of its own, since no module can import its packages. The generated file's module still has to require the sibling, through a `require` and `replace` in its `go.mod` or a `go.work`. Trees without any `go.mod`, such as GOPATH checkouts, are not checked.
 
 ## Command Line Options
 
+The flags below apply to `generate` and `check`.
+
 | Flag | Description | Default |
 |------|-------------|---------|
 | `-api` | Directory of API handlers | `api` |
-| `-out` | Output file path | `routes_gen.go` |
+| `-out` | Output file path, or `-` for stdout | `routes_gen.go` |
 | `-pkg` | Package name for generated file | `main` |
-| `-importPREFIX` | Import path prefix for API handlers | (required) |
+| `-backend` | Router backend to generate for: `gorilla` or `echo` (see Echo Backend) | `gorilla` |
+| `-importPREFIX` | Import path prefix for API handlers, checked against the `go.mod` the `-api` directory belongs to (see Handlers in Another Module) | (required) |
 | `-middleware` | Package containing middleware functions | (optional) |
 | `-middlewares` | Comma-separated list of middleware functions to apply globally | `loggingMiddleware` |
+| `-requestLogFormat` | Fields printed by the generated `loggingMiddleware`, e.g. `{method} {path} {status} {duration}` | `{method} {path}` |
 | `-groupMiddlewares` | JSON mapping of group to middleware list | (optional) |
+| `-groupPaths` | JSON mapping of group to the URL prefix replacing its directory path (see Group Prefixes) | (optional) |
+| `-chains` | JSON mapping of chain name to middleware list, referenced as `@name` | (optional) |
+| `-methodMiddlewares` | JSON mapping of method class (`read`, `write`, `*`) to middleware list | (optional) |
 | `-notFound` | Custom 404 handler (format: `package.Handler`) | (default handler used) |
+| `-notFoundStatus` | Status code written by the default 404 handler | `404` |
+| `-notFoundBody` | Body of the default 404 handler; an optional `%s` is replaced with the JSON-escaped request path | `{"error": "404 not found", "path": "%s"}` |
+| `-proxyFallback` | URL of a backend that requests matching no route are proxied to | (optional) |
+| `-spa` | JSON mapping of group to an embedded single-page app directory (see Hosting a Single-Page App) | (optional) |
+| `-spaCache` | `Cache-Control` header for the files of `-spa` apps; `index.html` gets `no-cache` | (optional) |
+| `-autoOptions` | Answer `OPTIONS` for paths without an `OPTIONS` handler with `204` and an `Allow` header, and narrow CORS preflight methods per path | `false` |
+| `-cors` | JSON CORS options; generates `corsMiddleware` (see CORS) | (optional) |
+| `-noRecover` | Do not generate `recoverMiddleware` (see Panic Recovery) | `false` |
+| `-otel` | Start an OpenTelemetry span named after the route around each handler (see OpenTelemetry Tracing) | `false` |
+| `-gzip` | Compress responses with the generated `gzipCompress` middleware applied globally (see Response Compression) | `false` |
+| `-gzipMinSize` | Smallest response `-gzip` compresses, e.g. `1KB` or `512B` | `1KB` |
+| `-maxBodySize` | Limit request bodies to this size, e.g. `1MB` or `512KB`, with the generated `maxBodySize` middleware applied globally (see Request Body Limits) | (optional) |
+| `-requestTimeout` | Cancel each request's context after this duration, e.g. `30s`, with the generated `requestTimeout` middleware applied globally outside the other middleware except `-securityHeaders` (see Request Timeouts) | (optional) |
+| `-requestID` | Generate `requestIDMiddleware`, applied globally as the outermost middleware (see Request IDs) | `false` |
+| `-securityHeaders` | Generate `securityHeadersMiddleware`, applied globally outside all other middleware, setting hardening headers such as `X-Content-Type-Options: nosniff` (see Security Headers) | `false` |
+| `-securityHeaderValues` | JSON mapping of header to value replacing, adding or, when empty, dropping `-securityHeaders` defaults | (optional) |
+| `-finalMiddleware` | Middleware wrapped around each handler as the innermost layer (format: `package.Middleware`) | (optional) |
+| `-scopeMiddleware` | Factory wrapped around routes with `//fsrouter:scopes`, called with their scopes (format: `Func` or `package.Func`) | (optional) |
+| `-redirects` | JSON mapping of old path to redirect target | (optional) |
+| `-mount` | JSON mapping of path prefix to a `func() http.Handler` serving everything under it (format: `Func` or `import/path.Func`) | (optional) |
+| `-grpcGateway` | `func() http.Handler` mounted under `-grpcPrefix`, typically a gRPC-Gateway mux (format: `import/path.Func`) | (optional) |
+| `-grpcPrefix` | Path prefix the `-grpcGateway` handler is mounted under, stripped before it routes | (required with `-grpcGateway`) |
+| `-stripPrefix` | Mount prefix stripped from request paths before routing, e.g. `/api` | (optional) |
+| `-dynamicLast` | Register `{param}` routes and groups after their static siblings (see Route Precedence) | `true` |
+| `-groupOrder` | Comma-separated top-level groups to register first, in this order (see Route Precedence) | (optional) |
+| `-routeOrder` | Order routes are emitted in: `fs` (directory walk), `alpha` (by path and method) or `rest` (by resource) (see Route Order) | `fs` |
+| `-encodedPath` | Match routes against the encoded request path (`UseEncodedPath`) | `false` |
+| `-caseInsensitive` | Lowercase request paths before routing (see Case-Insensitive Paths) | `false` |
+| `-pathCase` | Case of the URL segments derived from directory names: `preserve`, `kebab` (`user-profiles`), `snake` (`user_profiles`) or `lower` (`userprofiles`) (see Converting Directory Names) | `preserve` |
+| `-pathPolicy` | Warn about URL segments breaking a naming policy: `lowercase`, `kebab-lowercase` or `snake-lowercase` (see Enforcing a Path Policy) | (optional) |
+| `-validateBodies` | Validate JSON request bodies against `request.schema.json` files | `false` |
+| `-requireJSON` | Answer `415` to `POST`, `PUT` and `PATCH` requests whose body is not JSON (see Requiring JSON Bodies) | `false` |
+| `-devMiddlewares` | Comma-separated list of middleware functions applied only in builds with the `dev` tag | (optional) |
+| `-tagGroups` | JSON mapping of top-level group to a build tag its routes are only compiled with | (optional) |
+| `-target` | Where groups are registered: `file`, in `-out`, or `packages`, by a `register_gen.go` in each top-level group's directory (see Registering Groups From Their Packages) | `file` |
+| `-versionDirs` | Register each top-level version directory such as `v1` with its own function and generate `RegisterV1Routes` (see Versioned Routers) | `false` |
+| `-emitServer` | Also generate `NewServer` and `Run` (see Generating a Server) | `false` |
+| `-serverTimeouts` | JSON server timeouts for `-emitServer` | (see Generating a Server) |
+| `-serveSpec` | Path of a generated GET route serving an OpenAPI description of the routes | (optional) |
+| `-genClient` | Also generate a typed HTTP client into this file | (optional) |
+| `-clientParamCase` | Naming of path parameter arguments in the generated client: `camel` (`userID`), `snake` (`user_id`) or `raw` | `camel` |
+| `-clientPkg` | Package name for the generated client | value of `-pkg` |
+| `-genTS` | Also generate a TypeScript module exporting each route path into this file | (optional) |
+| `-genBench` | Also generate a `go test -bench` harness with a request per route into this `_test.go` file next to `-out` (see Benchmark Harness) | (optional) |
+| `-profile` | Also generate `RegisterRoutesRaw`, the same routes without any middleware | `false` |
+| `-routeProviders` | Generate a `RouteProvider` interface and make `RegisterRoutes` accept providers contributing routes | `false` |
+| `-routerOptions` | Make `RegisterRoutes` accept `Option`s setting the 404 handler and extra global middleware at call time (see Runtime Options) | `false` |
+| `-defaultMethod` | Method registered for a directory's only Go file when it is not named after a method, e.g. `GET` for `handler.go` | (optional) |
+| `-multiMethodFiles` | Let a Go file not named after a method register each exported function named after one, e.g. `Get` and `Delete` in `route.go` (see Multi-Method Files) | `false` |
+| `-maxDepth` | Error if the api tree has more directory levels than this; `0` is unlimited | `0` |
+| `-emitHandlerMap` | Also generate `Handlers`, a map from `"METHOD /path"` to each route's unwrapped handler | `false` |
+| `-emitRegistryOnly` | Generate only `Routes`, a `[]RouteInfo` of the discovered routes with their handlers, instead of a router (see Route Registry) | `false` |
+| `-concurrency` | Number of handler files parsed in parallel | `GOMAXPROCS` |
+| `-dedupeHandlers` | Reference handlers of symlinked files through the package of the file they link to, dropping the symlink package's import | `false` |
+| `-groupImports` | Split handler imports into blocks commented with their top-level directory | `false` |
+| `-nolint` | Add `//nolint:<value>` to every generated file, e.g. `all` or `unparam,revive` | (optional) |
+| `-postProcess` | Command run on every generated file before it is written or checked, with the file's path appended (see Post-Processing Generated Files) | (optional) |
+| `-scheme` | Comma-separated URL schemes, `http` or `https`, every route without a `//fsrouter:scheme` directive must be requested with (see Scheme Matching) | (optional) |
+| `-enabledIfFunc` | Predicate `func(name string) bool` deciding `//fsrouter:enabledIf` routes (format: `Func` or `package.Func`) | (optional) |
+| `-handlerStyle` | Handler signatures accepted: `http`, or `jsonapi` to also allow `func(r *http.Request) (any, error)` | `http` |
+| `-jsonErrorHandler` | `func(w http.ResponseWriter, r *http.Request, err error)` answering errors from JSON handlers (format: `Func` or `package.Func`) | generic 500 |
+| `-handlerReceiver` | Also accept handler methods of a struct built by the package's `New()` function | `false` |
+| `-apiDocComment` | Describe groups with the first sentence of their package comment, in the generated code and OpenAPI tags | `false` |
+| `-strict` | Report handler files without exported functions as errors instead of skipping them | `false` |
+| `-failOnWarnings` | Exit with an error after generating if any warning was printed | `false` |
+| `-stats` | Print route, group, parameter and middleware counts to stderr after generating | `false` |
+| `-verify` | Check that generated files are current without writing them, printing a unified diff of each difference | `false` |
+| `-printTree` | Print the discovered route tree and exit without writing a file | `false` |
+| `-logFormat` | Format of warnings, errors and summaries: `text` or `json` | `text` |
+| `-config` | `fsrouter.yaml` setting defaults for flags not given, or `none` (see Project Config) | found above `-api` |
+| `-version` | Print the fsrouter version and exit | `false` |
+| `-listBackends` | Print the available router backends and exit | `false` |
 
-## Setting Up Middleware
+### Project Config
 
-### Global Middleware
+Packages of a monorepo often run `go generate` with the same flags. Put them in an `fsrouter.yaml` once instead:
 
-You can specify multiple global middlewares using the `-middlewares` flag:
+```yaml
+# fsrouter.yaml at the module root
+importPREFIX: yourmodule/api
+middleware: yourmodule/middleware
+middlewares: loggingMiddleware,authMiddleware
+groupMiddlewares: '{"admin":"adminAuthMiddleware"}'
+validateBodies: true
+```
+
+fsrouter looks for the file in the `-api` directory and then in each parent, like git finding `.git`. The search stops at the directory holding the module's `go.mod`, so a config never applies to another module. Each key is a flag name without the dash, and its value becomes that flag's value unless the flag is also given on the command line. Values are plain, double-quoted with Go escapes, or single-quoted with `''` for a quote. JSON values such as `-groupMiddlewares` are easiest to write single-quoted. Paths such as `out` are relative to the directory fsrouter runs in, as on the command line.
+
+The file is a flat mapping, so nested values are an error, as are unknown flags, a flag set twice, and the `api` and `config` flags. `-config=path` reads another file instead, and `-config=none` turns the lookup off. Flags set by the config that only configure the router are ignored by `-emitRegistryOnly`, rather than reported as they are on the command line.
+
+## Handler Directives
+
+Handler files can carry `//fsrouter:` comment directives that change how their route is registered. Unknown directives are an error.
+
+### Timeouts
+
+`//fsrouter:timeout <duration>` wraps the route's handler in `http.TimeoutHandler`. The duration uses `time.ParseDuration` syntax:
+
+```go
+//fsrouter:timeout 5s
+package reports
+
+func Get(w http.ResponseWriter, r *http.Request) { ... }
+```
+
+```go
+reportsRouter.Handle("", http.TimeoutHandler(http.HandlerFunc(reports.Get), 5*time.Second, "timeout")).Methods("GET")
+```
+
+Requests that exceed the timeout receive `503 Service Unavailable` with the body `timeout`. The `time` import is only emitted when a route uses a timeout.
+
+### Logical Groups
+
+`//fsrouter:group <name>` registers the route under the named group's subrouter regardless of where the file lives. The group is created if no directory provides it, and its `-groupMiddlewares` apply as usual. The route keeps its path below its physical group:
+
+```
+api/
+  handlers/
+    stats/
+      get.go        # //fsrouter:group admin
+```
+
+```go
+adminRouter := r.PathPrefix("/admin").Subrouter()
+adminRouter.HandleFunc("/stats", handlers_stats.Get).Methods("GET")
+```
+
+Files without the directive are grouped by directory as before.
+
+### Tags
+
+`//fsrouter:tag <tag>...` marks a route as feature-flagged. Tags are separated by spaces or commas, and repeated directives accumulate. When any route is tagged, the generated function becomes `RegisterRoutes(tags ...string)` and only registers a tagged route when at least one of its tags is requested; untagged routes are always registered:
+
+```go
+//fsrouter:tag beta
+package reports
+```
+
+```go
+r := RegisterRoutes("beta") // includes beta routes
+r = RegisterRoutes()        // untagged routes only
+```
+
+### Environment-Gated Routes
+
+`//fsrouter:enabledIf <VAR>` registers a route only when the environment variable is set to a non-empty value when `RegisterRoutes` runs, for dark-launching endpoints:
+
+```go
+//fsrouter:enabledIf FEATURE_EXPORT
+package reports
+```
+
+```go
+if os.Getenv("FEATURE_EXPORT") != "" {
+	reportsRouter.HandleFunc("", reports.Get).Methods("GET")
+}
+```
+
+For other conditions, `-enabledIfFunc` names a `func(name string) bool` that is called with the variable name instead, such as `-enabledIfFunc=middleware.FeatureEnabled` to read a flag service. A route may carry one `enabledIf` directive; combined with tags, it is registered only when both allow it. The condition is evaluated once per `RegisterRoutes` call, so changing the environment later has no effect on a running router.
+
+### Default Content Type
+
+`//fsrouter:produces <media type>` sets the response `Content-Type` before the handler runs, so handlers don't each repeat `w.Header().Set`. The handler can still override it:
+
+```go
+//fsrouter:produces application/json
+package users
+```
+
+```go
+usersRouter.Handle("", produces("application/json")(http.HandlerFunc(users.Get))).Methods("GET")
+```
+
+The value must be a single media type, and a file may have only one `produces` directive. The wrapper sits inside body validation and timeouts, just outside `-finalMiddleware`.
+
+### Caching Headers
+
+`//fsrouter:cache <Cache-Control value>` sets the response `Cache-Control` header of the file's routes before the handler runs:
+
+```go
+//fsrouter:cache public, max-age=300
+package users
+```
+
+```go
+usersRouter.Handle("", cacheControl("public, max-age=300")(http.HandlerFunc(users.Get))).Methods("GET")
+```
+
+The value is emitted as written after a light check: comma-separated directives with an optional `=token` or `="quoted"` value, seconds for `max-age`, `s-maxage` and the other delta-seconds directives, and no directive twice. Unknown directives are allowed, so extensions such as `immutable` pass through. A file may have only one `cache` directive. The wrapper sits just outside `produces`, inside body validation, typed parameters and timeouts, so their 400 and 503 responses are not marked cacheable; the handler's own error responses are, unless it overrides the header. Only annotated routes are wrapped, and `cacheControl` is only generated when one is.
+
+### Header Matching
+
+`//fsrouter:header <Name>=<value>` makes a route match only requests carrying that header value, through mux's `Headers` matcher. A bare `<Name>` matches any value. Repeated directives accumulate, and all of them must match:
+
+```go
+//fsrouter:group users
+//fsrouter:header X-Api-Version=2
+package usersv2
+```
+
+```go
+usersRouter.HandleFunc("", usersv2.Get).Methods("GET").Headers("X-Api-Version", "2")
+usersRouter.HandleFunc("", users.Get).Methods("GET")
+```
+
+This allows header-based versioning, with several handlers for one method and path. Routes with header directives are registered before those without for the same method and path, so the header-less one answers everything else. Across groups, registration follows group order instead, which `-groupOrder` can set. Header names are canonicalized, and a name may appear once per route. `-emitHandlerMap` keys include the matches, as in `"GET /users X-Api-Version=2"`. `-genClient` has one method per method and path, so it reports such routes as duplicates. The echo backend does not support the directive.
+
+### Scheme Matching
+
+`//fsrouter:scheme <scheme>...` makes a route match only requests made with one of the listed schemes, `http` or `https`, through mux's `Schemes` matcher. Schemes are separated by spaces or commas, and repeated directives accumulate:
+
+```go
+//fsrouter:scheme https
+package login
+```
+
+```go
+authRouter.HandleFunc("/login", auth_login.Post).Methods("POST").Schemes("https")
+```
+
+`-scheme=https` sets the schemes of every route without the directive, so a health check can opt back into plain HTTP with `//fsrouter:scheme http https`. mux takes the scheme from the request URL, which servers leave empty, and otherwise from whether the connection uses TLS. Behind a proxy terminating TLS, every request looks like plain HTTP, so the routes answer 404 unless the proxy's scheme is restored first, for example by middleware reading `X-Forwarded-Proto`. Like header matches, routes with schemes are registered before those without for the same method and path, `-emitHandlerMap` keys end with the schemes, as in `"POST /auth/login https"`, and the `-emitRegistryOnly` route list has them in `Schemes`. The echo backend does not support the directive.
+
+### Renaming Parameters
+
+`//fsrouter:paramName <folder name> <name>` registers a `[param]` folder's parameter under another name, when the folder follows one naming convention and the API contract another:
+
+```go
+// api/users/[user]/get.go
+//fsrouter:paramName user userId
+package user
+```
+
+```go
+usersRouter.HandleFunc("/{userId}", users_user.Get).Methods("GET")
+```
+
+The handler then reads `mux.Vars(r)["userId"]`, and the OpenAPI description, client and TypeScript constants use the new name too; a typed parameter is parsed into the context under it. Like other directives it applies to the file's routes only, so the other method files of the directory keep `{user}` unless they rename it as well. Names may contain letters, digits, `_` and `-`. A parameter in the prefix of the route's group cannot be renamed, since all routes of the group share the prefix.
+
+### Authorization Scopes
+
+`//fsrouter:scopes <scope>...` declares the OAuth scopes a route requires. They are passed to the factory named by `-scopeMiddleware`, a `func(scopes ...string) func(http.Handler) http.Handler` from your middleware package, which wraps the route:
+
+```go
+//fsrouter:scopes read:users write:users
+package users
+```
+
+```go
+usersRouter.Handle("", middleware.RequireScopes("read:users", "write:users")(http.HandlerFunc(users.Get))).Methods("GET")
+```
+
+with `-middleware=yourmodule/middleware -scopeMiddleware=middleware.RequireScopes`. Scopes are separated by spaces, repeated directives accumulate, and duplicates are dropped. The factory decides what the scopes mean, typically by checking them against a token that authentication middleware in `-middlewares` or `-methodMiddlewares` has verified, so the check sits just inside `-methodMiddlewares` and outside the other per-route wrappers. A directive without `-scopeMiddleware` is an error.
+
+### Deprecated Routes
+
+`//fsrouter:deprecated` warns clients that a route is going away. Its responses get a `Deprecation: true` header, and the optional hints add a `Link` to the replacement and a `Sunset` date:
+
+```go
+//fsrouter:deprecated use /v2/users sunset 2027-01-31
+package users
+```
+
+```go
+usersRouter.Handle("", deprecated("/v2/users", "Sun, 31 Jan 2027 00:00:00 GMT")(http.HandlerFunc(users.Get))).Methods("GET")
+```
+
+`use` takes a path or an `http(s)` URL, sent as `Link: </v2/users>; rel="successor-version"`, and `sunset` a `YYYY-MM-DD` date, converted to an HTTP date at generation time. The wrapper sits outside timeouts and inside `-methodMiddlewares`, and `-serveSpec` marks the operation `deprecated`. The `deprecated` helper is only generated when a route uses the directive.
+
+### Internal Routes
+
+`//fsrouter:internal` marks an operational route, such as a debug or health endpoint, that is registered like any other but is not part of the public API:
+
+```go
+//fsrouter:internal
+package debug
+```
+
+The route is left out of the `-serveSpec` OpenAPI description, the `-genClient` client and the `-genTS` constants. A path whose routes are all internal gets no constant at all. The directive takes no value. The router, `-emitHandlerMap`, `-emitRegistryOnly`, `-printTree` and `-stats` still list the route.
+
+### Skipping Middleware
+
+`//fsrouter:skipMiddleware <middleware>...` exempts a route from middleware it would otherwise get, e.g. a public login route in an authenticated group:
+
+```go
+//fsrouter:skipMiddleware authMiddleware
+package login
+```
+
+mux applies group middleware to every route on a subrouter, so fsrouter registers the route on a sibling subrouter with the same prefix. That subrouter runs the rest of the group's middleware, including that of enclosing groups, in the same order:
+
+```go
+usersRouter := r.PathPrefix("/users").Subrouter()
+usersRouter.Use(authMiddleware)
+usersRouter.Use(rateLimit)
+users_no_authMiddlewareRouter := r.PathPrefix("/users").Subrouter()
+users_no_authMiddlewareRouter.Use(rateLimit)
+
+users_no_authMiddlewareRouter.HandleFunc("/login", users_login.Post).Methods("POST")
+```
+
+Routes that skip the same middleware share one sibling subrouter. `-methodMiddlewares` can be skipped too; they are simply left out of the route's wrapping. Global `-middlewares` run before routing and cannot be skipped, and naming middleware that does not apply to the route is an error. Names are separated by spaces or commas.
+
+## Overriding Directory Paths
+
+When the URL should not match the folder name, put a `_path` file in the directory containing a single path:
+
+```
+api/
+  apiV2Users/
+    _path           # /v2/users
+    get.go          # GET /v2/users
+    [userId]/
+      get.go        # GET /v2/users/{userId}
+```
+
+The override replaces only that directory's segment, so parent directories and subdirectories still contribute theirs. Group prefixes, redirects and nested routes follow the new path. The import alias and router variable still come from the folder name (`apiV2Users`, `apiV2UsersRouter`). Segments may contain letters, digits and `-._~`; anything else, an empty path, or a `_path` file at the api root is an error.
+
+`_path` is also how a URL gets non-ASCII segments. Go import paths must be ASCII, so a handler package cannot live in a directory named `café`, and fsrouter reports such a directory as an error instead of generating a router that does not compile. Name the directory `cafe` and give it a `_path` file containing `/café`. The route keeps the Unicode path, which mux matches against the decoded request path, so `/caf%C3%A9` and `/café` both reach it. With `-encodedPath`, the non-ASCII characters in literal segments are percent-encoded in the generated paths instead (`/caf%C3%A9`), since that is how the escaped request path spells them. Two directories whose identifiers come out the same, such as `a-b` and `a_b`, are reported as an error.
+
+### Group Prefixes
+
+`-groupPaths` sets the prefixes of route groups in one place instead of in `_path` files:
 
 ```bash
-fsrouter -middlewares="loggingMiddleware,authMiddleware,corsMiddleware"
+fsrouter -groupPaths='{"users":"/v1/users","legacy":"/old"}'
 ```
 
-### Group-Specific Middleware
+`api/users/get.go` then serves `GET /v1/users`, and the routes under `api/legacy/` are mounted under `/old`. The subrouter's `PathPrefix` and the paths of its routes, redirects and nested groups follow the new prefix, while the import aliases and router variables keep the folder names. A nested group's prefix is the full path, which must start with its parent directory's path: with `{"orgs/acme":"/orgs/team"}`, `api/orgs/acme/` is served under `/orgs/team`. Naming a directory that is not a route group, a directory that also has a `_path` file, or a prefix such as `/`, is an error. So are two groups ending up with the same prefix when one of them gets it from `-groupPaths`, since only the first registered could serve it.
 
-There are two ways to set up group-specific middleware:
+### Converting Directory Names
 
-1. Using the `-groupMiddlewares` flag with a JSON string:
+Rather than a `_path` file in every camelCase directory, `-pathCase` converts all directory names to URL segments in one convention:
+
+```
+api/
+  userProfiles/
+    get.go          # GET /user-profiles with -pathCase=kebab
+    [profileId]/
+      get.go        # GET /user-profiles/{profileId}
+```
+
+`kebab` and `snake` split words before an uppercase letter starting a new word and at existing dashes and underscores, joining them with `-` or `_`, so `APIKeys` becomes `api-keys` and `v2Items` `v2-items`. `lower` only lowercases, giving `userprofiles`. Parameter names, Go identifiers and group names in flags such as `-groupMiddlewares` are unchanged, and a `_path` file still overrides its directory's segment as written. Two handler directories converting to the same path, such as `userProfiles` and `user-profiles`, are an error; rename one or give it a `_path` file.
+
+### Enforcing a Path Policy
+
+Where `-pathCase` converts names, `-pathPolicy` only checks them. It prints a warning for each URL segment breaking the policy, naming the directory, `_path` file or handler file it comes from:
+
+```
+warning: api/userProfiles: segment "userProfiles" of /userProfiles does not follow -pathPolicy=kebab-lowercase
+```
+
+- `lowercase` rejects uppercase letters.
+- `kebab-lowercase` wants words of `a-z` and `0-9` joined by single dashes, such as `user-profiles`.
+- `snake-lowercase` wants the same words joined by single underscores, such as `user_profiles`.
+
+Dots may separate parts of a segment, so `openapi.json` passes kebab-case. Every policy also rejects paths from a `Paths` variable that end with a slash. `{param}` segments are not checked, since they are not part of a URL. A `_path` file's segments are checked as written, and segments converted by `-pathCase` are checked after conversion. Add `-failOnWarnings` to make violations fail a CI run:
 
 ```bash
-fsrouter -groupMiddlewares='{"users":"authMiddleware,rateLimit","admin":"adminAuthMiddleware"}'
+fsrouter check -importPREFIX=yourmodule/api -pathPolicy=kebab-lowercase -failOnWarnings
 ```
 
-2. Editing the generated code (will be overwritten on regeneration):
+## Serving Several Paths
+
+A handler that answers more than one URL lists them in a `Paths` variable in its file:
 
 ```go
-// After generation, you can manually edit:
-usersRouter.Use(authMiddleware)
-adminRouter.Use(adminAuthMiddleware)
+package api
+
+var Paths = []string{"/", "/index.html"}
+
+func Get(w http.ResponseWriter, r *http.Request) { ... }
 ```
 
-### Creating Custom Middleware
+Each path is relative to the handler's directory, `/` being the directory itself, so `api/get.go` above serves `GET /` and `GET /index.html`. Every path gets the same group, middleware and directives. The method files of a directory share a package, so when several of them list paths, name the variable after the handler instead, e.g. `GetPaths` in `get.go`. The value must be a `[]string` literal of string literals starting with `/`. A file without the variable serves only its directory's path.
 
-Define your middleware functions in your application code:
+### Symlinked Handlers
+
+Trees that serve one handler under two directories sometimes symlink the file, e.g. `api/people/get.go` pointing at `../users/get.go`. Each directory is still its own package, so the router imports both and references `people.Get` and `users.Get`, two copies of the same code. `-dedupeHandlers` resolves symlinks and references every handler of a shared file through one package, the one holding the real file, or the first in walk order when all of them are links:
 
 ```go
-// In your middleware package
-func authMiddleware(next http.Handler) http.Handler {
-    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        // Authentication logic here
-        token := r.Header.Get("Authorization")
-        if !validateToken(token) {
-            http.Error(w, "Unauthorized", http.StatusUnauthorized)
-            return
-        }
-        next.ServeHTTP(w, r)
-    })
+peopleRouter.HandleFunc("", users.Get).Methods("GET")
+usersRouter.HandleFunc("", users.Get).Methods("GET")
+```
+
+The symlink's package is then no longer imported unless it has handlers of its own. Each route keeps its own path, group, middleware and directives, which are read from the shared file anyway. `-handlerReceiver` routes of both directories share one struct. A `Paths` variable needs no flag, since its paths already share one reference.
+
+## Single-Handler Directories
+
+Endpoints with one method can skip naming the file after it. With `-defaultMethod=GET`, a directory whose only Go file is not named after a standard method registers that file for `GET`:
+
+```
+api/
+  health/
+    handler.go      # exports func Handler(...), served as GET /health
+```
+
+The handler function is still matched against the file name, so `handler.go` exports `Handler`. A directory with a method file such as `get.go`, or with more than one Go file, is unaffected. Without the flag, a file's upper-cased name is its method, so `handler.go` would register `HANDLER /health`.
+
+### Multi-Method Files
+
+With `-multiMethodFiles`, a Go file not named after a standard method holds a handler per method instead, each named after its method:
+
+```
+api/
+  items/
+    route.go        # exports Get, Post and Delete: GET, POST and DELETE /items
+```
+
+Every exported function of handler shape in such a file must be named after a method, and other exported functions, such as helpers returning a string, are ignored. A file with no method function is an error unless `-handlerReceiver` is set, in which case it may hold the struct and its `New` function. The file's directives apply to all its handlers, and a `Paths` variable to all of them unless named after one, e.g. `DeletePaths`. Two handlers of a directory registering the same method, such as `Get` in `route.go` and a `get.go` file, are reported. The flag cannot be combined with `-defaultMethod`, which decides the method of the same files.
+
+## Typed Parameters
+
+A parameter directory may declare a type after a colon, e.g. `api/users/[userId:int]/`. The route is still `/users/{userId}`, but fsrouter generates a small middleware for the directory that parses the value before the handler runs and answers `400 Bad Request` when it does not parse:
+
+```go
+usersRouter.Handle("/{userId}", users_userIdParams(http.HandlerFunc(users_userId.Get))).Methods("GET")
+```
+
+The parsed value is stored in the request context under the parameter name:
+
+```go
+userID := r.Context().Value("userId").(int)
+```
+
+The key is a plain string because handler packages cannot import the package holding the router, which imports them. Supported types are `int`, `int64`, `uint64`, `float64` and `bool`; any other type is an error. Only routes with typed parameters get the middleware. It runs inside method middleware and timeouts and outside body validation, so a malformed parameter is rejected before the body is read. The `-serveSpec` description gives typed parameters their OpenAPI type. `RegisterRoutesRaw` from `-profile` skips the middleware like all others, so handlers that rely on the context value cannot be benchmarked through it.
+
+### Catch-All Parameters
+
+A parameter directory named `[...name]` matches the rest of the path, slashes included. `api/files/[...path]/get.go` is registered as `/files/{path:.*}`, so `/files/docs/2024/report.pdf` reaches the handler with `mux.Vars(r)["path"]` set to `docs/2024/report.pdf`. The value may also be empty, as for `/files/`, but `/files` itself does not match; give `api/files/` a handler of its own for it.
+
+Directories may follow a catch-all, as in `api/repos/[...path]/edit/get.go` for `/repos/{path:.*}/edit`. `[...name?]` matches as little as possible with `.*?`. Since mux matches a route's whole path, this only changes the result when a later catch-all could take the same segments.
+
+The value has no leading slash. `//fsrouter:catchAll slash` wraps the route's handler in a generated `catchAllSlash` middleware that prefixes one, so the handler sees `/docs/2024/report.pdf` and `/` for `/files/`:
+
+```go
+//fsrouter:catchAll slash
+package files_path
+```
+
+Matching is unchanged. The directive is an error on a route without a catch-all, and it applies to the last one when there are several. `RegisterRoutesRaw` from `-profile` skips the middleware. Catch-alls cannot be typed. Under `-dynamicLast` they sort after `{param}` routes at the same depth, so `/files/{id}` is tried before `/files/{path:.*}`. The `-genClient` and `-genTS` helpers escape the slashes of a catch-all value, which mux decodes before matching unless `-encodedPath` is set. The Echo backend does not support them.
+
+## JSON Handlers
+
+With `-handlerStyle=jsonapi`, a handler may return its response instead of writing it:
+
+```go
+func Get(r *http.Request) (any, error) {
+    return store.FindUsers(r.Context(), r.URL.Query().Get("q"))
 }
 ```
 
-## Extending
+fsrouter detects the signature per handler, so such handlers can sit next to plain `http.HandlerFunc` ones in the same tree. `interface{}` is accepted for `any`; other result types are not, and a function returning a concrete type has to be declared with `any`. JSON handlers are registered through a generated adapter:
 
-The generated code can be further customized as needed, but keep in mind that regeneration will overwrite your changes.
+```go
+usersRouter.HandleFunc("", jsonHandler(users.Get)).Methods("GET")
+```
 
-Use the command-line flags whenever possible to avoid manual edits.
+`jsonHandler` marshals the returned value with `encoding/json` and writes it with `200 OK` and `Content-Type: application/json`. A returned error, or a value that cannot be marshaled, is passed to the error handler instead. The default one answers `500` with `{"error":"internal server error"}` and keeps the error itself from the client. Set `-jsonErrorHandler=middleware.WriteError` to name a `func(w http.ResponseWriter, r *http.Request, err error)` of your own, for example one mapping not-found errors to `404`. Middleware, directives and typed parameters wrap the adapter like any other handler. Without `-handlerStyle=jsonapi`, a handler with this signature is reported as an error.
+
+## Method Handlers
+
+With `-handlerReceiver`, handlers may be methods of a struct carrying their dependencies, declared next to a constructor in the same package:
+
+```go
+type Users struct{ db *sql.DB }
+
+func New() *Users { return &Users{db: openDB()} }
+
+// get.go
+func (h *Users) Get(w http.ResponseWriter, r *http.Request) { ... }
+```
+
+`RegisterRoutes` calls `New` once per package and registers its methods:
+
+```go
+usersHandler := users.New()
+usersRouter.HandleFunc("", usersHandler.Get).Methods("GET")
+```
+
+A method is matched by name in its method file like a function, with either a pointer or value receiver, and may mix with free functions in the same tree. In this mode only method files are handler files; other Go files, such as the one declaring the struct, are left alone. All method handlers of a directory must belong to one exported type, and the package must declare `func New() *T` or `func New() T` without parameters; both are checked at generation time. `-emitHandlerMap` entries call `New` for each handler, since they live outside `RegisterRoutes`.
+
+## Route Precedence
+
+mux tries routes and subrouters in registration order and uses the first match, so `/users/{userId}` registered before `/users/settings` would answer `/users/settings` itself. By default (`-dynamicLast=true`) fsrouter orders registration so static segments win:
+
+1. Groups are sorted by name, then routes and groups are each stably sorted by the kinds of their path segments.
+2. Two paths are compared segment by segment. At the first position where one has a static segment and the other a `{param}`, the static one is registered first.
+3. If one path's kinds are a prefix of the other's, the shorter path comes first, which keeps every group before its nested groups.
+4. Paths with the same kinds at every position keep the order of the directory walk, which is lexical by file name.
+
+So `/users/settings` precedes `/users/{userId}`, and an `orgs/acme` group precedes an `orgs/[orgId]` group. Since only the kinds are compared, `/orgs/{orgId}/projects` may still be registered before `/orgs/acme/projects`; they are on different subrouters and never compete. Typed parameters and `{param}`s listed in a `Paths` variable count as dynamic.
+
+`-dynamicLast=false` registers routes in walk order and groups in name order instead. There `[param]` directories sort before lowercase names, because `[` comes before `a`.
+
+Groups whose prefixes overlap, such as an `api` group and an `apiV2` group whose `_path` is `/api/v2`, can be registered in an explicit order with `-groupOrder=apiV2,api`. The listed top-level groups come first, in that order, each followed by its nested groups. Unlisted groups follow in the order described above, which is by name with `-dynamicLast=false`. Listing a name that is not a top-level group, or one twice, is an error. Routes within each group keep their precedence order.
+
+### Route Order
+
+Within each group, routes are emitted in the order of the directory walk. For generated code that reads better in review, `-routeOrder` sorts them within each group. It never changes the route that answers a request:
+
+- `alpha` sorts routes by path, then by method name.
+- `rest` lists each resource's collection routes before its item routes. Methods on a path follow in the order `GET`, `HEAD`, `POST`, `PUT`, `PATCH`, `DELETE`, `OPTIONS`, then other methods, then a fallback. Static segments sort before `{param}`s, so `/users/settings` comes between `/users` and `/users/{userId}`.
+
+A route only moves past routes that no request could match together: routes with different methods, or paths that differ in a static segment. Routes that could compete keep the order `-dynamicLast` gave them. So with `-dynamicLast=false`, `GET /users/{userId}` stays ahead of `GET /users/settings`. The `-emitRegistryOnly` route list follows the same order.
+
+## Mounting Under a Prefix
+
+When a reverse proxy forwards `/api/...` to the service unchanged, `-stripPrefix=/api` makes `RegisterRoutes` strip the prefix before routing:
+
+```go
+root := mux.NewRouter()
+root.NotFoundHandler = r.NotFoundHandler
+root.Handle("/api", http.RedirectHandler("/api/", http.StatusMovedPermanently))
+root.PathPrefix("/api/").Handler(http.StripPrefix("/api", r))
+return root
+```
+
+Interaction with gorilla/mux path matching:
+
+- Routes, groups and middleware see the stripped path, so `/api/users/42` matches `/users/{userId}` and `mux.Vars` works unchanged.
+- `/api` itself redirects to `/api/`, which reaches the `/` route. Without this, mux would clean the empty stripped path by redirecting to `/`, outside the mount point.
+- Requests outside the prefix get the same 404 handler as unmatched routes.
+- URLs built from named routes with `URL()` do not include the prefix.
+
+## Encoded Paths
+
+By default mux matches routes against the decoded path, so `/files/a%2Fb` is seen as `/files/a/b` and does not match `/files/{name}`. `-encodedPath` emits `r.UseEncodedPath()` right after the router is created (and on the `-stripPrefix` root router), so matching uses the path as sent.
+
+This also changes what handlers receive: `mux.Vars(r)["name"]` is then `a%2Fb`, still encoded, and must be decoded with `url.PathUnescape` where the literal value is needed.
+
+## Case-Insensitive Paths
+
+mux matches paths case-sensitively and has no option to do otherwise. For legacy clients that send `/Users/42`, `-caseInsensitive` makes `RegisterRoutes` return an outer router that lowercases the request path before the generated router (and the `-stripPrefix` root) sees it.
+
+Everything after routing sees the lowercased path, including middleware, `r.URL` and `{param}` values, so `/users/ABC` yields `mux.Vars(r)["userId"] == "abc"`. The path as sent is kept in the request context, read with the generated `OriginalPathFromContext(r.Context())`, and in the `X-Original-Path` request header for handler packages, which cannot import the router's package.
+
+Registered paths must be lowercase outside their `{param}` segments, or they could never match; a directory such as `apiV2Users` is an error until it is renamed or given a lowercase `_path`. The same applies to redirect sources, `-stripPrefix` and `-serveSpec`.
+
+## Request Body Validation
+
+With `-validateBodies`, a `request.schema.json` placed next to a directory's handlers validates the JSON body of that directory's `POST`, `PUT` and `PATCH` routes before the handler runs:
+
+```
+api/
+  users/
+    post.go
+    request.schema.json
+```
+
+Invalid bodies receive `400 Bad Request` with a message naming the failing field. The schema is embedded into the generated file, together with a small validator supporting the `type`, `properties`, `required`, `items` and `enum` keywords. Directories without a schema file are not wrapped.
+
+### Requiring JSON Bodies
+
+`-requireJSON` wraps every `POST`, `PUT` and `PATCH` route in a generated `requireJSON` middleware, which answers `415 Unsupported Media Type` when the request has a body and its `Content-Type` is neither `application/json` nor a `+json` type such as `application/merge-patch+json`:
+
+```go
+authRouter.Handle("/login", requireJSON(http.HandlerFunc(auth_login.Post))).Methods("POST")
+```
+
+Requests with an empty body, such as a `POST` triggering an action, pass, and parameters such as `charset` are ignored. The check runs around `-validateBodies`, so a form post is rejected before its body is parsed as JSON, and inside the other per-route layers. Routes of other methods are not wrapped. A route accepting other bodies, such as a file upload, opts out with `//fsrouter:skipMiddleware requireJSON`.
+
+## Compiling Groups Behind Build Tags
+
+`-tagGroups` leaves whole feature areas out of a binary unless it is built with a tag:
+
+```bash
+fsrouter -tagGroups='{"admin":"admin"}'
+```
+
+The `admin` group, its nested groups and its routes move into `routes_admin_gen.go` (named after `-out`), guarded by `//go:build admin`. Only that file imports the group's handler packages, so a build without the tag does not compile or link them. The main file declares a `buildTagRoutes` hook list and runs it after its own routes. Each gated file adds itself to the list in `init`:
+
+```go
+// routes_admin_gen.go, only built with -tags admin
+func init() {
+	buildTagRoutes = append(buildTagRoutes, registerAdminRoutes)
+}
+```
+
+Several groups can share a tag. Only top-level groups can be gated, and `dev` is reserved for `-devMiddlewares`. Gated files for tags that are no longer listed are removed on the next run. This is separate from `//fsrouter:tag`, which decides at runtime which compiled routes `RegisterRoutes` registers.
+
+## Versioned Routers
+
+When `api/` holds API versions side by side, `-versionDirs` makes each version a router of its own that can also be served alone:
+
+```
+api/
+  health/get.go     # GET /health
+  v1/
+    users/get.go    # GET /v1/users
+  v2/
+    users/get.go    # GET /v2/users
+```
+
+```go
+func RegisterRoutes() *mux.Router   // /health, /v1/... and /v2/...
+func RegisterV1Routes() *mux.Router // /v1/... only
+func RegisterV2Routes() *mux.Router // /v2/... only
+```
+
+A version directory is a top-level directory named `v` followed by a number, such as `v1`, `v2` or `v3beta1`. Its groups and routes move into a function such as `addV1Routes`. `RegisterRoutes` calls that function where the group would have been registered, so precedence and matching are unchanged. A `_path` file can still rename the prefix.
+
+`RegisterV1Routes` builds a router with the routes of `v1`, under the same `/v1` paths as in `RegisterRoutes`. It gets the same 404 handler and global middleware, and its `-groupMiddlewares`, such as `{"v1":"authV1"}`, give each version different middleware. It takes the tags or `Option`s of `RegisterRoutes` when routes are tagged or `-routerOptions` is set. Redirects, mounts, `-serveSpec`, route providers and groups gated by `-tagGroups` stay in `RegisterRoutes`. `-stripPrefix` and `-caseInsensitive` only wrap `RegisterRoutes`. A version directory cannot be gated by `-tagGroups` or host a `-spa` app, and the echo backend does not support the flag. Without any version directory, the flag prints a warning.
+
+## Registering Groups From Their Packages
+
+By default `RegisterRoutes` registers every route itself. `-target=packages` moves the registration of each top-level group into the group's own package instead, as a `register_gen.go` next to its handlers:
+
+```go
+// api/users/register_gen.go
+package users
+
+func Register(r *mux.Router) {
+	r.HandleFunc("", Get).Methods("GET")
+	r.HandleFunc("/{id}", users_id.Get).Methods("GET")
+}
+```
+
+```go
+// routes_gen.go
+usersRouter := r.PathPrefix("/users").Subrouter()
+usersRouter.Use(authMiddleware)
+users.Register(usersRouter)
+```
+
+The main file creates the group's subrouter with its middleware and 405 handler as before and imports only the group's package, which in turn imports the packages of its subdirectories. Routes and nested groups of the group move into `Register`, in the same order, so matching is unchanged. Handlers of the group's own package are referred to without a qualifier. Routes under the api root itself and `-skipMiddleware` variants stay in the main file.
+
+The import goes from the router's package to the group's, so `register_gen.go` cannot refer to anything the router's package declares. Middleware of nested groups, guards and handler wrappers must come from other packages, such as `middleware.Auth` with `-middleware`. An unqualified name, for example `authMiddleware`, or a route wrapped in a generated helper such as `-validateBodies`, `-produces` or a typed parameter parser, is an error naming the route. So are tagged routes, `-handlerReceiver` methods, a group without a directory, and a package that declares `Register` already. Handlers below the group may not import the group's package, directly or through other packages of the api directory, since the group's package now imports them: the cycle is reported with the chain of packages, for example `api/users/settings` importing `api/users` for a shared constant, which then belongs in a package of its own. The flag cannot be combined with `-out=-`, `-emitRegistryOnly`, `-profile`, `-tagGroups`, `-versionDirs` or `-emitHandlerMap`, and the echo backend does not support it. A `register_gen.go` that is no longer generated, for example after switching back to `-target=file`, is removed on the next run.
+
+## Redirects
+
+To redirect an old path, add a `redirect.txt` to the directory that would serve it:
+
+```
+api/
+  old-users/
+    redirect.txt    # contains: => /users
+```
+
+Redirects can also be declared centrally:
+
+```bash
+fsrouter -redirects='{"/legacy":"302 => /auth/login"}'
+```
+
+Values take the form `[status] => target`. The status defaults to `301`; `302`, `303`, `307` and `308` are also accepted. Each redirect is registered on the top-level router:
+
+```go
+r.HandleFunc("/old-users", http.RedirectHandler("/users", 301).ServeHTTP)
+```
+
+With `-stripPrefix=/api`, a target starting with `/` is a path below the mount prefix like the routes themselves, so `/api/old-users` redirects to `/api/users`. Targets with a scheme or host, such as `https://example.com/users`, are used as they are.
+
+## Customizing the Default 404
+
+The default 404 handler can be tuned without writing Go:
+
+```bash
+fsrouter -notFoundStatus=410 -notFoundBody='{"error": "gone", "path": "%s"}'
+```
+
+The body may contain at most one `%s`, which receives the request path JSON-escaped; use `%%` for a literal percent sign. The default body is written as it always was, with the path as is. The response is served as `application/json`. These flags have no effect when `-notFound` is set.
+
+## Per-Group 405 Responses
+
+A request whose path matches a route but whose method does not gets mux's plain 405. A group directory can answer it in its own error envelope with a `methodNotAllowed.go` file, which is not a handler file and must declare `func MethodNotAllowed(w http.ResponseWriter, r *http.Request)`:
+
+```go
+usersRouter := r.PathPrefix("/users").Subrouter()
+usersRouter.MethodNotAllowedHandler = http.HandlerFunc(users.MethodNotAllowed)
+```
+
+A `methodNotAllowed.go` at the root of the api directory becomes the router's `MethodNotAllowedHandler`, the default for groups without one. Nested groups without one fall back to their enclosing group's. Only route groups can have the file, except those gated by `-tagGroups`, and the echo backend does not support it.
+
+## Fallback Handlers
+
+A `fallback.go` file answers every method of its directory's path that has no handler file of its own, instead of the 405 or 404 the client would get otherwise:
+
+```
+api/
+  users/
+    get.go          # GET /users
+    fallback.go     # exports func Fallback(...), serving POST, DELETE, ... /users
+```
+
+The route is registered without `.Methods()`, after the last other route of its path, so mux tries the specific methods first:
+
+```go
+usersRouter.HandleFunc("", users.Get).Methods("GET")
+usersRouter.HandleFunc("", users.Fallback)
+```
+
+Its directives and `Paths` variable work as in other handler files, and of `-methodMiddlewares` only the `*` class applies. Since the path then always has a matching route, `methodNotAllowed.go` never answers for it. `-autoOptions` adds no `OPTIONS` route for the path, leaving `OPTIONS` requests to the fallback too; give the directory an `options.go` to answer them separately. The fallback has no entry in `-serveSpec` or `-genClient`, is listed as `* /users` by `-printTree` and `-emitHandlerMap`, and has an empty `Method` in `-emitRegistryOnly`. The echo backend does not support it.
+
+## Proxying Unimplemented Routes
+
+While migrating from an older service, `-proxyFallback=http://legacy:8080` sets the router's `NotFoundHandler` to an `httputil.NewSingleHostReverseProxy` for that URL. Endpoints can then move over one at a time: anything without a handler under `api/` falls through to the legacy backend. The URL must be an absolute `http` or `https` URL, and the flag cannot be combined with `-notFound`. The `net/http/httputil` and `net/url` imports are only emitted when it is set.
+
+## Hosting a Single-Page App
+
+`-spa='{"app":"web/dist"}'` serves a built frontend from the `app` group. The directory is embedded with `//go:embed`, so it is resolved relative to the generated file and must lie below it, and it must exist when generating:
+
+```go
+// Single-page apps, answering GET requests no route of their group matches
+appRouter.PathPrefix("/").Methods("GET", "HEAD").Handler(spaHandler(appSPAFiles, "web/dist", "/app"))
+```
+
+The fallback is registered after the group's routes, so `/app/...` handlers still win. Existing files such as `/app/assets/main.js` are served as they are, and any other path gets `index.html`, leaving it to client-side routing. Any group with a static prefix works, nested or not; groups gated by `-tagGroups` or in a `-versionDirs` version do not. As with `go:embed` in general, files starting with `.` or `_` are left out. A directory without `index.html` is reported as a warning.
+
+Files are served by `http.FileServer`, which takes the content type from the extension and sniffs the first bytes of files without one, so a `LICENSE` file is served as `text/plain`. `-spaCache='public, max-age=31536000, immutable'` sets a `Cache-Control` header on the app's files, suited to the content-hashed assets of most build tools. `index.html`, whether requested through a directory or as the fallback, gets `no-cache` instead, so browsers revalidate it and a deploy takes effect at once. The value is checked like `//fsrouter:cache`.
+
+## Mounting Other Handlers
+
+Handlers built elsewhere, such as a debug mux or a third-party admin UI, can be served from the generated router with `-mount`. It maps path prefixes to functions returning an `http.Handler`, either a `Func` of the generated package or an exported `import/path.Func`:
+
+```bash
+fsrouter -api=./api -importPREFIX=yourmodule/api -mount='{"/debug":"debugHandler","/vars":"expvar.Handler"}'
+```
+
+```go
+// Mounted handlers, before the groups so no {param} prefix shadows them
+r.PathPrefix("/debug/").Handler(debugHandler())
+r.PathPrefix("/vars/").Handler(expvarMount.Handler())
+```
+
+Each function is called once per `RegisterRoutes` call. Unlike the gRPC-Gateway below, the prefix is not stripped, so `net/http/pprof` style handlers that expect the full path work as they are; wrap the handler in `http.StripPrefix` yourself otherwise. Global middleware applies to mounted handlers too. Packages are imported as their last path element followed by `Mount`, once however many prefixes they serve. Prefixes must be static, cannot be nested in one another or the `-grpcPrefix`, and a route, redirect or `-serveSpec` path under one is an error.
+
+## Mounting a gRPC-Gateway
+
+Services exposing both REST and gRPC can serve their [gRPC-Gateway](https://github.com/grpc-ecosystem/grpc-gateway) from the same router. `-grpcGateway` names an exported `func() http.Handler` by import path, and `-grpcPrefix` the path it is mounted under:
+
+```bash
+fsrouter -api=./api -importPREFIX=yourmodule/api -grpcGateway=yourmodule/gw.Register -grpcPrefix=/grpc
+```
+
+```go
+// gRPC-Gateway, mounted before the groups so no {param} prefix shadows it
+r.PathPrefix("/grpc/").Handler(http.StripPrefix("/grpc", gateway.Register()))
+```
+
+The function typically creates a `runtime.ServeMux`, registers the generated gateway handlers on it and returns it; it is called once per `RegisterRoutes` call. The prefix is stripped, so the gateway sees the paths from its proto annotations. Global middleware applies to gateway requests as well. A route, redirect or `-serveSpec` path under the prefix is an error, since the gateway would shadow it. The package is imported as `gateway` only when the flag is set.
+
+## CORS
+
+`-cors` generates a `corsMiddleware` from JSON options instead of a hand-written one:
+
+```bash
+fsrouter -cors='{"origins":["https://app.example.com"],"methods":["GET","POST"],"credentials":true,"maxAge":600}'
+```
+
+`origins` is required and may be `["*"]` to allow any origin. `methods` defaults to `GET, HEAD, POST, PUT, PATCH, DELETE`, and `OPTIONS` is always allowed. Without `headers`, the requested headers are echoed back. `exposeHeaders` sets `Access-Control-Expose-Headers`, and `maxAge` is in seconds.
+
+By default the middleware is added globally, before any `-middlewares`. To limit CORS to some groups, reference `corsMiddleware` in `-groupMiddlewares` instead (or place it yourself in `-middlewares`):
+
+```bash
+fsrouter -cors='{"origins":["*"]}' -groupMiddlewares='{"public":"corsMiddleware"}'
+```
+
+mux only runs middleware once a route matches, so each router using `corsMiddleware` also gets an `OPTIONS` route registered after its handlers. Preflight requests therefore get a `204` response, even for paths with no handler. The route only matches requests with an `Access-Control-Request-Method` header, so other requests for unknown paths still reach `-notFound` or `-proxyFallback`, or get mux's `404`.
+
+### OPTIONS Responses
+
+`-autoOptions` registers an `OPTIONS` route for every path without an `options.go` or `fallback.go` handler, right after the path's other routes. It answers `204 No Content` with an `Allow` header listing the path's methods:
+
+```go
+usersRouter.Handle("/{userId}", optionsHandler("DELETE, GET, OPTIONS")).Methods("OPTIONS")
+```
+
+Combined with `-cors`, preflight requests get a complete answer for the requested path: `corsMiddleware` looks the matched route's path template up in the generated `corsPathMethods` and sends the same `Allow` header, with `Access-Control-Allow-Methods` narrowed to the path's methods that `-cors` allows. Paths without a route still get the global method list from the catch-all `OPTIONS` route. An `OPTIONS` route is only guarded by tags or `enabledIf` when all routes of its path share the same condition. Method middleware does not wrap it, so preflight requests are not rejected by authentication.
+
+## Setting Up Middleware
+
+Functions from the `-middleware` package are referenced through the `middleware` import alias, e.g. `-middlewares=middleware.Auth`. The package is only imported when at least one middleware refers to it; otherwise fsrouter warns that the flag had no effect.
+
+### Global Middleware
+
+You can specify multiple global middlewares using the `-middlewares` flag:
+
+```bash
+fsrouter -middlewares="loggingMiddleware,authMiddleware,corsMiddleware"
+```
+
+Middleware constructors that take arguments can be called in place. The call is emitted verbatim:
+
+```bash
+fsrouter -middleware=yourmodule/middleware -middlewares="middleware.RateLimit(100, time.Minute),loggingMiddleware"
+```
+
+```go
+r.Use(middleware.RateLimit(100, time.Minute))
+```
+
+This works in every middleware flag. Commas inside the parentheses do not split the list. Arguments may be literals, names, `pkg.Name` selectors and arithmetic on them. Anything else, such as nested calls or function literals, is rejected. The `time` import is added when an argument uses it; other packages must already be imported by the generated file.
+
+The generated `loggingMiddleware` prints the method and path of each request. `-requestLogFormat='{method} {path} {status} {duration}'` makes it print the given fields instead, with the text between them kept as is:
+
+```go
+start := time.Now()
+rec := &logRecorder{ResponseWriter: w, status: http.StatusOK}
+next.ServeHTTP(rec, r)
+fmt.Printf("%s %s %d %s\n", r.Method, r.URL.Path, rec.status, time.Since(start))
+```
+
+The fields are `{method}`, `{path}`, `{query}`, `{remote}`, `{status}`, `{bytes}` and `{duration}`, and any other is an error. `{status}` and `{bytes}` wrap the `ResponseWriter` in a `logRecorder` that keeps the first status written, `200` if none, and counts the body bytes. It implements `Unwrap`, so `http.ResponseController` still reaches the original writer. The flag is unrelated to `-logFormat`, which formats fsrouter's own output. mux runs middleware only for matched routes, so requests answered by the 404 handler are not logged.
+
+### Group-Specific Middleware
+
+There are two ways to set up group-specific middleware:
+
+1. Using the `-groupMiddlewares` flag with a JSON string:
+
+```bash
+fsrouter -groupMiddlewares='{"users":"authMiddleware,rateLimit","admin":"adminAuthMiddleware"}'
+```
+
+A group's value may also be an array of strings, as in `{"users":["authMiddleware","rateLimit"]}`.
+
+Keys may also name a deeper directory relative to the api root. That directory becomes a nested group under its closest enclosing group, and its `[param]` segments become part of the subrouter prefix:
+
+```bash
+fsrouter -groupMiddlewares='{"orgs/[orgId]/projects":"orgMemberMiddleware"}'
+```
+
+```go
+orgsRouter := r.PathPrefix("/orgs").Subrouter()
+orgs_orgId_projectsRouter := orgsRouter.PathPrefix("/{orgId}/projects").Subrouter()
+orgs_orgId_projectsRouter.Use(orgMemberMiddleware)
+```
+
+Every route under `api/orgs/[orgId]/projects/` is registered on the nested group, so its middleware applies to all of an org's projects.
+
+When several groups share a stack, define it once with `-chains` and reference it as `@name`:
+
+```bash
+fsrouter -chains='{"secure":"authMiddleware,csrfMiddleware,loggingMiddleware"}' -groupMiddlewares='{"admin":"@secure","billing":"@secure,auditMiddleware"}'
+```
+
+Chains are expanded in place before the code is generated, so `adminRouter` gets one `Use` call per middleware in the chain. They may reference other chains and also work in `-middlewares`. Unknown chains and cycles are errors.
+
+2. Editing the generated code (will be overwritten on regeneration):
+
+```go
+// After generation, you can manually edit:
+usersRouter.Use(authMiddleware)
+adminRouter.Use(adminAuthMiddleware)
+```
+
+### Method-Based Middleware
+
+`-methodMiddlewares` applies middleware by the kind of request rather than by group, e.g. authentication for every mutating route across the API:
+
+```bash
+fsrouter -methodMiddlewares='{"write":"authMiddleware","read":"cacheMiddleware"}'
+```
+
+`read` covers `GET`, `HEAD` and `OPTIONS`, `write` covers `POST`, `PUT`, `PATCH` and `DELETE`, and `*` covers every route. mux middleware cannot see which route's method matched, so these wrap each handler at its registration instead of using `r.Use`:
+
+```go
+usersRouter.Handle("/{userId}", authMiddleware(http.HandlerFunc(users_userId.Post))).Methods("POST")
+```
+
+They are the outermost per-route layer, outside timeouts, and `*` middleware runs before a class's. Chains (`@name`) may be used here too.
+
+### Development-Only Middleware
+
+Middleware such as a profiler can be limited to development builds:
+
+```bash
+fsrouter -devMiddlewares="pprofMiddleware"
+```
+
+This writes a second file next to `-out` (`routes_dev_gen.go` for `routes_gen.go`) guarded by `//go:build dev`. The main file stays tag-neutral: it declares a `devMiddlewareHook` and calls it after the global middleware when the dev file has set it. Build with `go build -tags dev` to include the middleware. When the flag is dropped, the generated dev file is removed on the next run.
+
+### Final Middleware
+
+Use `-finalMiddleware` for a response finalizer that must run last, closest to the handler (e.g. setting security headers):
+
+```bash
+fsrouter -middleware=yourmodule/middleware -finalMiddleware=middleware.SecurityHeaders
+```
+
+Unlike global middleware registered with `r.Use`, which mux applies around the whole matched chain in registration order, the final middleware wraps each handler directly at its registration:
+
+```go
+usersRouter.Handle("/{userId}", middleware.SecurityHeaders(http.HandlerFunc(users_userId.Get))).Methods("GET")
+```
+
+It only runs once a route has matched, always after every global and group middleware, and can still modify the `ResponseWriter` before the handler writes.
+
+### Panic Recovery
+
+Every generated router recovers from panics. `recoverMiddleware` is registered before all other global middleware, so a panic in a handler or in any middleware is caught. It logs the panic value with the request method, path and stack trace through the standard `log` package and answers `500 Internal Server Error`. `http.ErrAbortHandler` is re-panicked, so deliberately aborted responses behave as without the middleware.
+
+```go
+r.Use(recoverMiddleware)
+r.Use(loggingMiddleware)
+```
+
+Pass `-noRecover` to leave it out, for example when your own recovery middleware is listed in `-middlewares`. Listing `recoverMiddleware` in `-middlewares` or `-groupMiddlewares` places it yourself.
+
+### Request IDs
+
+`-requestID` generates `requestIDMiddleware` and registers it before every other global middleware except `recoverMiddleware`, so logging and recovery middleware already see the ID. Each request keeps its incoming `X-Request-ID` header when it is at most 128 printable ASCII characters; otherwise it gets a random UUID. The ID is set on the response header and stored in the request context under the generated `RequestIDKey{}` type:
+
+```go
+id := RequestIDFromContext(r.Context())
+```
+
+Handler packages cannot import the package holding the router, so the middleware also sets the ID on the request header, where they read it with `r.Header.Get("X-Request-ID")`. List `requestIDMiddleware` in `-middlewares` or `-groupMiddlewares` to place it yourself. Like all `r.Use` middleware it only runs for requests that match a route.
+
+### Request Body Limits
+
+`-maxBodySize=1MB` generates a `maxBodySize(limit int64)` middleware factory and applies it globally, inside request IDs and recovery and outside the rest:
+
+```go
+r.Use(maxBodySize(1 << 20))
+```
+
+Sizes are whole numbers of bytes, `KB`, `MB` or `GB`, counted in powers of 1024. A request declaring a larger `Content-Length` gets `413 Request Entity Too Large` before any handler runs. Other bodies are wrapped in `http.MaxBytesReader`, so reading past the limit fails with an `*http.MaxBytesError` that the handler should answer with 413; the `-validateBodies` middleware does so.
+
+The factory can also be called from `-groupMiddlewares` or `-methodMiddlewares` with a size in bytes, e.g. `'{"uploads":"maxBodySize(50 << 20)"}'`, and is generated whenever one of them does. Limits nest, so a group's limit can only lower the global one; to let one group accept larger uploads, leave `-maxBodySize` unset and give each group its own. List a `maxBodySize(...)` call in `-middlewares` to place the global limit yourself.
+
+### Request Timeouts
+
+`-requestTimeout=30s` generates a `requestTimeout(d time.Duration)` middleware and applies it globally, outside every other middleware including recovery, and inside only `-securityHeaders`:
+
+```go
+r.Use(requestTimeout(30*time.Second))
+```
+
+Each request's context is cancelled once the duration has passed, so database calls and outgoing requests made with `r.Context()` give up with `context.DeadlineExceeded`. Unlike `http.TimeoutHandler` it writes no response of its own: a handler that ignores its context still runs to completion and answers normally. The factory can also be called from `-groupMiddlewares` or `-methodMiddlewares`, e.g. `'{"reports":"requestTimeout(2*time.Minute)"}'`, where a longer duration cannot extend the global deadline. List a `requestTimeout(...)` call in `-middlewares` to place it yourself.
+
+### Security Headers
+
+`-securityHeaders` generates `securityHeadersMiddleware` and applies it globally, outside every other middleware, so the responses of request timeouts and recovered panics carry the headers too. It sets a default set suited to a JSON API:
+
+| Header | Value |
+|--------|-------|
+| `Content-Security-Policy` | `default-src 'none'; frame-ancestors 'none'` |
+| `Cross-Origin-Opener-Policy` | `same-origin` |
+| `Referrer-Policy` | `strict-origin-when-cross-origin` |
+| `Strict-Transport-Security` | `max-age=63072000; includeSubDomains` |
+| `X-Content-Type-Options` | `nosniff` |
+| `X-Frame-Options` | `DENY` |
+
+`-securityHeaderValues` takes a JSON mapping of header to value. A default header gets the new value, an empty value drops it, and other names are added:
+
+```bash
+fsrouter -securityHeaders -securityHeaderValues='{"X-Frame-Options":"SAMEORIGIN","Strict-Transport-Security":"","Permissions-Policy":"camera=()"}'
+```
+
+The headers end up in a `securityHeaders` variable in the generated file, one per line with a comment saying what it is for. The middleware sets them before calling the handler, so a handler can still replace or delete one for its own responses. Header names are canonicalized. An invalid name, a multi-line value, or dropping a header that is not a default is an error. List `securityHeadersMiddleware` in `-middlewares` or `-groupMiddlewares` to place it yourself. Like all `r.Use` middleware it only runs for requests that match a route, so 404 responses go without the headers.
+
+### Response Compression
+
+`-gzip` generates a `gzipCompress(minSize int)` middleware factory using `compress/gzip` and applies it globally, outside the other middleware from `-middlewares` so their output is compressed too:
+
+```go
+r.Use(gzipCompress(1 << 10))
+```
+
+A response is compressed when the request's `Accept-Encoding` allows gzip, its body reaches `-gzipMinSize` (`1KB` by default, in the units of `-maxBodySize`) and its `Content-Type` is compressible: `text/*`, JSON, XML, JavaScript and WebAssembly, or any `+json` or `+xml` type. Until then the start of the body is held back, so smaller responses, `HEAD` requests, `204` and `304` responses, images and bodies that already set `Content-Encoding` are sent unchanged. A missing `Content-Type` is sniffed with `http.DetectContentType` first. Compressed responses drop `Content-Length`, and every response gets `Vary: Accept-Encoding`. Flushing ends the holding back early, so a streamed response flushed before reaching the minimum size is sent uncompressed.
+
+Like `maxBodySize`, the factory can be called from `-groupMiddlewares` or `-methodMiddlewares` instead, e.g. `'{"reports":"gzipCompress(256)"}'`, and listing a `gzipCompress(...)` call in `-middlewares` places the global one yourself. Applying it at two levels is harmless, since the outer one passes responses the inner one compressed through unchanged.
+
+### Creating Custom Middleware
+
+Define your middleware functions in your application code:
+
+```go
+// In your middleware package
+func authMiddleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        // Authentication logic here
+        token := r.Header.Get("Authorization")
+        if !validateToken(token) {
+            http.Error(w, "Unauthorized", http.StatusUnauthorized)
+            return
+        }
+        next.ServeHTTP(w, r)
+    })
+}
+```
+
+## OpenTelemetry Tracing
+
+`-otel` wraps every route's handler in a generated `otelSpan` that starts an OpenTelemetry server span per request:
+
+```go
+usersRouter.Handle("/{userId}", otelSpan("GET", "/users/{userId}", http.HandlerFunc(users_userId.Get))).Methods("GET")
+```
+
+Span names follow the HTTP semantic conventions, the method and the route's path template such as `GET /users/{userId}`, taken from the scanned routes rather than from the request, so they stay few however many users there are. A trace propagated in the request headers is continued with the global propagator, and the span's context is passed on in `r.Context()` for the handler's own spans. Each span records `http.request.method`, `http.route`, `url.path` and `http.response.status_code`, and is marked as an error for 5xx responses. Paths are those the router matches, without a `-stripPrefix`.
+
+Spans come from the global tracer provider, so configure it with `otel.SetTracerProvider` and `otel.SetTextMapPropagator` at startup; until then they are no-ops. The span covers the per-route layers, such as directives and `-methodMiddlewares`, but not global or group middleware, which wrap the routers outside it. The generated file imports `go.opentelemetry.io/otel` and its `attribute`, `codes`, `propagation` and `trace` packages under `otel`-prefixed aliases, so the module must be required with `go get go.opentelemetry.io/otel`; without the flag nothing references it.
+
+## Generating a Server
+
+`-emitServer` adds a production-ready starting point next to the router:
+
+```go
+func main() {
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+    defer stop()
+    log.Fatal(Run(ctx, NewServer(":3000")))
+}
+```
+
+`NewServer(addr)` returns an `*http.Server` with `RegisterRoutes()` as its handler (it takes the same `tags` when routes are tagged). `Run` serves until the context is cancelled, then calls `Shutdown` so in-flight requests can finish, and returns `nil` after a clean shutdown.
+
+The timeouts default to `readHeader` 5s, `read` 30s, `write` 60s, `idle` 120s and `shutdown` 10s. Override any of them with `-serverTimeouts`; `"0"` disables a server timeout, which is useful for streaming responses:
+
+```bash
+fsrouter -emitServer -serverTimeouts='{"write":"0","shutdown":"30s"}'
+```
+
+## Serving an OpenAPI Description
+
+`-serveSpec=/openapi.json` registers `GET /openapi.json` on the top-level router, serving an OpenAPI 3.0 skeleton of the router. fsrouter builds it from the same route model as the registrations, so it cannot drift from the routes. It is embedded as a constant, so the handler only writes precomputed bytes.
+
+Each route becomes an operation. Its `operationId` is the matching `-genClient` method name, such as `GetUsersUserID`. `{param}` segments become required string path parameters, and `//fsrouter:tag` tags become operation tags. A `request.schema.json` next to a `POST`, `PUT` or `PATCH` handler becomes the JSON request body schema. With `-stripPrefix`, the prefix is listed as the server URL. The title is the `-importPREFIX`.
+
+The document lists every route, including tagged routes that a `RegisterRoutes` call leaves out. Response schemas are not known, so each operation only has a `default` response.
+
+`-apiDocComment` describes groups with their package comments. The first sentence of the comment in a group directory's `doc.go`, or else in its first Go file with one, is written above the group's subrouter:
+
+```go
+// Route group for users
+// Package users manages user accounts.
+usersRouter := r.PathPrefix("/users").Subrouter()
+```
+
+In the OpenAPI document, each described group becomes a tag with that description, added to the operations of the group's routes. Routes of nested groups get the innermost group's tag. Groups declared only by `//fsrouter:group`, without a directory of their own, stay undescribed.
+
+## Generating a Client
+
+`-genClient=client_gen.go` writes a typed Go client with one method per route. Method names combine the HTTP method and the path, and `[param]` folders become string arguments that are path-escaped into the URL. `POST`, `PUT` and `PATCH` methods also take a request body:
+
+```go
+c := NewClient("http://users-service:3000")
+resp, err := c.GetUsersUserID(ctx, userID)
+resp, err = c.PostUsersUserID(ctx, userID, strings.NewReader(`{"name":"ada"}`))
+```
+
+Use `-clientPkg` to generate the client into a different package than the router.
+
+Parameter arguments are camelCased with Go initialisms by default, so `[user_id]` becomes `userID`. `-clientParamCase=snake` turns `[userId]` into `user_id` instead, and `raw` keeps the directory's name as is, which is an error when it is not a valid Go identifier. Names that would clash with a keyword or the method's own `ctx`, `body` and `c` get a `Param` suffix in every case. Only the argument names change; method names stay the same.
+
+## TypeScript Route Constants
+
+`-genTS=web/src/routes.ts` writes a TypeScript module with one export per route path, so frontend code builds URLs from the same tree as the router instead of hardcoding them. Static paths become string constants and `[param]` folders become function arguments, escaped with `encodeURIComponent`:
+
+```ts
+// GET, POST /users
+export const users = "/users" as const;
+
+// GET /users/{user_id}
+export const usersUserId = (userId: string): string =>
+  `/users/${encodeURIComponent(userId)}`;
+```
+
+Names are the camelCased path segments, `root` for `/`, with a `Path` suffix for JavaScript reserved words such as `/new`. Paths are those the router matches, without a `-stripPrefix`. Like `-genClient`, the file is compared by `check`.
+
+## Scaffolding From OpenAPI
+
+The `scaffold` subcommand goes the other way: it creates the `api/` tree from an existing OpenAPI contract, with one stub handler file per path and method:
+
+```bash
+fsrouter scaffold -openapi=spec.yaml -api=./api
+```
+
+`/users/{userId}` with `get` and `delete` operations becomes `api/users/[userId]/get.go` and `delete.go`. Each stub exports the matching handler (`Get`, `Delete`), which answers `501 Not Implemented` until its TODO is filled in. Files that already exist are never overwritten, so the command can be rerun as the spec grows. New files reuse the package name of existing files in their directory.
+
+The spec may be JSON or YAML. YAML is read with a small scanner that only needs the block-style `paths` mapping, so fsrouter does not depend on a YAML library. Parameters must span a whole segment; paths such as `/files/{name}.json` are an error.
+
+## Benchmarking Without Middleware
+
+`-profile` generates `RegisterRoutesRaw()` next to `RegisterRoutes()`. It registers the identical route set, redirects and 404 handler, but omits every `r.Use` call and per-route wrapping (final middleware, timeouts), so you can benchmark pure routing overhead against your full middleware stack:
+
+```go
+func BenchmarkRouting(b *testing.B) {
+    for _, r := range []http.Handler{RegisterRoutes(), RegisterRoutesRaw()} {
+        // ...
+    }
+}
+```
+
+### Benchmark Harness
+
+`-genBench=routes_bench_test.go` writes a `go test -bench` harness next to the router instead of leaving the loop to you. It holds one representative request per route and three benchmarks:
+
+```bash
+go test -run '^$' -bench . -benchmem
+```
+
+- `BenchmarkRoutes` sends each request to `RegisterRoutes()` in a sub-benchmark named after the route, such as `BenchmarkRoutes/GET_/users/{userId}`, using `httptest.NewRecorder`.
+- `BenchmarkRoutesRaw` does the same with `RegisterRoutesRaw()` and is only generated with `-profile`.
+- `BenchmarkAllRoutes` sends every request once per iteration, a single number to track as the API grows.
+
+`{param}` segments are filled in with `sample`, typed parameters with a value of their type such as `1`, and catch-alls with `a/b`. `//fsrouter:header` matches are set on the request, with `x` for a header that may have any value, and routes limited to `https` are requested with an `https://example.com` URL, which `httptest` marks as TLS. A fallback gets the first of `GET`, `POST`, `PUT`, `PATCH` and `DELETE` its path has no other handler for. `-stripPrefix` is prepended to every path. When routes are tagged, `RegisterRoutes` is called with all their tags. The variables of `//fsrouter:enabledIf` routes are set with `b.Setenv`, which an `-enabledIfFunc` predicate may not read. Routes of `-tagGroups` groups answer 404 unless the benchmark runs with their build tag.
+
+Handlers and middleware run as they would in production, so handlers with side effects should be faked out, or their sub-benchmarks left out with a narrower `-bench` pattern. The benchmark names only depend on the routes, so the results of `-backend=gorilla` and `-backend=echo` can be compared with `benchstat`. The file must end in `_test.go` and sit in the directory of `-out`, and `-genBench` cannot be combined with `-out=-`.
+
+## Handler Map
+
+`-emitHandlerMap` also generates `Handlers`, which maps each route's method and path to the handler the router would call, before any middleware or per-route wrapping:
+
+```go
+var Handlers = map[string]http.HandlerFunc{
+    "GET /users": users.Get,
+    "GET /users/{userId}": users_userId.Get,
+    "POST /users/{userId}": users_userId.Post,
+}
+```
+
+Test harnesses and custom routers can call handlers through it without going through `RegisterRoutes`. Keys use the route path as registered, with `{param}` segments, so a handler reading `mux.Vars` still needs them set on the request. JSON handlers appear through their `jsonHandler` adapter. The map lists routes regardless of their tags and `enabledIf` conditions; routes of groups gated by `-tagGroups` are added by the gated files when they are built.
+
+## Route Registry
+
+When fsrouter should only discover routes and your own server dispatches them, `-emitRegistryOnly` generates a route list instead of a router. The file imports the handler packages and `net/http`, but not gorilla/mux:
+
+```go
+var Routes = []RouteInfo{
+	{Method: "GET", Path: "/users", Group: "users", Handler: users.Get},
+	{Method: "GET", Path: "/users/{userId}", Params: []string{"userId"}, Group: "users", Handler: users_userId.Get},
+}
+```
+
+Each `RouteInfo` has the method and path, the `{param}` names, the `//fsrouter:header` matches and schemes, the group, and the `//fsrouter:tag` and `//fsrouter:enabledIf` values, so the server can decide registration itself. Its `Handler` is the handler as written, without middleware or the wrapping of directives such as `timeout`, `produces` or `scopes`. JSON handlers appear through the generated `jsonHandler` adapter. `methodNotAllowed.go` files are left out. Routes are listed in the order the router would register them, so `-dynamicLast` and `-groupOrder` still apply.
+
+Only flags that affect discovery or the files built from the routes can be combined with it, such as `-genClient`, `-genTS`, `-handlerStyle` and `-postProcess`; a flag that only configures the router, such as `-cors` or `-middlewares`, is an error.
+
+## Inspecting the Route Tree
+
+`-printTree` prints what the walker discovered, including groups, nested groups, routes, handlers and attached middleware, without writing any file:
+
+```
+$ fsrouter -importPREFIX=yourmodule/api -groupMiddlewares='{"users":"authMiddleware"}' -printTree
+r [recoverMiddleware, loggingMiddleware]
+├── auth (/auth)
+│   └── POST /auth/login -> auth_login.Post
+└── users (/users) [authMiddleware]
+    ├── GET /users/{userId} -> users_userId.Get
+    └── GET /users -> users.Get
+```
+
+### Explaining a Route
+
+`fsrouter explain` takes the `generate` flags and a `-route`, and prints the middleware that runs before the route's handler, from the outermost inwards, with where each comes from:
+
+```
+$ fsrouter explain -importPREFIX=yourmodule/api -groupMiddlewares='{"users":"authMiddleware"}' -route="GET /users/{id}"
+GET /users/{userId} -> users_userId.Get (api/users/[userId]/get.go)
+matchers: .Methods("GET")
+middleware, outermost first:
+   1. recoverMiddleware                                 global
+   2. loggingMiddleware                                 global
+   3. authMiddleware                                    group users
+   4. http.TimeoutHandler(h, 5*time.Second, "timeout")  //fsrouter:timeout
+```
+
+The chain lists the global middleware, then the `-routerOptions` and `-devMiddlewares` layers where set, the middleware of each enclosing group from the top-level one inwards, and the per-route layers: `-methodMiddlewares`, directives such as `timeout` or `produces`, generated wrappers such as typed parameter parsing, and `-finalMiddleware` last. `h` stands for the wrapped handler. The matchers are the chained `Methods`, `Headers` and `Schemes` calls, and a `registered:` line names the `//fsrouter:tag`, `//fsrouter:enabledIf` and `-tagGroups` conditions the route is registered under.
+
+`{param}` segments match any parameter, so `{id}` finds `{userId}`. `* /path` explains a fallback, and a bare `/path` every route of the path, which also lists the routes `//fsrouter:header` or `//fsrouter:scheme` tell apart. A route that does not exist is an error. Like `-printTree`, the command writes no file, and a `fsrouter.yaml` config applies to it as to `generate`.
+
+`-stats` prints a summary to stderr after generating, for tracking the API's growth or noticing an unexpectedly empty tree:
+
+```
+routes:          3 (GET 2, POST 1)
+groups:          2
+dynamic params:  1
+deepest nesting: 2
+middlewares:     3
+redirects:       0
+```
+
+Dynamic params count distinct `{param}` segments, so `/users/{userId}` and `/users/{userId}/posts` share one. Middlewares count distinct functions across global, group, method and final middleware. With `-logFormat=json` the summary is a single line, `{"level":"info","msg":"stats","stats":{"routes":3,"methods":{"GET":2,"POST":1},...}}`.
+
+## Echo Backend
+
+`-backend=echo` generates the same routes for [Echo](https://echo.labstack.com) v4.10 or later instead of gorilla/mux. `RegisterRoutes` then returns an `*echo.Echo`, which is still an `http.Handler`:
+
+```go
+r := echo.New()
+usersRouter := r.Group("/users") // route groups become Echo groups
+usersRouter.GET("/:userId", echoHandler(http.HandlerFunc(users_userId.Get)))
+```
+
+Handlers keep the `http.HandlerFunc` shape. The generated `echoHandler` adapts them and copies Echo's path parameters onto the request, so handlers read them with `r.PathValue("userId")` rather than `mux.Vars`. Middleware keeps the `func(http.Handler) http.Handler` shape too and is installed with `r.Use(echo.WrapMiddleware(...))` or the group's `Use`. Directives, body validation, CORS, request IDs, redirects, the 404 handler, `-proxyFallback`, `-serveSpec`, `-emitServer` and `-profile` work as with gorilla/mux. Echo runs middleware for unmatched requests as well, so no OPTIONS routes are added for CORS. `-stripPrefix`, `-encodedPath`, `-caseInsensitive`, `-tagGroups`, `-devMiddlewares`, `-autoOptions` and `-versionDirs` are not supported yet and are reported as errors.
+
+## Linting Generated Files
+
+When golangci-lint also checks generated files, `-nolint` adds a file-wide `//nolint` comment right before the package clause of the router, the dev and build-tag files and the client:
+
+```go
+// Code generated by fsrouter; DO NOT EDIT.
+//nolint:unparam,revive
+package main
+```
+
+Pass `all` to silence every linter, or a comma-separated list of linter names. The repository's lint configuration is left untouched.
+
+## Post-Processing Generated Files
+
+`-postProcess=./scripts/format.sh` runs a command of your own on every generated file, for example to add a copyright header or apply a house formatter. The contract:
+
+- The command gets the path of the file to process as its last argument, after any arguments given in the flag, e.g. `-postProcess="./scripts/license.sh 2026"`. Arguments are split on spaces, without shell quoting.
+- It rewrites that file in place. The file is a temporary copy next to the output with the same extension, so it sorts with the output but is hidden from the Go tool; the output itself is only written once the command succeeds.
+- It runs for every generated file: the router, the dev and build-tag files, the client and the TypeScript module, so check the extension when the tool only handles Go.
+- A non-zero exit status fails generation. Its output goes to stderr, so `-out=-` stays clean.
+
+Because the command's result is what gets written, `fsrouter check` and `-verify` run it as well and compare processed output, and files do not look stale after processing. The command is resolved before anything is generated, so a missing or non-executable script is reported up front.
+
+## Grouping Imports
+
+A large tree produces a long list of handler imports. `-groupImports` splits it into one block per top-level directory, each under a comment naming it:
+
+```go
+import (
+    "fmt"
+    "net/http"
+
+    // orgs
+    orgs "yourmodule/api/orgs"
+    orgs_orgId "yourmodule/api/orgs/[orgId]"
+
+    // users
+    users "yourmodule/api/users"
+    users_userId "yourmodule/api/users/[userId]"
+
+    // middleware
+    middleware "yourmodule/middleware"
+
+    "github.com/gorilla/mux"
+)
+```
+
+Handlers directly under `api/` are labeled with the api directory's name. Blocks are sorted by directory and imports by path within each, so gofmt leaves the grouping as generated.
+
+## Extending
+
+The generated code can be further customized as needed, but keep in mind that regeneration will overwrite your changes.
+
+Use the command-line flags whenever possible to avoid manual edits.
+
+### Route Providers
+
+For routes that do not come from the `api/` tree, such as those of plugins, `-routeProviders` generates a `RouteProvider` interface and a variadic `RegisterRoutes`:
+
+```go
+type RouteProvider interface {
+	Routes(r *mux.Router)
+}
+
+func RegisterRoutes(providers ...RouteProvider) *mux.Router
+```
+
+Each provider's `Routes` is called in order after the API routes and before the CORS preflight and `-spa` fallbacks, on the top-level router, so global middleware applies to its routes. A provider can add subrouters of its own. With the echo backend, `Routes` receives the `*echo.Echo`. `RegisterRoutesRaw` takes the same providers. Tagged routes already make `RegisterRoutes` variadic, so `//fsrouter:tag` is an error with this flag.
+
+### Runtime Options
+
+The 404 handler and global middleware are normally fixed at generation time. With `-routerOptions`, `RegisterRoutes` takes functional options instead, so one generated router can be configured differently by each caller without regenerating:
+
+```go
+type Option func(*routerOptions)
+
+func WithNotFound(h http.Handler) Option
+func WithMiddleware(mw ...func(http.Handler) http.Handler) Option
+
+func RegisterRoutes(opts ...Option) *mux.Router
+```
+
+```go
+r := api.RegisterRoutes(
+	api.WithNotFound(notFoundPage),
+	api.WithMiddleware(metrics, audit),
+)
+```
+
+`WithNotFound` replaces the `-notFound`, `-proxyFallback` or default 404 handler, and `-stripPrefix` and `-caseInsensitive` answer with it too. `WithMiddleware` runs its middleware after `-middleware` and the generated global middleware, in the order given. Calling it again adds more middleware, while a later `WithNotFound` replaces an earlier one. `RegisterRoutesRaw` takes the same options but ignores `WithMiddleware`. Routes stay fixed at generation time. With the echo backend, the middleware is wrapped with `echo.WrapMiddleware`. Like `-routeProviders`, this flag makes `RegisterRoutes` variadic, so it cannot be combined with `-routeProviders` or `//fsrouter:tag`.
 
 ## Example Generated Router
 
@@ -143,6 +1455,7 @@ func RegisterRoutes() *mux.Router {
     r := mux.NewRouter()
 
     // Global middleware
+    r.Use(recoverMiddleware)
     r.Use(loggingMiddleware)
     r.Use(authMiddleware)
     r.Use(corsMiddleware)
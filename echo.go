@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// echoModule is the module the echo backend's generated file imports. Its
+// RouteNotFound needs v4.10.0 or later.
+const echoModule = "github.com/labstack/echo/v4"
+
+const echoTemplate = `// Code generated by fsrouter; DO NOT EDIT.
+package {{.Package}}
+
+import (
+{{range .StdImports}}	"{{.}}"
+{{end}}{{range .Imports}}{{if .Comment}}
+	// {{.Comment}}
+{{end}}	{{.Alias}} "{{.Path}}"
+{{end}}
+	"{{echoModule}}"
+)
+{{if .HandlerMap}}{{template "handlerMap" .}}{{end}}
+{{range $reg := .Registrations}}
+{{if $reg.Raw}}// RegisterRoutesRaw creates and returns an Echo instance with the same
+// routes as RegisterRoutes but without any middleware, for benchmarking
+// routing overhead
+func RegisterRoutesRaw({{if $.Tagged}}tags ...string{{else if $.RouteProviders}}providers ...RouteProvider{{else if $.RouterOptions}}opts ...Option{{end}}) *echo.Echo {{"{"}}{{else}}// RegisterRoutes creates and returns an Echo instance with all API routes
+// registered{{if $.Tagged}}. Tagged routes are only registered when one of
+// their tags is requested.{{end}}{{if $.RouteProviders}}, followed by the
+// routes of the providers{{end}}{{if $.RouterOptions}}. The Options replace
+// its 404 handler or add global middleware{{end}}
+func RegisterRoutes({{if $.Tagged}}tags ...string{{else if $.RouteProviders}}providers ...RouteProvider{{else if $.RouterOptions}}opts ...Option{{end}}) *echo.Echo {{"{"}}{{end}}
+{{if $.RouterOptions}}	o := applyOptions(opts)
+{{end}}	r := echo.New()
+{{if $.Tagged}}
+	enabled := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		enabled[tag] = true
+	}
+{{end}}
+	// Default 404 handler
+	r.RouteNotFound("/*", echo.WrapHandler({{if $.ProxyFallback}}proxyFallback(){{else}}http.HandlerFunc({{if $.NotFound}}{{$.NotFound}}{{else}}defaultNotFoundHandler{{end}}){{end}}))
+{{if $.RouterOptions}}	if o.notFound != nil {
+		r.RouteNotFound("/*", echo.WrapHandler(o.notFound))
+	}
+{{end}}{{if not $reg.Raw}}
+	// Global middleware (applied to all routes)
+{{range $.Middlewares}}	r.Use(echo.WrapMiddleware({{.}}))
+{{end}}{{if $.RouterOptions}}	for _, mw := range o.middlewares {
+		r.Use(echo.WrapMiddleware(mw))
+	}
+{{end}}// Add more global middleware here
+	// r.Use(echo.WrapMiddleware(authMiddleware))
+{{end}}
+{{if $.Gateway}}	// gRPC-Gateway, mounted under its prefix
+	r.Any("{{$.Gateway.Prefix}}/*", echo.WrapHandler(http.StripPrefix("{{$.Gateway.Prefix}}", {{$.Gateway.Func}}())))
+{{end}}{{if $.Mounts}}	// Mounted handlers
+{{range $.Mounts}}	r.Any("{{.Prefix}}/*", echo.WrapHandler({{.Func}}()))
+{{end}}{{end}}{{if $.Receivers}}	// Handler structs, built once for all their routes
+{{range $.Receivers}}	{{.Var}} := {{.Alias}}.New()
+{{end}}{{end}}{{range $.Groups}}
+	// Route group for {{.Name}}{{if .Doc}}
+	// {{.Doc}}{{end}}
+	{{.Var}} := {{.Parent}}.Group("{{echoGroupPrefix .Prefix}}")
+{{if not $reg.Raw}}	// Group-specific middleware
+{{if .Middlewares}}{{$router := .Var}}{{range .Middlewares}}	{{$router}}.Use(echo.WrapMiddleware({{.}}))
+{{end}}{{else}}	// Add group-specific middleware here if needed
+	// {{.Var}}.Use(echo.WrapMiddleware(someMiddleware))
+{{end}}{{end}}{{end}}
+
+{{if $.Redirects}}
+	// Redirects
+{{range $.Redirects}}	r.Any({{printf "%q" (echoPath .From)}}, echo.WrapHandler(http.RedirectHandler({{printf "%q" .To}}, {{.Status}})))
+{{end}}{{end}}
+{{range $.Routes}}{{if .Guard}}	if {{.Guard}} {
+	{{end}}	{{.Router}}.{{echoMethod .Method}}"{{echoPath .SubPath}}", echoHandler({{if and .HandlerExpr (not $reg.Raw)}}{{.HandlerExpr}}{{else}}http.HandlerFunc({{.HandlerFunc}}){{end}}))
+{{if .Guard}}	}
+{{end}}{{end}}{{if $.SpecPath}}
+	// OpenAPI description of the routes
+	r.GET({{printf "%q" $.SpecPath}}, echo.WrapHandler(http.HandlerFunc(serveSpec)))
+{{end}}{{if $.RouteProviders}}
+	// Routes contributed by plugins
+	for _, p := range providers {
+		p.Routes(r)
+	}
+{{end}}
+	return r
+}
+{{end}}{{if .RouteProviders}}
+// RouteProvider contributes routes to the Echo instance built by
+// RegisterRoutes, for plugins. Routes is called after the API routes are
+// registered, on the instance running the global middleware
+type RouteProvider interface {
+	Routes(r *echo.Echo)
+}
+{{end}}
+// echoHandler adapts a net/http handler to Echo. Echo's path parameters are
+// copied to the request, where handlers read them with r.PathValue
+func echoHandler(h http.Handler) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		r := c.Request()
+		for _, name := range c.ParamNames() {
+			r.SetPathValue(name, c.Param(name))
+		}
+		h.ServeHTTP(c.Response(), r)
+		return nil
+	}
+}
+
+{{template "helpers" .}}`
+
+// echoMethods are the HTTP methods echo.Echo and echo.Group have a
+// registration method for; others are registered with Add.
+var echoMethods = standardMethods
+
+// echoFuncs are the template functions used by echoTemplate.
+var echoFuncs = template.FuncMap{
+	"echoModule": func() string { return echoModule },
+	"echoPath":   echoPath,
+	// A group served at / keeps the leading slash in its routes' paths,
+	// which Echo appends to the group prefix as they are.
+	"echoGroupPrefix": func(prefix string) string {
+		if prefix == "/" {
+			return ""
+		}
+		return echoPath(prefix)
+	},
+	"echoMethod": func(method string) string {
+		if slices.Contains(echoMethods, method) {
+			return method + "("
+		}
+		return fmt.Sprintf("Add(%q, ", method)
+	},
+}
+
+// echoPath rewrites a mux route path for Echo, e.g. /users/{userId}
+// becomes /users/:userId.
+func echoPath(path string) string {
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			segs[i] = ":" + seg[1:len(seg)-1]
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// checkEchoBackend reports the first option the echo backend cannot
+// generate, given the set flags, and the first path it cannot express.
+func checkEchoBackend(unsupported map[string]bool, routes []route, redirects []redirect) error {
+	for _, name := range slices.Sorted(maps.Keys(unsupported)) {
+		if unsupported[name] {
+			return fmt.Errorf("-%s is not supported by the echo backend", name)
+		}
+	}
+	paths := make([]string, 0, len(routes)+len(redirects))
+	for _, rt := range routes {
+		if len(rt.Headers) > 0 {
+			return fmt.Errorf("%s %s: //fsrouter:header is not supported by the echo backend", rt.methodLabel(), rt.RoutePath)
+		}
+		if len(rt.Schemes) > 0 {
+			return fmt.Errorf("%s %s: //fsrouter:scheme is not supported by the echo backend", rt.methodLabel(), rt.RoutePath)
+		}
+		if rt.Method == "" {
+			return fmt.Errorf("%s: fallback.go files are not supported by the echo backend", rt.RoutePath)
+		}
+		paths = append(paths, rt.RoutePath)
+	}
+	for _, rd := range redirects {
+		paths = append(paths, rd.From)
+	}
+	for _, p := range paths {
+		for _, seg := range strings.Split(p, "/") {
+			param := strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}")
+			if param && strings.Contains(seg, ":") || !param && strings.ContainsAny(seg, "{}:*") {
+				return fmt.Errorf("path %s cannot be expressed as an echo route, only whole-segment {param}s are supported", p)
+			}
+		}
+	}
+	return nil
+}
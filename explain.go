@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// explainLayer is one middleware of an explained route, with where it is
+// configured.
+type explainLayer struct {
+	Expr   string
+	Source string
+}
+
+// explainOptions are the generate flags deciding the layers outside the
+// route model.
+type explainOptions struct {
+	Root            string
+	DevMiddlewares  []string
+	RouterOptions   bool
+	FinalMiddleware string
+	ScopeMiddleware string
+	EnabledIfFunc   string
+	Otel            bool
+}
+
+// explainRoutes prints, for "fsrouter explain", each route matching query,
+// "METHOD /path" or a bare path for all its methods, with the middleware
+// that runs before its handler from the outermost inwards, its matchers and
+// the conditions it is registered under. {param} segments of query match
+// any parameter, so "GET /users/{id}" finds /users/{userId}.
+func explainRoutes(w io.Writer, query string, routes []route, groups []group, middlewares []string, opts explainOptions) error {
+	method, p, ok := strings.Cut(strings.TrimSpace(query), " ")
+	if !ok {
+		method, p = "", method
+	}
+	method, p = strings.ToUpper(method), strings.TrimSpace(p)
+	if !strings.HasPrefix(p, "/") {
+		return fmt.Errorf("route %q must be \"METHOD /path\" or \"/path\", such as \"GET /users/{id}\"", query)
+	}
+
+	var matched []route
+	for _, rt := range routes {
+		if (method == "" || method == rt.methodLabel()) && explainPathMatch(p, rt.RoutePath) {
+			matched = append(matched, rt)
+		}
+	}
+	if len(matched) == 0 {
+		return fmt.Errorf("no route matches %s, -printTree lists them all", strings.TrimSpace(query))
+	}
+
+	byVar := make(map[string]group)
+	for _, g := range groups {
+		byVar[g.Var] = g
+	}
+	for i, rt := range matched {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s %s -> %s (%s)\n", rt.methodLabel(), rt.RoutePath, rt.HandlerFunc(), filepath.Join(opts.Root, filepath.FromSlash(rt.File)))
+
+		matchers := rt.Matchers()
+		if rt.Method != "" {
+			matchers = fmt.Sprintf(".Methods(%q)", rt.Method) + matchers
+		} else {
+			matchers = "fallback for the methods without a handler of their own" + matchers
+		}
+		fmt.Fprintf(w, "matchers: %s\n", matchers)
+
+		// Groups nest from the top-level router, whose middleware runs
+		// first.
+		var chain []group
+		for v := rt.Router; v != "r" && v != ""; v = byVar[v].Parent {
+			chain = append([]group{byVar[v]}, chain...)
+		}
+		var conditions []string
+		if len(chain) > 0 && chain[len(chain)-1].Gate != "" {
+			conditions = append(conditions, "compiled with -tags "+chain[len(chain)-1].Gate)
+		}
+		if len(rt.Tags) > 0 {
+			conditions = append(conditions, "when RegisterRoutes is called with "+strings.Join(rt.Tags, " or "))
+		}
+		if rt.EnabledIf != "" {
+			conditions = append(conditions, "if "+routeGuard(route{EnabledIf: rt.EnabledIf}, opts.EnabledIfFunc))
+		}
+		if len(conditions) > 0 {
+			fmt.Fprintf(w, "registered: %s\n", strings.Join(conditions, ", "))
+		}
+
+		var layers []explainLayer
+		for _, mw := range middlewares {
+			layers = append(layers, explainLayer{mw, "global"})
+		}
+		if opts.RouterOptions {
+			layers = append(layers, explainLayer{"WithMiddleware options", "RegisterRoutes call"})
+		}
+		for _, mw := range opts.DevMiddlewares {
+			layers = append(layers, explainLayer{mw, "-devMiddlewares, dev build tag"})
+		}
+		for _, g := range chain {
+			for _, mw := range g.Middlewares {
+				layers = append(layers, explainLayer{mw, "group " + g.Name})
+			}
+		}
+		wraps := routeWraps(rt, opts.FinalMiddleware, opts.ScopeMiddleware, opts.Otel)
+		for i := len(wraps) - 1; i >= 0; i-- {
+			layers = append(layers, explainLayer{strings.TrimSuffix(wraps[i].Wrap("h"), "(h)"), wraps[i].Source})
+		}
+		if len(layers) == 0 {
+			fmt.Fprintln(w, "middleware: none")
+			continue
+		}
+		width := 0
+		for _, l := range layers {
+			width = max(width, len(l.Expr))
+		}
+		fmt.Fprintln(w, "middleware, outermost first:")
+		for i, l := range layers {
+			fmt.Fprintf(w, "  %2d. %-*s  %s\n", i+1, width, l.Expr, l.Source)
+		}
+	}
+	return nil
+}
+
+// explainPathMatch reports whether the route path p of an explain query
+// names routePath, comparing {param} segments by position only.
+func explainPathMatch(p, routePath string) bool {
+	a, b := strings.Split(p, "/"), strings.Split(routePath, "/")
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] && !(strings.HasPrefix(a[i], "{") && strings.HasPrefix(b[i], "{")) {
+			return false
+		}
+	}
+	return true
+}
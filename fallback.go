@@ -0,0 +1,44 @@
+package main
+
+import "slices"
+
+// fallbackMethod is the method the file name of a fallback.go file would
+// register. Its route gets no method instead, answering every method of
+// its path that no other handler of the path registers.
+const fallbackMethod = "FALLBACK"
+
+// methodLabel returns the method of a route for messages and listings, "*"
+// for a fallback.
+func (rt route) methodLabel() string {
+	if rt.Method == "" {
+		return "*"
+	}
+	return rt.Method
+}
+
+// fallbacksLast moves each fallback route right after the last other route
+// of its path. mux tries routes in registration order, so the methods with
+// a handler of their own keep reaching it.
+func fallbacksLast(routes []route) {
+	for i := len(routes) - 1; i >= 0; i-- {
+		if routes[i].Method != "" {
+			continue
+		}
+		last := i
+		for j := i + 1; j < len(routes); j++ {
+			if routes[j].RoutePath == routes[i].RoutePath && routes[j].Method != "" {
+				last = j
+			}
+		}
+		if last > i {
+			fb := routes[i]
+			copy(routes[i:last], routes[i+1:last+1])
+			routes[last] = fb
+		}
+	}
+}
+
+// hasFallback reports whether path has a fallback route.
+func hasFallback(routes []route, path string) bool {
+	return slices.ContainsFunc(routes, func(rt route) bool { return rt.Method == "" && rt.RoutePath == path })
+}
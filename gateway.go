@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+)
+
+// gatewayAlias is the name the generated file imports the -grpcGateway
+// package as.
+const gatewayAlias = "gateway"
+
+// gatewayConfig mounts a user-provided handler, typically a gRPC-Gateway
+// runtime.ServeMux, under Prefix.
+type gatewayConfig struct {
+	// Path is the import path of the package declaring the function.
+	Path string
+	// Func is the qualified call target, e.g. gateway.Register.
+	Func   string
+	Prefix string
+}
+
+// parseGateway splits a -grpcGateway value such as yourmodule/gw.Register
+// into the package import path and function name, and validates the
+// -grpcPrefix it is mounted under.
+func parseGateway(value, prefix string) (*gatewayConfig, error) {
+	i := strings.LastIndex(value, ".")
+	if i <= strings.LastIndex(value, "/") || !token.IsIdentifier(value[i+1:]) || !token.IsExported(value[i+1:]) {
+		return nil, fmt.Errorf("grpcGateway must be an import path and exported function such as yourmodule/gw.Register, got %q", value)
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return nil, fmt.Errorf("grpcGateway requires a -grpcPrefix to mount the gateway under, such as /grpc")
+	}
+	if !strings.HasPrefix(prefix, "/") || strings.ContainsAny(prefix, "{}") {
+		return nil, fmt.Errorf("grpcPrefix must be a static path starting with /, got %q", prefix)
+	}
+	return &gatewayConfig{Path: value[:i], Func: gatewayAlias + "." + value[i+1:], Prefix: prefix}, nil
+}
+
+// checkGatewayPrefix reports the first route, redirect or spec path that
+// lies under the gateway prefix, where the gateway would shadow it.
+func checkGatewayPrefix(gw *gatewayConfig, routes []route, redirects []redirect, specPath string) error {
+	return checkShadowed(gw.Prefix, fmt.Sprintf("-grpcPrefix=%s, which the gateway serves", gw.Prefix), routes, redirects, specPath)
+}
+
+// checkShadowed reports the first route, redirect or spec path that lies
+// under prefix, where the handler mounted there would shadow it. owner
+// describes the mount in the error.
+func checkShadowed(prefix, owner string, routes []route, redirects []redirect, specPath string) error {
+	under := func(p string) bool { return p == prefix || strings.HasPrefix(p, prefix+"/") }
+	for _, rt := range routes {
+		if under(rt.RoutePath) {
+			return fmt.Errorf("route %s %s is under %s", rt.methodLabel(), rt.RoutePath, owner)
+		}
+	}
+	for _, rd := range redirects {
+		if under(rd.From) {
+			return fmt.Errorf("%s: redirect from %s is under %s", rd.Source, rd.From, owner)
+		}
+	}
+	if specPath != "" && under(specPath) {
+		return fmt.Errorf("serveSpec %s is under %s", specPath, owner)
+	}
+	return nil
+}
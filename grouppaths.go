@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// applyGroupPaths records the -groupPaths prefixes of groups in paths as
+// the segments replacing their directory names, as a _path file would.
+// Parents are applied before the groups nested in them, whose prefix must
+// extend the parent directory's path. pathFiles are the directories with a
+// _path file, which may not also be listed.
+func applyGroupPaths(fsys fs.FS, root string, groupPaths, paths map[string]string, pathFiles map[string]bool) error {
+	var names []string
+	for name := range groupPaths {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		return strings.Count(a, "/") - strings.Count(b, "/")
+	})
+	for _, name := range names {
+		display := filepath.Join(root, filepath.FromSlash(name))
+		if info, err := fs.Stat(fsys, name); err != nil || !info.IsDir() || name == "." || name == "" {
+			return fmt.Errorf("%s is not a directory of the api tree", name)
+		}
+		if pathFiles[name] {
+			return fmt.Errorf("%s has a %s file, set its path there or in -groupPaths", display, pathFileName)
+		}
+		prefix := "/" + strings.Trim(groupPaths[name], "/")
+		if prefix == "/" {
+			return fmt.Errorf("%s: a group needs a prefix such as /v1/users, got %q", name, groupPaths[name])
+		}
+		parent := routePath(parentDir(name), paths)
+		rest, ok := strings.CutPrefix(prefix, strings.TrimSuffix(parent, "/")+"/")
+		if !ok {
+			return fmt.Errorf("%s is nested under %s, its path must start with it, got %s", name, parent, prefix)
+		}
+		override, err := parsePathOverride(rest)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		paths[name] = override
+	}
+	return nil
+}
+
+// checkGroupPaths reports -groupPaths entries that are not route groups,
+// and groups sharing a prefix when one of them gets it from -groupPaths,
+// since only the first registered could serve it.
+func checkGroupPaths(groupNames []string, groupPaths, paths map[string]string) error {
+	for name := range groupPaths {
+		if !slices.Contains(groupNames, name) {
+			return fmt.Errorf("%s is not a route group", name)
+		}
+	}
+	prefixes := make(map[string]string)
+	for _, name := range groupNames {
+		prefix := routePath(name, paths)
+		if prev, ok := prefixes[prefix]; ok {
+			_, listed := groupPaths[name]
+			if _, prevListed := groupPaths[prev]; listed || prevListed {
+				return fmt.Errorf("%s and %s both have the prefix %s", prev, name, prefix)
+			}
+		}
+		prefixes[prefix] = name
+	}
+	return nil
+}
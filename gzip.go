@@ -0,0 +1,176 @@
+package main
+
+import "strings"
+
+// gzipName is the middleware factory generated for -gzip and for group or
+// method middleware calling it.
+const gzipName = "gzipCompress"
+
+// gzipImports are the standard library packages used by gzipTemplate.
+var gzipImports = []string{"compress/gzip", "strconv", "strings"}
+
+const gzipTemplate = `
+// gzipCompress compresses responses of at least minSize bytes with gzip when
+// the client accepts it and the content type is compressible. The start of
+// each response is buffered until minSize bytes are written or the handler
+// returns, so smaller responses are sent as they are
+func gzipCompress(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if r.Method == http.MethodHead || !gzipAccepted(r.Header.Get("Accept-Encoding")) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			gw := &gzipWriter{ResponseWriter: w, minSize: minSize}
+			defer gw.close()
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+// gzipAccepted reports whether an Accept-Encoding header allows gzip
+func gzipAccepted(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		switch strings.ToLower(strings.TrimSpace(coding)) {
+		case "gzip", "x-gzip", "*":
+			if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+				v, err := strconv.ParseFloat(q, 64)
+				return err == nil && v > 0
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// gzipCompressible reports whether responses of a content type shrink under
+// compression, unlike images and archives that are compressed already
+func gzipCompressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml", "application/wasm", "application/x-ndjson":
+		return true
+	}
+	return strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
+// gzipWriter holds back the status and start of a response for gzipCompress
+// until it can decide whether to compress it
+type gzipWriter struct {
+	http.ResponseWriter
+	minSize int
+	status  int
+	buf     []byte
+	// started is set once the header is sent, gz when the body is
+	// compressed
+	started bool
+	gz      *gzip.Writer
+}
+
+func (w *gzipWriter) WriteHeader(status int) {
+	switch {
+	case w.started, w.status != 0:
+	case status < http.StatusOK:
+		w.ResponseWriter.WriteHeader(status)
+	default:
+		w.status = status
+	}
+}
+
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	if w.started {
+		if w.gz != nil {
+			return w.gz.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) < w.minSize || len(w.buf) == 0 {
+		return len(b), nil
+	}
+	if err := w.start(); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// start sends the header, switching to gzip when the buffered body is large
+// enough and compressible, followed by the buffered body
+func (w *gzipWriter) start() error {
+	w.started = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	h := w.Header()
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if len(w.buf) > 0 && len(w.buf) >= w.minSize && h.Get("Content-Encoding") == "" &&
+		w.status != http.StatusNoContent && w.status != http.StatusNotModified && gzipCompressible(h.Get("Content-Type")) {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// close sends a response still held back when the handler returns and ends
+// the gzip stream
+func (w *gzipWriter) close() {
+	if !w.started && (w.status != 0 || len(w.buf) > 0) {
+		w.start()
+	}
+	if w.gz != nil {
+		w.gz.Close()
+	}
+}
+
+// Flush sends what is held back, so streamed responses reach the client as
+// they are written
+func (w *gzipWriter) Flush() {
+	if !w.started {
+		w.start()
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// set deadlines
+func (w *gzipWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+`
+
+// parseGzip parses a -gzipMinSize into the gzipCompress call applying it,
+// e.g. gzipCompress(1 << 10).
+func parseGzip(minSize string) (string, error) {
+	size, err := parseSize(minSize)
+	if err != nil {
+		return "", err
+	}
+	return gzipName + "(" + size + ")", nil
+}
+
+// isGzipCall reports whether a middleware entry is a call of the generated
+// gzipCompress factory.
+func isGzipCall(mw string) bool {
+	return strings.HasPrefix(mw, gzipName+"(")
+}
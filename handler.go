@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// findHandler returns the name of the function a handler file registers:
+// the exported top-level function whose name matches the file name
+// case-insensitively, e.g. Get in get.go. Other exported functions are
+// ignored, so a file may hold helpers next to its handler. The function must
+// have the shape of an http.HandlerFunc, or of a JSON handler
+// func(*http.Request) (any, error), reported by jsonAPI. With receivers, the
+// handler may also be a method of an exported type, returned as receiver.
+func findHandler(fset *token.FileSet, file *ast.File, fileName string, receivers bool) (name, receiver string, jsonAPI bool, err error) {
+	httpName := importName(file, "net/http")
+
+	var matches []*ast.FuncDecl
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || !fn.Name.IsExported() || !strings.EqualFold(fn.Name.Name, fileName) {
+			continue
+		}
+		if fn.Recv == nil || receivers && receiverType(fn) != "" {
+			matches = append(matches, fn)
+		}
+	}
+
+	filename := fset.Position(file.Package).Filename
+	switch len(matches) {
+	case 0:
+		return "", "", false, fmt.Errorf("%s: no handler function, want func %s(w http.ResponseWriter, r *http.Request)", filename, exportedName(strings.ToLower(fileName)))
+	case 1:
+	default:
+		return "", "", false, fmt.Errorf("%s: %s and %s both match %s.go, keep only one", fset.Position(matches[1].Pos()), matches[0].Name.Name, matches[1].Name.Name, fileName)
+	}
+
+	fn := matches[0]
+	if fn.Recv != nil {
+		receiver = receiverType(fn)
+	}
+	switch {
+	case isHandlerFunc(fn.Type, httpName):
+		return fn.Name.Name, receiver, false, nil
+	case isJSONHandlerFunc(fn.Type, httpName):
+		return fn.Name.Name, receiver, true, nil
+	}
+	return "", "", false, fmt.Errorf("%s: %s must have the signature func(w http.ResponseWriter, r *http.Request), or func(r *http.Request) (any, error) with -handlerStyle=jsonapi", fset.Position(fn.Pos()), fn.Name.Name)
+}
+
+// findMethodHandlers returns the routes of a -multiMethodFiles file: a copy
+// of rt for each exported function named after a standard method, such as
+// Get or Delete. A function of handler shape named otherwise is an error. So
+// is a file without such functions, unless receivers lets it hold the
+// struct instead.
+func findMethodHandlers(fset *token.FileSet, file *ast.File, rt route, receivers bool) ([]route, error) {
+	httpName := importName(file, "net/http")
+
+	var routes []route
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || !fn.Name.IsExported() || fn.Recv != nil && (!receivers || receiverType(fn) == "") {
+			continue
+		}
+		method := strings.ToUpper(fn.Name.Name)
+		plain, jsonAPI := isHandlerFunc(fn.Type, httpName), isJSONHandlerFunc(fn.Type, httpName)
+		switch {
+		case !slices.Contains(standardMethods, method) && (plain || jsonAPI):
+			return nil, fmt.Errorf("%s: %s is not named after an HTTP method such as Get or Post", fset.Position(fn.Pos()), fn.Name.Name)
+		case !slices.Contains(standardMethods, method):
+			continue
+		case !plain && !jsonAPI:
+			return nil, fmt.Errorf("%s: %s must have the signature func(w http.ResponseWriter, r *http.Request), or func(r *http.Request) (any, error) with -handlerStyle=jsonapi", fset.Position(fn.Pos()), fn.Name.Name)
+		}
+		if i := slices.IndexFunc(routes, func(r route) bool { return r.Method == method }); i >= 0 {
+			return nil, fmt.Errorf("%s: %s and %s both register %s, keep only one", fset.Position(fn.Pos()), routes[i].Handler, fn.Name.Name, method)
+		}
+		h := rt
+		h.Method, h.Handler, h.JSONAPI = method, fn.Name.Name, jsonAPI
+		if fn.Recv != nil {
+			h.Receiver = receiverType(fn)
+		}
+		routes = append(routes, h)
+	}
+	if len(routes) == 0 && !receivers {
+		return nil, fmt.Errorf("%s: no handler function, want functions named after HTTP methods such as func Get(w http.ResponseWriter, r *http.Request)", fset.Position(file.Package).Filename)
+	}
+	return routes, nil
+}
+
+// errEmptyHandler is returned for a handler file declaring no exported
+// function or method at all, such as an unfinished one.
+var errEmptyHandler = errors.New("no exported functions")
+
+// hasExportedFunc reports whether a file declares an exported function or
+// method.
+func hasExportedFunc(file *ast.File) bool {
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Name.IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+// declaresFunc reports whether a file declares an exported function or
+// method named name, matched case-insensitively.
+func declaresFunc(file *ast.File, name string) bool {
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Name.IsExported() && strings.EqualFold(fn.Name.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHandlerFunc reports whether a function type is
+// func(http.ResponseWriter, *http.Request), with net/http imported as
+// httpName.
+func isHandlerFunc(ft *ast.FuncType, httpName string) bool {
+	if ft.TypeParams != nil || ft.Results != nil && len(ft.Results.List) > 0 {
+		return false
+	}
+	params := fieldTypes(ft.Params)
+	if len(params) != 2 || httpName == "" {
+		return false
+	}
+	star, ok := params[1].(*ast.StarExpr)
+	return ok && isQualified(params[0], httpName, "ResponseWriter") && isQualified(star.X, httpName, "Request")
+}
+
+// isQualified reports whether expr is the qualified identifier pkg.name.
+func isQualified(expr ast.Expr, pkg, name string) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == name && isIdent(sel.X, pkg)
+}
+
+// importName returns the name a file refers to the package at path by, or
+// "" when the file does not import it.
+func importName(file *ast.File, path string) string {
+	for _, spec := range file.Imports {
+		p, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || p != path {
+			continue
+		}
+		if spec.Name != nil {
+			return spec.Name.Name
+		}
+		return path[strings.LastIndex(path, "/")+1:]
+	}
+	return ""
+}
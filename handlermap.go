@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+const handlerMapTemplate = `
+// Handlers maps "METHOD /path" to the handler registered for each route,
+// without middleware or per-route wrapping, for test harnesses and custom
+// routers. It holds every route, whether or not a RegisterRoutes call
+// registers it{{if .TagGroups}}; the files gated by build tags add their routes{{end}}
+var Handlers = map[string]http.HandlerFunc{
+{{range .Routes}}	{{handlerKey .}}: {{.NewHandlerFunc}},
+{{end}}}
+`
+
+// handlerKey returns the quoted Handlers map key of a route, e.g.
+// "GET /users/{userId}" or "* /users/{userId}" for a fallback, followed by
+// the //fsrouter:header and //fsrouter:scheme matches that tell routes of
+// the same path apart, as in "GET /users X-Api-Version=2" or
+// "GET /login https".
+func handlerKey(rt route) string {
+	key := rt.methodLabel() + " " + rt.RoutePath
+	for i := 0; i < len(rt.Headers); i += 2 {
+		key += " " + rt.Headers[i] + "=" + rt.Headers[i+1]
+	}
+	if len(rt.Schemes) > 0 {
+		key += " " + strings.Join(rt.Schemes, ",")
+	}
+	return strconv.Quote(key)
+}
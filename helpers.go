@@ -0,0 +1,80 @@
+package main
+
+// helpersTemplate holds the functions the generated file defines for every
+// backend: the default middleware and 404 handler and whatever the enabled
+// features need.
+const helpersTemplate = `{{if .RequestLog}}{{template "requestLog" .}}{{else}}// Default middleware for logging requests
+func loggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Printf("%s %s\n", r.Method, r.URL.Path)
+		next.ServeHTTP(w, r)
+	})
+}
+{{end}}
+{{if .SpecPath}}{{template "spec" .}}{{end}}
+{{if .Server}}{{template "server" .}}{{end}}
+{{if .CORS}}{{template "cors" .}}{{end}}
+{{if .Recover}}{{template "recover" .}}{{end}}
+{{if .ParamParsers}}{{template "paramParsers" .}}{{end}}
+{{if .RequestID}}{{template "requestID" .}}{{end}}{{if .SecurityHeaders}}{{template "securityHeaders" .}}{{end}}
+{{if .CaseInsensitive}}{{template "caseInsensitive" .}}{{end}}
+{{if .JSONHandler}}{{template "jsonHandler" .}}{{end}}{{if .OptionsPaths}}{{template "options" .}}{{end}}{{if .SPAs}}{{template "spa" .}}{{end}}{{if .MaxBodySize}}{{template "maxBodySize" .}}{{end}}{{if .RequestTimeout}}{{template "requestTimeout" .}}{{end}}{{if .Gzip}}{{template "gzip" .}}{{end}}{{if .Otel}}{{template "otel" .}}{{end}}{{if .RequireJSON}}{{template "requireJSON" .}}{{end}}{{if .RouterOptions}}{{template "routerOptions" .}}{{end}}{{if .CatchAllSlash}}{{template "catchAllSlash" .}}{{end}}{{if .Produces}}
+// produces sets a default Content-Type, which the handler may still override
+func produces(contentType string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+{{end}}{{if .CacheControl}}
+// cacheControl sets the route's Cache-Control header, which the handler may
+// still override, e.g. for error responses
+func cacheControl(value string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", value)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+{{end}}{{if .Deprecated}}
+// deprecated marks the responses of a deprecated route with a Deprecation
+// header, and a Link to its successor and a Sunset date when known
+func deprecated(successor, sunset string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			if successor != "" {
+				w.Header().Set("Link", "<"+successor+">; rel=\"successor-version\"")
+			}
+			if sunset != "" {
+				w.Header().Set("Sunset", sunset)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+{{end}}{{if .Schemas}}{{template "validateBody" .}}{{end}}
+{{if .ProxyFallback}}
+// proxyFallback forwards requests that match no route to the legacy backend
+func proxyFallback() http.Handler {
+	target, err := url.Parse({{printf "%q" .ProxyFallback}})
+	if err != nil {
+		panic(err)
+	}
+	return httputil.NewSingleHostReverseProxy(target)
+}
+{{else if not .NotFound}}
+// Default 404 handler
+func defaultNotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader({{if eq .NotFoundStatus 404}}http.StatusNotFound{{else}}{{.NotFoundStatus}}{{end}})
+{{if .NotFoundDefault}}	w.Write([]byte({{"\x60"}}{"error": "404 not found", "path": "{{"\x60"}} + r.URL.Path + {{"\x60"}}"}{{"\x60"}}))
+{{else if .NotFoundHasPath}}	path, _ := json.Marshal(r.URL.Path)
+	fmt.Fprintf(w, {{printf "%q" .NotFoundBody}}, path[1:len(path)-1])
+{{else}}	fmt.Fprintf(w, {{printf "%q" .NotFoundBody}})
+{{end}}}
+{{end}}
+`
@@ -0,0 +1,115 @@
+package main
+
+import "go/ast"
+
+// handlerStyles are the values -handlerStyle accepts. http allows only
+// http.HandlerFunc handlers; jsonapi also allows JSON-returning ones.
+var handlerStyles = []string{"http", "jsonapi"}
+
+// jsonHandlerImports are the standard library packages used by
+// jsonHandlerTemplate.
+var jsonHandlerImports = []string{"encoding/json"}
+
+const jsonHandlerTemplate = `
+// jsonHandler adapts a handler returning a value and an error. The value is
+// written as JSON with 200 OK, the error is passed to {{.JSONErrorHandler}}
+func jsonHandler(h func(*http.Request) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		v, err := h(r)
+		if err != nil {
+			{{.JSONErrorHandler}}(w, r, err)
+			return
+		}
+		body, err := json.Marshal(v)
+		if err != nil {
+			{{.JSONErrorHandler}}(w, r, fmt.Errorf("encoding response: %w", err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(append(body, '\n'))
+	}
+}
+{{if eq .JSONErrorHandler "jsonError"}}
+// jsonError answers 500 Internal Server Error for an error returned by a
+// JSON handler, without revealing the error to the client
+func jsonError(w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInternalServerError)
+	fmt.Fprintln(w, ` + "`" + `{"error":"internal server error"}` + "`" + `)
+}
+{{end}}`
+
+// defaultJSONErrorHandler is the generated error handler used unless
+// -jsonErrorHandler is set.
+const defaultJSONErrorHandler = "jsonError"
+
+// HandlerFunc returns the expression for the route's handler as an
+// http.HandlerFunc-compatible function, adapting JSON handlers. Generated
+// routes have no package, their Handler is the expression itself. Method
+// handlers are called on the struct RegisterRoutes builds.
+func (rt route) HandlerFunc() string {
+	return rt.handlerFunc(rt.receiverVar())
+}
+
+// NewHandlerFunc is HandlerFunc for use outside RegisterRoutes, building
+// the struct of a method handler with its own New call.
+func (rt route) NewHandlerFunc() string {
+	return rt.handlerFunc(rt.Alias + "." + receiverConstructor + "()")
+}
+
+// receiverVar is the variable RegisterRoutes holds the route's handler
+// struct in.
+func (rt route) receiverVar() string {
+	return rt.Alias + "Handler"
+}
+
+func (rt route) handlerFunc(receiver string) string {
+	if rt.Alias == "" {
+		return rt.Handler
+	}
+	h := rt.Alias + "." + rt.Handler
+	if rt.Receiver != "" {
+		h = receiver + "." + rt.Handler
+	}
+	if rt.JSONAPI {
+		return "jsonHandler(" + h + ")"
+	}
+	return h
+}
+
+// isJSONHandlerFunc reports whether a function type is
+// func(*http.Request) (any, error), with net/http imported as httpName.
+// interface{} is accepted for any.
+func isJSONHandlerFunc(ft *ast.FuncType, httpName string) bool {
+	if ft.TypeParams != nil || httpName == "" {
+		return false
+	}
+	params, results := fieldTypes(ft.Params), fieldTypes(ft.Results)
+	if len(params) != 1 || len(results) != 2 {
+		return false
+	}
+	star, ok := params[0].(*ast.StarExpr)
+	if !ok || !isQualified(star.X, httpName, "Request") || !isIdent(results[1], "error") {
+		return false
+	}
+	if iface, ok := results[0].(*ast.InterfaceType); ok {
+		return len(iface.Methods.List) == 0
+	}
+	return isIdent(results[0], "any")
+}
+
+// fieldTypes returns the type of each parameter or result in a field list,
+// repeating shared types such as the int in (a, b int).
+func fieldTypes(fields *ast.FieldList) []ast.Expr {
+	if fields == nil {
+		return nil
+	}
+	var types []ast.Expr
+	for _, field := range fields.List {
+		for range max(len(field.Names), 1) {
+			types = append(types, field.Type)
+		}
+	}
+	return types
+}
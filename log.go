@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// diag prints the generator's diagnostics. -logFormat switches it to JSON
+// lines once the flags are parsed.
+var diag = &logger{stdout: os.Stdout, stderr: os.Stderr, warnings: new(int)}
+
+// logger prints summaries to stdout and warnings and errors to stderr,
+// either as plain text or, for CI systems, as one JSON object per line
+// with level, msg and, when known, path fields. warnings counts the
+// warnings printed, shared with the loggers returned by at.
+type logger struct {
+	json     bool
+	path     string
+	stdout   io.Writer
+	stderr   io.Writer
+	warnings *int
+}
+
+// setFormat selects the output format from a -logFormat value.
+func (l *logger) setFormat(format string) error {
+	switch format {
+	case "text":
+		l.json = false
+	case "json":
+		l.json = true
+	default:
+		return fmt.Errorf("logFormat must be text or json, got %q", format)
+	}
+	return nil
+}
+
+// at returns a logger attaching path to its messages.
+func (l *logger) at(path string) *logger {
+	c := *l
+	c.path = path
+	return &c
+}
+
+func (l *logger) infof(format string, args ...any) {
+	l.print(l.stdout, "info", "", fmt.Sprintf(format, args...))
+}
+
+func (l *logger) warnf(format string, args ...any) {
+	*l.warnings++
+	l.print(l.stderr, "warning", "warning: ", fmt.Sprintf(format, args...))
+}
+
+// fatal prints an error, formatting args like fmt.Println, and exits.
+func (l *logger) fatal(args ...any) {
+	l.print(l.stderr, "error", "", strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+	os.Exit(1)
+}
+
+// fatalf prints a formatted error and exits.
+func (l *logger) fatalf(format string, args ...any) {
+	l.print(l.stderr, "error", "", fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+func (l *logger) print(w io.Writer, level, prefix, msg string) {
+	if !l.json {
+		fmt.Fprintln(w, prefix+msg)
+		return
+	}
+	line, _ := json.Marshal(struct {
+		Level string `json:"level"`
+		Msg   string `json:"msg"`
+		Path  string `json:"path,omitempty"`
+	}{level, msg, l.path})
+	fmt.Fprintln(w, string(line))
+}
@@ -1,191 +1,1258 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"io/fs"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"maps"
+	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"text/template"
+	"time"
+	"unicode"
 )
 
+// version is the fsrouter build version, set at build time with
+// -ldflags "-X main.version=v1.2.3".
+var version = "dev"
+
+// backends lists the router backends the generator can emit.
+var backends = []string{"gorilla", "echo"}
+
 type route struct {
+	// Method is empty for the handler of a fallback.go file, which answers
+	// the methods of its path without a handler of their own.
 	Method     string
 	RoutePath  string
+	SubPath    string
+	Dir        string
 	ImportPath string
 	Alias      string
 	Handler    string
 	Group      string
+	Router     string
+	// File is the slash-separated path of the handler file below the api
+	// directory.
+	File string
+
+	// Tags limit registration to RegisterRoutes calls requesting one of
+	// them. Untagged routes are always registered.
+	Tags []string
+	// Guard is the condition under which the route is registered, or empty
+	// when it is registered unconditionally.
+	Guard string
+	// Params are the typed [name:type] parameters of the route's directory,
+	// parsed by the generated ParamParser middleware.
+	Params      []typedParam
+	ParamParser string
+	// Schema names the generated request.schema.json variable validating
+	// the request body, when -validateBodies is set.
+	Schema string
+	// Timeout wraps the handler in http.TimeoutHandler when set.
+	Timeout time.Duration
+	// Produces is the default Content-Type set before the handler runs.
+	Produces string
+	// CacheControl is the Cache-Control header of //fsrouter:cache, set
+	// before the handler runs.
+	CacheControl string
+	// Scopes are the OAuth scopes of //fsrouter:scopes directives, checked
+	// by the -scopeMiddleware factory.
+	Scopes []string
+	// Headers are the name and value pairs of //fsrouter:header
+	// directives the request must match, an empty value matching any.
+	Headers []string
+	// Schemes are the URL schemes of //fsrouter:scheme, or of -scheme for
+	// routes without the directive, the request must use.
+	Schemes []string
+	// Deprecated is set by //fsrouter:deprecated, whose hints give the
+	// Successor path for the Link header and the Sunset HTTP date.
+	Deprecated bool
+	Successor  string
+	Sunset     string
+	// CatchAllSlash names the catch-all parameter whose value gets a leading
+	// slash, from //fsrouter:catchAll slash.
+	CatchAllSlash string
+	// Internal is set by //fsrouter:internal for routes that are registered
+	// but left out of the OpenAPI description, client and TypeScript module.
+	Internal bool
+	// Middlewares wrap the handler according to its method class, from
+	// -methodMiddlewares, outermost first.
+	Middlewares []string
+	// Skip lists group or method middleware the route opts out of.
+	Skip []string
+	// Receiver is the struct type a -handlerReceiver handler is a method
+	// of, built once per RegisterRoutes call by the package's New.
+	Receiver string
+	// JSONAPI is set for handlers of the func(*http.Request) (any, error)
+	// shape accepted by -handlerStyle=jsonapi, registered through the
+	// generated jsonHandler adapter.
+	JSONAPI bool
+	// EnabledIf names the environment variable that must be set for the
+	// route to be registered, from //fsrouter:enabledIf.
+	EnabledIf string
+	// RequireJSON wraps the handler in the generated requireJSON, from
+	// -requireJSON for POST, PUT and PATCH routes.
+	RequireJSON bool
+	// HandlerExpr is the wrapped handler registered with Handle, or empty
+	// when the handler is registered with HandleFunc as is.
+	HandlerExpr string
+}
+
+// redirect sends requests for From to To, declared by a redirect.txt file or
+// the -redirects flag.
+type redirect struct {
+	From   string
+	To     string
+	Status int
+	Source string
+}
+
+// importEntry is an aliased import in the generated file. Comment, when
+// set, starts a new block of imports under a // Comment line.
+type importEntry struct {
+	Path    string
+	Alias   string
+	Comment string
+}
+
+// group is a subrouter. Every first-level directory is a group; deeper
+// directories become nested groups of their closest ancestor group when
+// they are configured in -groupMiddlewares.
+type group struct {
+	Name        string
+	Var         string
+	Parent      string
+	Prefix      string
+	Middlewares []string
+	// Gate is the build tag the group is only compiled with, from
+	// -tagGroups.
+	Gate string
+	// Doc is the synopsis of the group's package comment, from
+	// -apiDocComment.
+	Doc string
+	// MethodNotAllowed answers requests matching a path of the group but
+	// none of its methods, from the group's methodNotAllowed.go.
+	MethodNotAllowed string
+	// Version names the register function of the -versionDirs directory
+	// the group belongs to, such as V1 for RegisterV1Routes.
+	Version string
+	// Register is the alias of the package whose Register function adds
+	// the routes of the top-level group, for -target=packages.
+	Register string
 }
 
 func main() {
-	src := flag.String("api", "api", "directory of API handlers")
-	out := flag.String("out", "routes_gen.go", "output file")
-	pkg := flag.String("pkg", "main", "package name for generated file")
-	importPre := flag.String("importPREFIX", "", "module import prefix for api")
-	middlewarePkg := flag.String("middleware", "", "package containing middleware functions")
-	middlewares := flag.String("middlewares", "loggingMiddleware", "comma-separated list of middleware functions to apply globally")
-	groupMiddlewares := flag.String("groupMiddlewares", "", "JSON mapping of group to middleware functions, e.g., '{\"users\":\"authMiddleware,rateLimit\"}'")
-	notFoundHandler := flag.String("notFound", "", "custom 404 handler (format: package.Handler)")
-	flag.Parse()
+	// Without a subcommand fsrouter generates, as it did before subcommands
+	// existed.
+	cmd, args := "generate", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+	switch cmd {
+	case "generate":
+		runGenerate(cmd, args, false)
+	case "check":
+		runGenerate(cmd, args, true)
+	case "explain":
+		runGenerate(cmd, args, false)
+	case "scaffold":
+		runScaffold(args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q, want generate, check, explain or scaffold\n", cmd)
+		os.Exit(2)
+	}
+}
+
+// runGenerate implements "fsrouter generate", which writes the router and
+// any companion files, and "fsrouter check", which takes the same flags but
+// only verifies that those files are up to date. "fsrouter explain" takes
+// them too, with -route, and prints the middleware of a route instead.
+func runGenerate(name string, args []string, check bool) {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: fsrouter %s [flags]\n", name)
+		flags.PrintDefaults()
+	}
+	src := flags.String("api", "api", "directory of API handlers")
+	var explainQuery string
+	if name == "explain" {
+		flags.StringVar(&explainQuery, "route", "", "route to explain, \"METHOD /path\" such as \"GET /users/{id}\", \"* /path\" for a fallback, or \"/path\" for all its methods")
+	}
+	out := flags.String("out", "routes_gen.go", "output file")
+	pkg := flags.String("pkg", "main", "package name for generated file")
+	backend := flags.String("backend", "gorilla", "router backend to generate for, see -listBackends")
+	importPre := flags.String("importPREFIX", "", "module import prefix for api")
+	middlewarePkg := flags.String("middleware", "", "package containing middleware functions")
+	routerOptions := flags.Bool("routerOptions", false, "make RegisterRoutes(opts ...Option) accept WithNotFound and WithMiddleware options configuring the router at call time")
+	target := flags.String("target", "file", "where route groups are registered: file, in the -out file, or packages, by a register_gen.go Register function in each top-level group's directory")
+	versionDirs := flags.Bool("versionDirs", false, "register each top-level version directory such as v1 with its own function, and generate RegisterV1Routes building a router of the version alone")
+	routeProviders := flags.Bool("routeProviders", false, "generate a RouteProvider interface and make RegisterRoutes(providers ...RouteProvider) register the routes plugins contribute")
+	requestLogFormat := flags.String("requestLogFormat", "", "fields printed by the generated loggingMiddleware, e.g. '{method} {path} {status} {duration}'; fields are method, path, query, remote, status, bytes and duration")
+	middlewares := flags.String("middlewares", "loggingMiddleware", "comma-separated list of middleware functions to apply globally")
+	groupMiddlewares := flags.String("groupMiddlewares", "", "JSON mapping of group to middleware functions, e.g., '{\"users\":\"authMiddleware,rateLimit\"}'")
+	groupPathsFlag := flags.String("groupPaths", "", "JSON mapping of group to the URL prefix it is mounted under instead of its directory path, e.g., '{\"users\":\"/v1/users\"}'")
+	methodMiddlewares := flags.String("methodMiddlewares", "", "JSON mapping of method class (read, write or *) to middleware functions wrapped around matching routes, e.g., '{\"write\":\"authMiddleware\"}'")
+	chainsFlag := flags.String("chains", "", "JSON mapping of chain name to middleware functions, referenced as @name in -middlewares and -groupMiddlewares, e.g., '{\"secure\":\"auth,csrf,logging\"}'")
+	notFoundHandler := flags.String("notFound", "", "custom 404 handler (format: package.Handler)")
+	redirectsFlag := flags.String("redirects", "", "JSON mapping of old path to redirect target, e.g., '{\"/old\":\"/new\",\"/tmp\":\"302 => /new\"}'")
+	notFoundStatus := flags.Int("notFoundStatus", 404, "status code written by the default 404 handler")
+	notFoundBody := flags.String("notFoundBody", defaultNotFoundBody, "response body of the default 404 handler; an optional %s is replaced with the JSON-escaped request path")
+	autoOptionsFlag := flags.Bool("autoOptions", false, "answer OPTIONS requests for paths without an OPTIONS handler with 204 and an Allow header listing their methods")
+	spaFlag := flags.String("spa", "", "JSON mapping of group to a directory of a built single-page app, embedded next to the output file and served for GET requests no route matches, e.g., '{\"app\":\"web/dist\"}'")
+	spaCache := flags.String("spaCache", "", "Cache-Control header for the files of -spa apps, e.g. 'public, max-age=31536000, immutable'; index.html gets no-cache")
+	corsFlag := flags.String("cors", "", "JSON CORS options generating corsMiddleware, e.g., '{\"origins\":[\"https://app.example.com\"],\"credentials\":true}'")
+	noRecover := flags.Bool("noRecover", false, "do not generate recoverMiddleware, which turns handler panics into 500 responses as the outermost global middleware")
+	requestTimeoutFlag := flags.String("requestTimeout", "", "cancel each request's context after this duration with the generated requestTimeout middleware, applied globally as the outermost middleware, e.g. 30s")
+	requireJSON := flags.Bool("requireJSON", false, "reject POST, PUT and PATCH requests with a body that is not application/json with 415 before the handler runs")
+	otelFlag := flags.Bool("otel", false, "start an OpenTelemetry span named after the route around each handler, importing go.opentelemetry.io/otel")
+	gzipFlag := flags.Bool("gzip", false, "compress responses with the generated gzipCompress middleware, applied globally")
+	gzipMinSize := flags.String("gzipMinSize", "1KB", "smallest response -gzip compresses, e.g. 1KB or 512B")
+	maxBodySizeFlag := flags.String("maxBodySize", "", "limit request bodies to this size with the generated maxBodySize middleware, applied globally, e.g. 1MB or 512KB")
+	requestID := flags.Bool("requestID", false, "generate requestIDMiddleware, applied globally as the outermost middleware, which sets an X-Request-ID on every request")
+	securityHeadersFlag := flags.Bool("securityHeaders", false, "generate securityHeadersMiddleware, applied globally as the outermost middleware, which sets hardening headers such as X-Content-Type-Options: nosniff on every response")
+	securityHeaderValues := flags.String("securityHeaderValues", "", "JSON mapping of header to value replacing or adding to the -securityHeaders defaults, an empty value dropping a default, e.g., '{\"X-Frame-Options\":\"SAMEORIGIN\"}'")
+	proxyFallback := flags.String("proxyFallback", "", "URL of a backend that requests matching no route are proxied to, e.g. http://legacy:8080")
+	scopeMiddleware := flags.String("scopeMiddleware", "", "factory func(scopes ...string) func(http.Handler) http.Handler wrapped around routes with //fsrouter:scopes directives (format: Func or package.Func)")
+	finalMiddleware := flags.String("finalMiddleware", "", "middleware wrapped around each handler as the innermost layer (format: package.Middleware)")
+	handlerStyle := flags.String("handlerStyle", "http", "handler signatures accepted: http, or jsonapi to also allow func(r *http.Request) (any, error) handlers whose result is written as JSON")
+	jsonErrorHandler := flags.String("jsonErrorHandler", "", "func(w http.ResponseWriter, r *http.Request, err error) answering errors from jsonapi handlers, defaults to a generic 500 (format: Func or package.Func)")
+	groupOrder := flags.String("groupOrder", "", "comma-separated list of top-level groups to register first, in this order, e.g. api,admin,app")
+	routeOrder := flags.String("routeOrder", "fs", "order routes are emitted in: fs (directory walk), alpha (by path and method) or rest (collection, then item routes of each resource); mux matches requests the same either way")
+	dynamicLast := flags.Bool("dynamicLast", true, "register {param} routes and groups after their static siblings, so /users/settings is not shadowed by /users/{userId}")
+	encodedPath := flags.Bool("encodedPath", false, "match routes against the encoded request path (mux UseEncodedPath), so {param} values keep escapes such as %2F")
+	caseInsensitive := flags.Bool("caseInsensitive", false, "lowercase request paths before routing, so legacy clients sending mixed-case paths still match")
+	grpcGateway := flags.String("grpcGateway", "", "func() http.Handler returning a gRPC-Gateway mux to mount under -grpcPrefix (format: import/path.Func)")
+	mountFlag := flags.String("mount", "", "JSON mapping of path prefix to a func() http.Handler serving everything under it, e.g., '{\"/debug\":\"debugHandler\",\"/admin\":\"yourmodule/admin.Handler\"}'")
+	grpcPrefix := flags.String("grpcPrefix", "", "path prefix the -grpcGateway handler is mounted under, stripped before it routes, e.g. /grpc")
+	stripPrefix := flags.String("stripPrefix", "", "mount prefix stripped from request paths before routing, e.g. /api")
+	validateBodies := flags.Bool("validateBodies", false, "validate JSON request bodies against request.schema.json files in handler directories")
+	devMiddlewares := flags.String("devMiddlewares", "", "comma-separated list of middleware functions applied only in builds with the dev tag")
+	tagGroupsFlag := flags.String("tagGroups", "", "JSON mapping of top-level group to a build tag its routes are only compiled with, e.g., '{\"admin\":\"admin\"}'")
+	emitServer := flags.Bool("emitServer", false, "also generate NewServer and Run, an http.Server with timeouts and graceful shutdown")
+	serverTimeouts := flags.String("serverTimeouts", "", "JSON server timeouts for -emitServer, e.g., '{\"readHeader\":\"5s\",\"write\":\"0\",\"shutdown\":\"30s\"}'")
+	serveSpecPath := flags.String("serveSpec", "", "also register a GET route at this path serving an OpenAPI description of the routes, e.g. /openapi.json")
+	genClient := flags.String("genClient", "", "also generate a typed HTTP client with one method per route into this file")
+	pathCase := flags.String("pathCase", "preserve", "case of the URL segments derived from directory names: preserve, kebab (user-profiles), snake (user_profiles) or lower (userprofiles)")
+	pathPolicy := flags.String("pathPolicy", "", "warn about URL segments breaking a naming policy: lowercase, kebab-lowercase or snake-lowercase; with -failOnWarnings a CI gate")
+	clientParamCase := flags.String("clientParamCase", "camel", "naming of path parameter arguments in the generated client: camel (userID), snake (user_id) or raw")
+	genBench := flags.String("genBench", "", "also generate a go test -bench harness sending a request to each route into this _test.go file next to -out")
+	genTS := flags.String("genTS", "", "also generate a TypeScript module exporting each route path as a constant into this file")
+	clientPkg := flags.String("clientPkg", "", "package name for the generated client (defaults to -pkg)")
+	profile := flags.Bool("profile", false, "also generate RegisterRoutesRaw, the same routes without any middleware, for benchmarking")
+	handlerReceiver := flags.Bool("handlerReceiver", false, "also accept handlers that are methods of a struct built by the package's New function, e.g. func (h *Users) Get(w, r)")
+	multiMethodFiles := flags.Bool("multiMethodFiles", false, "let handler files not named after a method register each exported function named after one, e.g. Get and Delete in route.go")
+	defaultMethod := flags.String("defaultMethod", "", "method registered for a directory's only handler file when it is not named after a method, e.g. GET for handler.go")
+	maxDepth := flags.Int("maxDepth", 0, "maximum number of directory levels in the api tree, 0 for unlimited")
+	dedupe := flags.Bool("dedupeHandlers", false, "reference handlers whose file is a symlink to another handler file through that file's package, dropping the symlink package's import")
+	emitRegistryOnly := flags.Bool("emitRegistryOnly", false, "generate only Routes, a []RouteInfo of the discovered routes with their handlers, instead of a router, for servers doing their own dispatch")
+	emitHandlerMap := flags.Bool("emitHandlerMap", false, "also generate Handlers, a map from \"METHOD /path\" to each route's unwrapped handler")
+	concurrency := flags.Int("concurrency", runtime.GOMAXPROCS(0), "number of handler files parsed in parallel")
+	groupImports := flags.Bool("groupImports", false, "split the generated handler imports into blocks commented with their top-level directory")
+	postProcessFlag := flags.String("postProcess", "", "command run on every generated file before it is written or checked, with the file's path appended, e.g. ./scripts/format.sh")
+	nolint := flags.String("nolint", "", "add a //nolint comment for these comma-separated linters, or all, to every generated file")
+	schemeFlag := flags.String("scheme", "", "comma-separated URL schemes every route must be requested with unless its //fsrouter:scheme directive says otherwise, e.g. https")
+	enabledIfFunc := flags.String("enabledIfFunc", "", "predicate func(name string) bool deciding //fsrouter:enabledIf routes instead of a non-empty environment variable (format: Func or package.Func)")
+	stats := flags.Bool("stats", false, "print a summary of the generated routes, groups, parameters and middlewares to stderr")
+	apiDocComment := flags.Bool("apiDocComment", false, "describe each group with the first sentence of its package comment, above its subrouter and as an OpenAPI tag")
+	strict := flags.Bool("strict", false, "report handler files without exported functions as errors instead of skipping them with a warning")
+	failOnWarnings := flags.Bool("failOnWarnings", false, "exit with an error after generating when any warning was printed")
+	verify := flags.Bool("verify", false, "check that the generated files are current like fsrouter check, printing a unified diff of each difference")
+	printRouteTree := flags.Bool("printTree", false, "print the discovered route tree and exit without writing a file")
+	showVersion := flags.Bool("version", false, "print the fsrouter version and exit")
+	listBackends := flags.Bool("listBackends", false, "print the available router backends and exit")
+	logFormat := flags.String("logFormat", "text", "format of warnings, errors and summaries: text or json")
+	configPath := flags.String("config", "", "fsrouter.yaml whose flag values apply to flags not given, found in the api directory or the closest parent up to the module root by default; none to use no config")
+	flags.Parse(args)
+	var fromConfig map[string]bool
+	if *configPath == "" {
+		var err error
+		if *configPath, err = findConfig(*src); err != nil {
+			diag.fatal("config:", err)
+		}
+	}
+	if *configPath != "" && *configPath != "none" {
+		data, err := os.ReadFile(*configPath)
+		if err != nil {
+			diag.fatal("config:", err)
+		}
+		settings, err := parseConfig(string(data))
+		if err == nil {
+			fromConfig, err = applyConfig(flags, settings)
+		}
+		if err != nil {
+			diag.at(*configPath).fatalf("config: %s: %v", *configPath, err)
+		}
+	}
+	if err := diag.setFormat(*logFormat); err != nil {
+		diag.fatal(err)
+	}
+
+	if *showVersion {
+		fmt.Println("fsrouter", buildVersion())
+		return
+	}
+	if *listBackends {
+		for _, b := range backends {
+			fmt.Println(b)
+		}
+		return
+	}
+	if name == "explain" {
+		switch {
+		case explainQuery == "":
+			diag.fatal("explain needs -route, such as -route=\"GET /users/{id}\"")
+		case *emitRegistryOnly:
+			diag.fatal("explain shows the middleware of the generated router, -emitRegistryOnly generates none")
+		}
+	}
 
 	if *importPre == "" {
-		fmt.Fprintln(os.Stderr, "importPREFIX is required")
-		os.Exit(1)
+		diag.fatal("importPREFIX is required")
+	}
+	if err := checkImportPrefix(*src, *out, *importPre); err != nil {
+		diag.fatal("importPREFIX:", err)
+	}
+	if *emitRegistryOnly {
+		if err := checkRegistryFlags(flags, fromConfig); err != nil {
+			diag.fatal(err)
+		}
+	}
+	if *out == stdoutPath {
+		switch {
+		case check || *verify:
+			diag.fatal("-out=- writes to stdout, there is no file to check")
+		case *devMiddlewares != "" || *tagGroupsFlag != "":
+			diag.fatal("-out=- writes a single file, -devMiddlewares and -tagGroups need files next to it")
+		case *genBench != "":
+			diag.fatal("-out=- writes to stdout, -genBench needs the router in a file to test")
+		}
+		// Keep stdout clean Go source for piping.
+		diag.stdout = os.Stderr
+	}
+	if *genClient == stdoutPath || *genTS == stdoutPath || *genBench == stdoutPath {
+		diag.fatal("only -out can be - to write to stdout")
+	}
+	if *nolint != "" {
+		if err := checkNolint(*nolint); err != nil {
+			diag.fatal(err)
+		}
+	}
+	var post *postProcessor
+	if *postProcessFlag != "" {
+		var err error
+		if post, err = parsePostProcess(*postProcessFlag); err != nil {
+			diag.fatal("postProcess:", err)
+		}
+	}
+	var reqLog *requestLog
+	if *requestLogFormat != "" {
+		var err error
+		if reqLog, err = parseRequestLogFormat(*requestLogFormat); err != nil {
+			diag.fatal("requestLogFormat:", err)
+		}
+	}
+	var reqTimeout string
+	if *requestTimeoutFlag != "" {
+		var err error
+		if reqTimeout, err = parseRequestTimeout(*requestTimeoutFlag); err != nil {
+			diag.fatal("requestTimeout:", err)
+		}
+	}
+	var gzipCall string
+	if *gzipFlag {
+		var err error
+		if gzipCall, err = parseGzip(*gzipMinSize); err != nil {
+			diag.fatal("gzipMinSize:", err)
+		}
+	}
+	var defaultSchemes []string
+	if *schemeFlag != "" {
+		var err error
+		if defaultSchemes, err = parseSchemes(*schemeFlag); err != nil {
+			diag.fatal("scheme:", err)
+		}
+	}
+	var securityHeaders []securityHeader
+	if *securityHeadersFlag {
+		var err error
+		if securityHeaders, err = parseSecurityHeaders(*securityHeaderValues); err != nil {
+			diag.fatal("securityHeaderValues:", err)
+		}
+	} else if *securityHeaderValues != "" {
+		diag.fatal("securityHeaderValues requires -securityHeaders")
+	}
+	var bodyLimit string
+	if *maxBodySizeFlag != "" {
+		var err error
+		if bodyLimit, err = parseMaxBodySize(*maxBodySizeFlag); err != nil {
+			diag.fatal("maxBodySize:", err)
+		}
+	}
+	if *enabledIfFunc != "" {
+		if expr, err := parser.ParseExpr(*enabledIfFunc); err != nil || !isQualifiedName(expr) {
+			diag.fatal("enabledIfFunc must be a function name such as featureEnabled or package.Enabled, got", *enabledIfFunc)
+		}
+	}
+	if *scopeMiddleware != "" {
+		if expr, err := parser.ParseExpr(*scopeMiddleware); err != nil || !isQualifiedName(expr) {
+			diag.fatal("scopeMiddleware must be a function name such as requireScopes or auth.RequireScopes, got", *scopeMiddleware)
+		}
+	}
+	if !slices.Contains(routeOrders, *routeOrder) {
+		diag.fatalf("unknown routeOrder %q, want one of %s", *routeOrder, strings.Join(routeOrders, ", "))
+	}
+	if !slices.Contains(targets, *target) {
+		diag.fatalf("unknown target %q, want one of %s", *target, strings.Join(targets, ", "))
+	}
+	if *target == "packages" {
+		switch {
+		case *backend == "echo":
+			diag.fatal("-target=packages is not supported by the echo backend")
+		case *out == stdoutPath:
+			diag.fatal("-out=- writes a single file, -target=packages writes files into the group directories")
+		case *emitRegistryOnly:
+			diag.fatal("-emitRegistryOnly generates no router for -target=packages to split")
+		case *profile:
+			diag.fatal("-target=packages cannot be combined with -profile, Register has no raw variant")
+		case *tagGroupsFlag != "":
+			diag.fatal("-target=packages cannot be combined with -tagGroups, which register groups from files next to -out")
+		case *versionDirs:
+			diag.fatal("-target=packages cannot be combined with -versionDirs, which register groups from version functions")
+		case *emitHandlerMap:
+			diag.fatal("-target=packages cannot be combined with -emitHandlerMap, which would import every handler package again")
+		}
+	}
+	if *pathPolicy != "" && !slices.Contains(pathPolicies, *pathPolicy) {
+		diag.fatalf("unknown pathPolicy %q, want one of %s", *pathPolicy, strings.Join(pathPolicies, ", "))
+	}
+	if !slices.Contains(pathCases, *pathCase) {
+		diag.fatalf("unknown pathCase %q, want one of %s", *pathCase, strings.Join(pathCases, ", "))
+	}
+	if !slices.Contains(clientParamCases, *clientParamCase) {
+		diag.fatalf("unknown clientParamCase %q, want one of %s", *clientParamCase, strings.Join(clientParamCases, ", "))
+	}
+	if !slices.Contains(handlerStyles, *handlerStyle) {
+		diag.fatalf("unknown handlerStyle %q, want one of %s", *handlerStyle, strings.Join(handlerStyles, ", "))
+	}
+	if *jsonErrorHandler != "" {
+		if *handlerStyle != "jsonapi" {
+			diag.fatal("jsonErrorHandler requires -handlerStyle=jsonapi")
+		}
+		if expr, err := parser.ParseExpr(*jsonErrorHandler); err != nil || !isQualifiedName(expr) {
+			diag.fatal("jsonErrorHandler must be a function name such as writeError or package.WriteError, got", *jsonErrorHandler)
+		}
+	} else {
+		*jsonErrorHandler = defaultJSONErrorHandler
+	}
+	if !slices.Contains(backends, *backend) {
+		diag.fatalf("unknown backend %q, want one of %s", *backend, strings.Join(backends, ", "))
+	}
+
+	if *notFoundStatus < 100 || *notFoundStatus > 599 {
+		diag.fatal("notFoundStatus must be a valid HTTP status code, got", *notFoundStatus)
+	}
+	notFoundHasPath, err := checkNotFoundBody(*notFoundBody)
+	if err != nil {
+		diag.fatal("Error parsing notFoundBody:", err)
 	}
 
-	var routes []route
-	routeGroups := make(map[string]bool)
+	*stripPrefix = strings.TrimSuffix(*stripPrefix, "/")
+	if *stripPrefix != "" && !strings.HasPrefix(*stripPrefix, "/") {
+		diag.fatal("stripPrefix must start with /, got", *stripPrefix)
+	}
 
-	filepath.WalkDir(*src, func(path string, d fs.DirEntry, err error) error {
-		if err != nil || d.IsDir() {
-			return err
+	if *proxyFallback != "" {
+		if *notFoundHandler != "" {
+			diag.fatal("proxyFallback and notFound cannot be used together")
 		}
-		if !strings.HasSuffix(d.Name(), ".go") {
-			return nil
+		if err := checkProxyURL(*proxyFallback); err != nil {
+			diag.fatal("Error parsing proxyFallback:", err)
 		}
+	}
 
-		fileName := strings.TrimSuffix(d.Name(), ".go")
-		method := strings.ToUpper(fileName)
+	var cors *corsConfig
+	if *corsFlag != "" {
+		if cors, err = parseCORS(*corsFlag); err != nil {
+			diag.fatal("Error parsing cors JSON:", err)
+		}
+	}
 
-		relDir, err := filepath.Rel(*src, filepath.Dir(path))
-		if err != nil {
-			return err
+	chains := make(map[string][]string)
+	if *chainsFlag != "" {
+		var raw map[string]string
+		if err := json.Unmarshal([]byte(*chainsFlag), &raw); err != nil {
+			diag.fatal("Error parsing chains JSON:", err)
+		}
+		for name, list := range raw {
+			chains[name] = splitList(list)
 		}
-		segments := strings.Split(relDir, string(os.PathSeparator))
+		// Expand every chain up front so that unused chains are checked too.
+		for _, name := range slices.Sorted(maps.Keys(chains)) {
+			if _, err := expandChains([]string{"@" + name}, chains, nil); err != nil {
+				diag.fatal("chains:", err)
+			}
+		}
+	}
 
-		var group string
-		if len(segments) > 0 && segments[0] != "" {
-			group = segments[0]
-			routeGroups[group] = true
+	groupMiddlewareMap := make(map[string][]string)
+	if *groupMiddlewares != "" {
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(*groupMiddlewares), &raw); err != nil {
+			diag.fatal("Error parsing groupMiddlewares JSON:", err)
 		}
-		if group == "" {
-			group = "root"
-			routeGroups[group] = true
+		for name, value := range raw {
+			list, err := middlewareValue(value)
+			if err != nil {
+				diag.fatalf("groupMiddlewares for %s: %v", name, err)
+			}
+			expanded, err := expandChains(list, chains, nil)
+			if err != nil {
+				diag.fatalf("groupMiddlewares for %s: %v", name, err)
+			}
+			groupMiddlewareMap[strings.Trim(name, "/")] = expanded
+		}
+	}
+
+	groupPathMap := make(map[string]string)
+	if *groupPathsFlag != "" {
+		var raw map[string]string
+		if err := json.Unmarshal([]byte(*groupPathsFlag), &raw); err != nil {
+			diag.fatal("Error parsing groupPaths JSON:", err)
+		}
+		for name, prefix := range raw {
+			groupPathMap[strings.Trim(name, "/")] = prefix
+		}
+	}
+
+	tagGroupMap := make(map[string]string)
+	if *tagGroupsFlag != "" {
+		if err := json.Unmarshal([]byte(*tagGroupsFlag), &tagGroupMap); err != nil {
+			diag.fatal("Error parsing tagGroups JSON:", err)
+		}
+	}
+
+	var server *serverConfig
+	if *emitServer {
+		if server, err = parseServerTimeouts(*serverTimeouts); err != nil {
+			diag.fatal("Error parsing serverTimeouts JSON:", err)
 		}
+	} else if *serverTimeouts != "" {
+		diag.fatal("serverTimeouts requires -emitServer")
+	}
 
-		var parts []string
-		for _, seg := range segments {
-			if seg == "" || seg == "index" {
-				continue
+	methodClassMap := make(map[string][]string)
+	if *methodMiddlewares != "" {
+		var raw map[string]string
+		if err := json.Unmarshal([]byte(*methodMiddlewares), &raw); err != nil {
+			diag.fatal("Error parsing methodMiddlewares JSON:", err)
+		}
+		for class, list := range raw {
+			if _, ok := methodClasses[class]; !ok {
+				diag.fatalf("methodMiddlewares: unknown method class %q, want read, write or *", class)
 			}
-			if strings.HasPrefix(seg, "[") && strings.HasSuffix(seg, "]") {
-				parts = append(parts, "{"+seg[1:len(seg)-1]+"}")
-			} else {
-				parts = append(parts, seg)
+			expanded, err := expandChains(splitList(list), chains, nil)
+			if err != nil {
+				diag.fatalf("methodMiddlewares for %s: %v", class, err)
 			}
+			methodClassMap[class] = expanded
+		}
+	}
+
+	if info, err := os.Stat(*src); err != nil || !info.IsDir() {
+		diag.at(*src).fatalf("api directory %s does not exist or is not a directory", *src)
+	}
+	if *concurrency < 1 {
+		diag.fatal("concurrency must be at least 1, got", *concurrency)
+	}
+	*defaultMethod = strings.ToUpper(*defaultMethod)
+	if *defaultMethod != "" && !slices.Contains(standardMethods, *defaultMethod) {
+		diag.fatalf("defaultMethod must be one of %s, got %q", strings.Join(standardMethods, ", "), *defaultMethod)
+	}
+	if *defaultMethod != "" && *multiMethodFiles {
+		diag.fatal("defaultMethod and multiMethodFiles both decide the methods of files not named after one, set only one")
+	}
+	if *maxDepth < 0 {
+		diag.fatal("maxDepth must not be negative, got", *maxDepth)
+	}
+	tree, err := scanAPI(os.DirFS(*src), *src, *importPre, scanOptions{
+		concurrency:   *concurrency,
+		maxDepth:      *maxDepth,
+		defaultMethod: *defaultMethod,
+		receivers:     *handlerReceiver,
+		strict:        *strict,
+		pathCase:      *pathCase,
+		multiMethod:   *multiMethodFiles,
+		groupPaths:    groupPathMap,
+	})
+	if err != nil {
+		diag.fatal("Error walking api directory:", err)
+	}
+	if *pathCase != "preserve" {
+		if err := checkPathCaseCollisions(tree.Routes, tree.Paths, *src, *pathCase); err != nil {
+			diag.fatal("pathCase:", err)
 		}
-		routePath := "/" + strings.Join(parts, "/")
-		if routePath == "/" {
-			routePath = "/"
+	}
+	if *pathPolicy != "" {
+		for _, v := range checkPathPolicy(tree.Routes, tree.Paths, *pathPolicy) {
+			display := filepath.Join(*src, filepath.FromSlash(v.Source))
+			diag.at(display).warnf("%s: %s", display, v.describe(*pathPolicy))
 		}
+	}
+	routes, redirects := tree.Routes, tree.Redirects
+	if *dedupe {
+		n, err := dedupeHandlers(routes, *src)
+		if err != nil {
+			diag.fatal("dedupeHandlers:", err)
+		}
+		if n > 0 {
+			diag.infof("Shared the handlers of %d symlinked routes", n)
+		}
+	}
+	if len(routes) == 0 {
+		diag.at(*src).warnf("no handler files found in %s, RegisterRoutes will return a router without routes", *src)
+	}
+	usesJSONHandler := slices.ContainsFunc(routes, func(rt route) bool { return rt.JSONAPI })
+	if *handlerStyle != "jsonapi" {
+		for _, rt := range routes {
+			if rt.JSONAPI {
+				dir := filepath.Join(*src, filepath.FromSlash(rt.Dir))
+				diag.at(dir).fatalf("%s handler %s in %s returns (any, error), which needs -handlerStyle=jsonapi", rt.methodLabel(), rt.Handler, dir)
+			}
+		}
+	}
 
-		importPath := strings.TrimSuffix(filepath.ToSlash(filepath.Join(*importPre, relDir)), "/")
-		alias := strings.ReplaceAll(relDir, string(os.PathSeparator), "_")
-		if alias == "" || alias == "." {
-			alias = filepath.Base(*src)
+	if *redirectsFlag != "" {
+		var raw map[string]string
+		if err := json.Unmarshal([]byte(*redirectsFlag), &raw); err != nil {
+			diag.fatal("Error parsing redirects JSON:", err)
 		}
+		for from, value := range raw {
+			rd, err := parseRedirect(from, value)
+			if err != nil {
+				diag.fatal("-redirects:", err)
+			}
+			rd.Source = "-redirects"
+			redirects = append(redirects, rd)
+		}
+	}
+	redirectSources := make(map[string]string)
+	for _, rd := range redirects {
+		if prev, ok := redirectSources[rd.From]; ok {
+			diag.at(rd.Source).fatalf("%s: redirect for %s already declared by %s", rd.Source, rd.From, prev)
+		}
+		redirectSources[rd.From] = rd.Source
+	}
+	sort.Slice(redirects, func(i, j int) bool { return redirects[i].From < redirects[j].From })
 
-		alias = strings.ReplaceAll(alias, "-", "_")
-		alias = strings.ReplaceAll(alias, ".", "_")
+	// A directory is a group when it is first-level, has group middleware
+	// configured or is named by a group directive. Each route belongs to its
+	// deepest enclosing group unless a directive places it elsewhere.
+	declaredGroups := make(map[string]bool)
+	for _, rt := range routes {
+		if rt.Group != "" {
+			declaredGroups[rt.Group] = true
+		}
+	}
+	isGroup := func(dir string) bool {
+		if dir == "" {
+			return false
+		}
+		_, configured := groupMiddlewareMap[dir]
+		return !strings.Contains(dir, "/") || configured || declaredGroups[dir]
+	}
+	enclosingGroup := func(dir string) string {
+		for dir != "" {
+			if isGroup(dir) {
+				return dir
+			}
+			dir = parentDir(dir)
+		}
+		return ""
+	}
 
-		handler := strings.Title(fileName)
+	groupSet := make(map[string]bool)
+	for i := range routes {
+		if routes[i].Schemes == nil {
+			routes[i].Schemes = defaultSchemes
+		}
+		physical := enclosingGroup(routes[i].Dir)
+		if !strings.HasPrefix(routes[i].RoutePath, routePath(physical, tree.Paths)) {
+			diag.fatalf("%s: //fsrouter:paramName cannot rename a parameter of the %s group prefix, which all its routes share", filepath.Join(*src, filepath.FromSlash(routes[i].Dir)), physical)
+		}
+		name := routes[i].Group
+		if name == "" {
+			name = physical
+		}
+		if name != physical {
+			// The route keeps its path below its physical group but is
+			// mounted under the declared group's prefix.
+			routes[i].RoutePath = joinPath(routePath(name, tree.Paths), relativePath(routes[i].RoutePath, physical, tree.Paths))
+		}
+		routes[i].Group = name
+		routes[i].Router = "r"
+		routes[i].SubPath = routes[i].RoutePath
+		if name != "" {
+			routes[i].Router = sanitizeIdent(name) + "Router"
+			routes[i].SubPath = relativePath(routes[i].RoutePath, name, tree.Paths)
+		}
+		for ; name != ""; name = enclosingGroup(parentDir(name)) {
+			groupSet[name] = true
+		}
+	}
 
-		routes = append(routes, route{
-			Method:     method,
-			RoutePath:  routePath,
-			ImportPath: importPath,
-			Alias:      alias,
-			Handler:    handler,
-			Group:      group,
+	var groupNames []string
+	for name := range groupSet {
+		groupNames = append(groupNames, name)
+	}
+	sort.Strings(groupNames)
+	if err := checkGroupPaths(groupNames, groupPathMap, tree.Paths); err != nil {
+		diag.fatal("groupPaths:", err)
+	}
+	if *dynamicLast {
+		slices.SortStableFunc(groupNames, func(a, b string) int {
+			return dynamicLastOrder(routePath(a, tree.Paths), routePath(b, tree.Paths))
 		})
-		return nil
-	})
+		slices.SortStableFunc(routes, func(a, b route) int { return dynamicLastOrder(a.RoutePath, b.RoutePath) })
+	}
+	if err := orderGroups(groupNames, splitList(*groupOrder)); err != nil {
+		diag.fatal("groupOrder:", err)
+	}
+	matchersFirst(routes)
+	fallbacksLast(routes)
+	orderRoutes(routes, *routeOrder)
 
-	f, err := os.Create(*out)
+	var routeGroups []group
+	for _, name := range groupNames {
+		g := group{
+			Name:        name,
+			Var:         sanitizeIdent(name) + "Router",
+			Parent:      "r",
+			Prefix:      routePath(name, tree.Paths),
+			Middlewares: groupMiddlewareMap[name],
+		}
+		if parent := enclosingGroup(parentDir(name)); parent != "" {
+			g.Parent = sanitizeIdent(parent) + "Router"
+			g.Prefix = relativePath(g.Prefix, parent, tree.Paths)
+		}
+		routeGroups = append(routeGroups, g)
+	}
+	if err := assignGates(routeGroups, tagGroupMap); err != nil {
+		diag.fatal("tagGroups:", err)
+	}
+	if *versionDirs {
+		n, err := assignVersions(routeGroups)
+		if err != nil {
+			diag.fatal("versionDirs:", err)
+		}
+		if n == 0 {
+			diag.at(*src).warnf("-versionDirs has no effect, %s has no version directory such as v1", *src)
+		}
+	}
+	methodNotAllowed, err := assignMethodNotAllowed(routeGroups, tree.MethodNotAllowed, *src)
 	if err != nil {
-		panic(err)
+		diag.fatal(err)
+	}
+	if *apiDocComment {
+		if err := groupDocs(os.DirFS(*src), routeGroups); err != nil {
+			diag.fatal("apiDocComment:", err)
+		}
+	}
+	var spas []spaGroup
+	if *spaFlag != "" {
+		if spas, err = parseSPA(*spaFlag, routeGroups, filepath.Dir(*out)); err != nil {
+			diag.fatal("spa:", err)
+		}
+	}
+	if *spaCache != "" {
+		if *spaFlag == "" {
+			diag.fatal("spaCache requires -spa")
+		}
+		if err := checkCacheControl(*spaCache); err != nil {
+			diag.fatal("spaCache:", err)
+		}
+	}
+
+	middlewareList, err := expandChains(splitList(*middlewares), chains, nil)
+	if err != nil {
+		diag.fatal("middlewares:", err)
+	}
+
+	// CORS applies globally, outside the other middleware, unless
+	// -middlewares or -groupMiddlewares place corsMiddleware explicitly.
+	// Each router running it also gets an OPTIONS route, since mux only
+	// runs middleware for requests that match a route.
+	var corsRouters []string
+	if cors != nil {
+		if slices.Contains(middlewareList, corsMiddlewareName) {
+			corsRouters = []string{"r"}
+		}
+		for _, g := range routeGroups {
+			if slices.Contains(g.Middlewares, corsMiddlewareName) {
+				corsRouters = append(corsRouters, g.Var)
+			}
+		}
+		if len(corsRouters) == 0 {
+			middlewareList = append([]string{corsMiddlewareName}, middlewareList...)
+			corsRouters = []string{"r"}
+		}
+	}
+	// Responses are compressed outside the handlers and middleware writing
+	// them, unless -middlewares places gzipCompress explicitly.
+	if gzipCall != "" && !slices.ContainsFunc(middlewareList, isGzipCall) {
+		middlewareList = append([]string{gzipCall}, middlewareList...)
+	}
+	// Bodies are limited before the handler and any middleware reads them,
+	// unless -middlewares places maxBodySize explicitly.
+	if bodyLimit != "" && !slices.ContainsFunc(middlewareList, isMaxBodySizeCall) {
+		middlewareList = append([]string{bodyLimit}, middlewareList...)
+	}
+	// The request ID is assigned first, so every other middleware can log
+	// it, unless -middlewares or -groupMiddlewares place it explicitly.
+	if *requestID {
+		placed := slices.Contains(middlewareList, requestIDMiddlewareName) || slices.ContainsFunc(routeGroups, func(g group) bool {
+			return slices.Contains(g.Middlewares, requestIDMiddlewareName)
+		})
+		if !placed {
+			middlewareList = append([]string{requestIDMiddlewareName}, middlewareList...)
+		}
+	}
+	// Panics are recovered outside everything else, so a panicking
+	// middleware is caught too.
+	if !*noRecover && !slices.Contains(middlewareList, recoverMiddlewareName) && !slices.ContainsFunc(routeGroups, func(g group) bool {
+		return slices.Contains(g.Middlewares, recoverMiddlewareName)
+	}) {
+		middlewareList = append([]string{recoverMiddlewareName}, middlewareList...)
+	}
+	// The deadline covers everything, recovery included, unless
+	// -middlewares places requestTimeout explicitly.
+	if reqTimeout != "" && !slices.ContainsFunc(middlewareList, isRequestTimeoutCall) {
+		middlewareList = append([]string{reqTimeout}, middlewareList...)
+	}
+	// Security headers are set outermost, so the responses of timeouts and
+	// recovered panics carry them too, unless -middlewares or
+	// -groupMiddlewares place securityHeadersMiddleware explicitly.
+	if securityHeaders != nil {
+		placed := slices.Contains(middlewareList, securityHeadersMiddlewareName) || slices.ContainsFunc(routeGroups, func(g group) bool {
+			return slices.Contains(g.Middlewares, securityHeadersMiddlewareName)
+		})
+		if !placed {
+			middlewareList = append([]string{securityHeadersMiddlewareName}, middlewareList...)
+		}
+	}
+	if *requireJSON {
+		for i := range routes {
+			routes[i].RequireJSON = hasBody(routes[i].Method)
+		}
+	}
+	variants, err := applySkips(routes, routeGroups, middlewareList, methodClassMap, tree.Paths, *src)
+	if err != nil {
+		diag.fatal("Error:", err)
+	}
+	for _, g := range variants {
+		if cors != nil && slices.Contains(g.Middlewares, corsMiddlewareName) {
+			corsRouters = append(corsRouters, g.Var)
+		}
+	}
+	routeGroups = append(routeGroups, variants...)
+	if *backend == "echo" {
+		// Echo runs middleware for requests no route matches, so
+		// preflight requests need no OPTIONS routes.
+		corsRouters = nil
+	}
+
+	devMiddlewareList := splitList(*devMiddlewares)
+
+	// Middleware entries are emitted verbatim, so only names and simple
+	// factory calls are accepted.
+	routerMiddlewares := slices.Clone(middlewareList)
+	for _, name := range slices.Sorted(maps.Keys(groupMiddlewareMap)) {
+		routerMiddlewares = append(routerMiddlewares, groupMiddlewareMap[name]...)
+	}
+	for _, class := range slices.Sorted(maps.Keys(methodClassMap)) {
+		routerMiddlewares = append(routerMiddlewares, methodClassMap[class]...)
+	}
+	if *finalMiddleware != "" {
+		routerMiddlewares = append(routerMiddlewares, *finalMiddleware)
+	}
+	for _, mw := range slices.Concat(routerMiddlewares, devMiddlewareList) {
+		if err := checkMiddlewareExpr(mw); err != nil {
+			diag.fatal("Error:", err)
+		}
+	}
+
+	usesMaxBodySize := slices.ContainsFunc(slices.Concat(routerMiddlewares, devMiddlewareList), isMaxBodySizeCall)
+	usesGzip := slices.ContainsFunc(slices.Concat(routerMiddlewares, devMiddlewareList), isGzipCall)
+	usesRequestTimeout := slices.ContainsFunc(slices.Concat(routerMiddlewares, devMiddlewareList), isRequestTimeoutCall)
+
+	var schemas []bodySchema
+	if *validateBodies {
+		schemas, err = compileSchemas(routes, tree.Schemas, *src)
+		if err != nil {
+			diag.fatal("Error compiling schemas:", err)
+		}
+	}
+
+	tagged, usesProduces, usesCache, usesDeprecated, usesRequireJSON := false, false, false, false, false
+	usesCatchAllSlash := false
+	for i := range routes {
+		routes[i].Middlewares = slices.DeleteFunc(methodMiddlewareList(routes[i].Method, methodClassMap), func(mw string) bool {
+			return slices.Contains(routes[i].Skip, mw)
+		})
+		// The registry lists handlers as they are and leaves registration
+		// to the caller.
+		if !*emitRegistryOnly {
+			if len(routes[i].Scopes) > 0 && *scopeMiddleware == "" {
+				diag.fatalf("%s: //fsrouter:scopes requires -scopeMiddleware", filepath.Join(*src, filepath.FromSlash(routes[i].Dir)))
+			}
+			routes[i].HandlerExpr = handlerExpr(routes[i], *finalMiddleware, *scopeMiddleware, *otelFlag)
+			routes[i].Guard = routeGuard(routes[i], *enabledIfFunc)
+		}
+		tagged = tagged || len(routes[i].Tags) > 0
+		usesProduces = usesProduces || routes[i].Produces != ""
+		usesCache = usesCache || routes[i].CacheControl != ""
+		usesDeprecated = usesDeprecated || routes[i].Deprecated
+		usesRequireJSON = usesRequireJSON || routes[i].RequireJSON
+		usesCatchAllSlash = usesCatchAllSlash || routes[i].CatchAllSlash != ""
+	}
+	if *routeProviders && tagged {
+		diag.fatal("routeProviders cannot be combined with //fsrouter:tag, both make RegisterRoutes variadic")
+	}
+	if *routerOptions && (tagged || *routeProviders) {
+		diag.fatal("routerOptions cannot be combined with //fsrouter:tag or -routeProviders, which make RegisterRoutes variadic too")
+	}
+
+	var spec string
+	if *serveSpecPath != "" {
+		if !strings.HasPrefix(*serveSpecPath, "/") {
+			diag.fatal("serveSpec must start with /, got", *serveSpecPath)
+		}
+		for _, rt := range routes {
+			if rt.RoutePath == *serveSpecPath && rt.Method == "GET" {
+				diag.fatalf("serveSpec %s conflicts with the GET handler in %s", *serveSpecPath, filepath.Join(*src, filepath.FromSlash(rt.Dir)))
+			}
+		}
+		if spec, err = buildSpec(routes, routeGroups, tree.Schemas, *importPre, *stripPrefix, *src); err != nil {
+			diag.fatal("Error building OpenAPI spec:", err)
+		}
+	}
+
+	if *backend == "echo" {
+		unsupported := map[string]bool{
+			"stripPrefix":     *stripPrefix != "",
+			"encodedPath":     *encodedPath,
+			"caseInsensitive": *caseInsensitive,
+			"tagGroups":       len(tagGroupMap) > 0,
+			"devMiddlewares":  *devMiddlewares != "",
+			"autoOptions":     *autoOptionsFlag,
+			"spa":             len(spas) > 0,
+			"versionDirs":     *versionDirs,
+		}
+		if err := checkEchoBackend(unsupported, routes, redirects); err != nil {
+			diag.fatal(err)
+		}
+		if len(tree.MethodNotAllowed) > 0 {
+			diag.fatalf("%s files are not supported by the echo backend", methodNotAllowedFile)
+		}
+	}
+
+	if *caseInsensitive {
+		if err := checkLowercasePaths(routes, redirects, *stripPrefix, *serveSpecPath, *src); err != nil {
+			diag.fatal("caseInsensitive:", err)
+		}
+	}
+
+	var gateway *gatewayConfig
+	if *grpcGateway != "" {
+		if gateway, err = parseGateway(*grpcGateway, *grpcPrefix); err != nil {
+			diag.fatal(err)
+		}
+		if err := checkGatewayPrefix(gateway, routes, redirects, *serveSpecPath); err != nil {
+			diag.fatal(err)
+		}
+		if *caseInsensitive && hasUpperLiteral(gateway.Prefix) {
+			diag.fatal("caseInsensitive: grpcPrefix has uppercase letters,", gateway.Prefix)
+		}
+	} else if *grpcPrefix != "" {
+		diag.fatal("grpcPrefix requires -grpcGateway")
+	}
+
+	var mounts []mount
+	if *mountFlag != "" {
+		if mounts, err = parseMounts(*mountFlag); err != nil {
+			diag.fatal("Error parsing mount JSON:", err)
+		}
+		for _, m := range mounts {
+			if err := checkShadowed(m.Prefix, fmt.Sprintf("-mount prefix %s, which %s serves", m.Prefix, m.Func), routes, redirects, *serveSpecPath); err != nil {
+				diag.fatal(err)
+			}
+			if gateway != nil && (strings.HasPrefix(m.Prefix+"/", gateway.Prefix+"/") || strings.HasPrefix(gateway.Prefix+"/", m.Prefix+"/")) {
+				diag.fatalf("mount prefix %s overlaps -grpcPrefix=%s", m.Prefix, gateway.Prefix)
+			}
+			if *caseInsensitive && hasUpperLiteral(m.Prefix) {
+				diag.fatal("caseInsensitive: mount prefix has uppercase letters,", m.Prefix)
+			}
+		}
+	}
+
+	if *printRouteTree {
+		printTree(os.Stdout, routes, routeGroups, redirects, middlewareList, *finalMiddleware)
+		return
+	}
+	if name == "explain" {
+		err := explainRoutes(os.Stdout, explainQuery, routes, routeGroups, middlewareList, explainOptions{
+			Root:            *src,
+			DevMiddlewares:  devMiddlewareList,
+			RouterOptions:   *routerOptions,
+			FinalMiddleware: *finalMiddleware,
+			ScopeMiddleware: *scopeMiddleware,
+			EnabledIfFunc:   *enabledIfFunc,
+			Otel:            *otelFlag,
+		})
+		if err != nil {
+			diag.fatal("explain:", err)
+		}
+		return
+	}
+
+	if *encodedPath {
+		for i := range routes {
+			routes[i].SubPath = escapeNonASCII(routes[i].SubPath)
+		}
+		for i := range routeGroups {
+			routeGroups[i].Prefix = escapeNonASCII(routeGroups[i].Prefix)
+		}
+		for i := range redirects {
+			redirects[i].From = escapeNonASCII(redirects[i].From)
+		}
+	}
+
+	// Redirect targets are paths as clients see them, so under a stripped
+	// mount prefix a path target points back below that prefix. URLs and
+	// scheme-relative targets are left alone.
+	if *stripPrefix != "" {
+		for i := range redirects {
+			if to := redirects[i].To; strings.HasPrefix(to, "/") && !strings.HasPrefix(to, "//") {
+				redirects[i].To = *stripPrefix + to
+			}
+		}
 	}
-	defer f.Close()
 
 	// Define the router template with proper escaping for template directives within backticks
 	routerTemplate := `// Code generated by fsrouter; DO NOT EDIT.
 package {{.Package}}
 
 import (
-	"fmt"
-	"net/http"
-{{range .Imports}}	{{.Alias}} "{{.Path}}"
+{{range .StdImports}}	"{{.}}"
+{{end}}{{range .Imports}}{{if .Comment}}
+	// {{.Comment}}
+{{end}}	{{.Alias}} "{{.Path}}"
 {{end}}
 	"github.com/gorilla/mux"
 )
-
-// RegisterRoutes creates and returns a router with all API routes registered
-func RegisterRoutes() *mux.Router {
-	r := mux.NewRouter()
-	
+{{if .HandlerMap}}{{template "handlerMap" .}}{{end}}
+{{range $reg := .Registrations}}
+{{if $reg.Raw}}// RegisterRoutesRaw creates and returns a router with the same routes as
+// RegisterRoutes but without any middleware, for benchmarking routing overhead
+func RegisterRoutesRaw({{if $.Tagged}}tags ...string{{else if $.RouteProviders}}providers ...RouteProvider{{else if $.RouterOptions}}opts ...Option{{end}}) *mux.Router {{"{"}}{{else}}// RegisterRoutes creates and returns a router with all API routes registered{{if $.Tagged}}.
+// Tagged routes are only registered when one of their tags is requested.{{end}}{{if $.RouteProviders}},
+// followed by the routes of the providers{{end}}{{if $.RouterOptions}}.
+// The Options replace its 404 handler or add global middleware{{end}}
+func RegisterRoutes({{if $.Tagged}}tags ...string{{else if $.RouteProviders}}providers ...RouteProvider{{else if $.RouterOptions}}opts ...Option{{end}}) *mux.Router {{"{"}}{{end}}
+{{if $.RouterOptions}}	o := applyOptions(opts)
+{{end}}	r := mux.NewRouter()
+{{if $.EncodedPath}}	r.UseEncodedPath()
+{{end}}{{if $.Tagged}}
+	enabled := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		enabled[tag] = true
+	}
+{{end}}
 	// Default 404 handler
-	r.NotFoundHandler = http.HandlerFunc({{if .NotFound}}{{.NotFound}}{{else}}defaultNotFoundHandler{{end}})
-	
+	r.NotFoundHandler = {{if $.ProxyFallback}}proxyFallback(){{else}}http.HandlerFunc({{if $.NotFound}}{{$.NotFound}}{{else}}defaultNotFoundHandler{{end}}){{end}}
+{{if $.RouterOptions}}	if o.notFound != nil {
+		r.NotFoundHandler = o.notFound
+	}
+{{end}}{{if $.MethodNotAllowed}}	r.MethodNotAllowedHandler = http.HandlerFunc({{$.MethodNotAllowed}})
+{{end}}{{if not $reg.Raw}}
 	// Global middleware (applied to all routes)
-{{range .Middlewares}}	r.Use({{.}})
+{{range $.Middlewares}}	r.Use({{.}})
+{{end}}{{if $.RouterOptions}}	for _, mw := range o.middlewares {
+		r.Use(mw)
+	}
 {{end}}// Add more global middleware here
 	// r.Use(authMiddleware)
 	// r.Use(corsMiddleware)
-	
-{{range $group, $_ := .Groups}}
-	// Route group for {{$group}}
-	{{$group}}Router := r.PathPrefix("/{{$group}}").Subrouter()
-	// Group-specific middleware
-{{if index $.GroupMiddlewares $group}}{{range index $.GroupMiddlewares $group}}	{{$group}}Router.Use({{.}})
+{{if $.DevMiddlewares}}
+	// Development-only middleware, installed by the dev-tagged file
+	if devMiddlewareHook != nil {
+		devMiddlewareHook(r)
+	}
+{{end}}{{end}}
+{{if $.Gateway}}	// gRPC-Gateway, mounted before the groups so no {param} prefix shadows it
+	r.PathPrefix("{{$.Gateway.Prefix}}/").Handler(http.StripPrefix("{{$.Gateway.Prefix}}", {{$.Gateway.Func}}()))
+{{end}}{{if $.Mounts}}	// Mounted handlers, before the groups so no {param} prefix shadows them
+{{range $.Mounts}}	r.PathPrefix("{{.Prefix}}/").Handler({{.Func}}())
+{{end}}{{end}}{{if $.Receivers}}	// Handler structs, built once for all their routes
+{{range $.Receivers}}	{{.Var}} := {{.Alias}}.New()
+{{end}}{{end}}{{range $.Groups}}{{if .Version}}
+	// Version directory {{.Name}}
+	add{{.Version}}Routes(r, {{if $.Tagged}}enabled{{else}}nil{{end}}, {{$reg.Raw}})
+{{else}}
+	// Route group for {{.Name}}{{if .Doc}}
+	// {{.Doc}}{{end}}
+	{{.Var}} := {{.Parent}}.PathPrefix("{{.Prefix}}").Subrouter()
+{{if .MethodNotAllowed}}	{{.Var}}.MethodNotAllowedHandler = http.HandlerFunc({{.MethodNotAllowed}})
+{{end}}{{if not $reg.Raw}}	// Group-specific middleware
+{{if .Middlewares}}{{$router := .Var}}{{range .Middlewares}}	{{$router}}.Use({{.}})
 {{end}}{{else}}	// Add group-specific middleware here if needed
-	// {{$group}}Router.Use(someMiddleware){{end}}
-{{end}}
-
-{{range .Routes}}	{{if .Group}}{{.Group}}Router{{else}}r{{end}}.HandleFunc("{{.RoutePath}}", {{.Alias}}.{{.Handler}}).Methods("{{.Method}}")
-{{end}}
+	// {{.Var}}.Use(someMiddleware)
+{{end}}{{end}}{{if .Register}}	{{.Register}}.Register({{.Var}})
+{{end}}{{end}}{{end}}
 
+{{if $.Redirects}}
+	// Redirects
+{{range $.Redirects}}	r.HandleFunc({{printf "%q" .From}}, http.RedirectHandler({{printf "%q" .To}}, {{.Status}}).ServeHTTP)
+{{end}}{{end}}
+{{range $.Routes}}{{if .Guard}}	if {{.Guard}} {
+	{{end}}	{{.Router}}.{{if and .HandlerExpr (not $reg.Raw)}}Handle("{{.SubPath}}", {{.HandlerExpr}}){{else}}HandleFunc("{{.SubPath}}", {{.HandlerFunc}}){{end}}{{if .Method}}.Methods("{{.Method}}"){{end}}{{.Matchers}}
+{{if .Guard}}	}
+{{end}}{{end}}{{if $.TagGroups}}
+	// Route groups compiled in by build tags
+	for _, register := range buildTagRoutes {
+		register(r, {{if $.Tagged}}enabled{{else}}nil{{end}}, {{$reg.Raw}})
+	}
+{{end}}{{if $.RouteProviders}}
+	// Routes contributed by plugins
+	for _, p := range providers {
+		p.Routes(r)
+	}
+{{end}}{{if $.SpecPath}}
+	// OpenAPI description of the routes
+	r.HandleFunc({{printf "%q" $.SpecPath}}, serveSpec).Methods("GET")
+{{end}}{{if $.CORSRouters}}
+	// Preflight requests for paths without an OPTIONS handler
+{{range $.CORSRouters}}	{{.}}.Methods(http.MethodOptions).Headers("Access-Control-Request-Method", "").HandlerFunc(corsPreflight)
+{{end}}{{end}}{{if $.SPAs}}
+	// Single-page apps, answering GET requests no route of their group matches
+{{range $.SPAs}}	{{.Router}}.PathPrefix("/").Methods("GET", "HEAD").Handler(spaHandler({{.Var}}, {{printf "%q" .Dir}}, {{printf "%q" .Prefix}}))
+{{end}}{{end}}
+{{if $.StripPrefix}}
+	// Strip the mount prefix before routing; the bare prefix redirects to
+	// the prefix with a trailing slash so it reaches the "/" route
+	root := mux.NewRouter()
+{{if $.EncodedPath}}	root.UseEncodedPath()
+{{end}}	root.NotFoundHandler = r.NotFoundHandler
+	root.Handle({{printf "%q" $.StripPrefix}}, http.RedirectHandler({{printf "%q" (print $.StripPrefix "/")}}, http.StatusMovedPermanently))
+	root.PathPrefix({{printf "%q" (print $.StripPrefix "/")}}).Handler(http.StripPrefix({{printf "%q" $.StripPrefix}}, r))
+{{if $.CaseInsensitive}}
+	// Lowercase request paths before routing
+	return caseInsensitive(root, r.NotFoundHandler)
+{{else}}	return root
+{{end}}{{else if $.CaseInsensitive}}
+	// Lowercase request paths before routing
+	return caseInsensitive(r, r.NotFoundHandler)
+{{else}}
 	return r
-}
-
-// Default middleware for logging requests
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Printf("%s %s\n", r.Method, r.URL.Path)
-		next.ServeHTTP(w, r)
-	})
-}
-
-{{if not .NotFound}}
-// Default 404 handler
-func defaultNotFoundHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNotFound)
-	w.Write([]byte(\`{"error": "404 not found", "path": "\` + r.URL.Path + \`"}\`))
-}
+{{end}}}
 {{end}}
-`
-	
-	tmpl := template.Must(template.New("router").Parse(routerTemplate))
+{{if .RouteProviders}}
+// RouteProvider contributes routes to the router built by RegisterRoutes,
+// for plugins. Routes is called after the API routes are registered, on
+// the router running the global middleware
+type RouteProvider interface {
+	Routes(r *mux.Router)
+}
+{{end}}{{if .TagGroups}}
+// buildTagRoutes is filled by the files generated for -tagGroups; each
+// registers the route groups compiled in with its build tag
+var buildTagRoutes []func(r *mux.Router, enabled map[string]bool, raw bool)
+{{end}}{{if .DevMiddlewares}}
+// devMiddlewareHook is set by the file generated for the dev build tag to
+// add development-only middleware.
+var devMiddlewareHook func(r *mux.Router)
+{{end}}{{if .Versions}}{{template "versions" .}}{{end}}
+{{template "helpers" .}}`
 
-	type importEntry struct {
-		Path  string
-		Alias string
+	if *backend == "echo" {
+		routerTemplate = echoTemplate
+	}
+	if *emitRegistryOnly {
+		routerTemplate = registryTemplate
 	}
-	imports := []importEntry{}
-	importMap := map[string]bool{}
+	tmpl := template.Must(template.New("router").Funcs(echoFuncs).Funcs(template.FuncMap{"handlerKey": handlerKey, "registryEntry": registryEntry}).Parse(routerTemplate))
+	template.Must(tmpl.New("helpers").Parse(helpersTemplate))
+	template.Must(tmpl.New("validateBody").Parse(validateBodyTemplate))
+	template.Must(tmpl.New("cors").Parse(corsTemplate))
+	template.Must(tmpl.New("server").Parse(serverTemplate))
+	template.Must(tmpl.New("spec").Parse(specTemplate))
+	template.Must(tmpl.New("recover").Parse(recoverTemplate))
+	template.Must(tmpl.New("paramParsers").Parse(paramParserTemplate))
+	template.Must(tmpl.New("requestID").Parse(requestIDTemplate))
+	template.Must(tmpl.New("securityHeaders").Parse(securityHeadersTemplate))
+	template.Must(tmpl.New("caseInsensitive").Parse(caseInsensitiveTemplate))
+	template.Must(tmpl.New("jsonHandler").Parse(jsonHandlerTemplate))
+	template.Must(tmpl.New("handlerMap").Parse(handlerMapTemplate))
+	template.Must(tmpl.New("options").Parse(optionsTemplate))
+	template.Must(tmpl.New("spa").Parse(spaTemplate))
+	template.Must(tmpl.New("requestLog").Parse(requestLogTemplate))
+	template.Must(tmpl.New("maxBodySize").Parse(maxBodySizeTemplate))
+	template.Must(tmpl.New("requestTimeout").Parse(requestTimeoutTemplate))
+	template.Must(tmpl.New("gzip").Parse(gzipTemplate))
+	template.Must(tmpl.New("otel").Parse(otelTemplate))
+	template.Must(tmpl.New("requireJSON").Parse(requireJSONTemplate))
+	template.Must(tmpl.New("routerOptions").Parse(routerOptionsTemplate))
+	template.Must(tmpl.New("groupRoutes").Parse(groupRoutesTemplate))
+	template.Must(tmpl.New("catchAllSlash").Parse(catchAllSlashTemplate))
+	template.Must(tmpl.New("versions").Parse(versionsTemplate))
 
-	for _, r := range routes {
-		if !importMap[r.ImportPath] {
-			imports = append(imports, importEntry{Path: r.ImportPath, Alias: r.Alias})
-			importMap[r.ImportPath] = true
+	// Groups gated by -tagGroups are registered by their own files, so the
+	// main file only imports what the rest refers to.
+	registered := routes
+	var optionsPaths []optionsPath
+	if *autoOptionsFlag {
+		var corsMethods []string
+		if cors != nil {
+			corsMethods = strings.Split(cors.Methods, ", ")
 		}
+		registered, optionsPaths = autoOptions(routes, corsMethods, *encodedPath)
 	}
-
-	if *middlewarePkg != "" {
-		imports = append(imports, importEntry{Path: *middlewarePkg, Alias: "middleware"})
+	mainRoutes, mainGroups, mainCORS, tagFiles := splitGated(*out, registered, routeGroups, corsRouters)
+	notAllowed := tree.MethodNotAllowed
+	if *emitRegistryOnly {
+		notAllowed = nil
+	}
+	var registerFiles []packageRegistration
+	if *target == "packages" {
+		if mainRoutes, mainGroups, registerFiles, err = splitPackages(mainRoutes, mainGroups, groupNames, notAllowed, *src, *importPre); err != nil {
+			diag.fatal("target:", err)
+		}
+		for _, s := range spas {
+			if s.Router != "r" && !slices.ContainsFunc(mainGroups, func(g group) bool { return g.Var == s.Router }) {
+				diag.fatalf("target: the single-page app under %s is in a nested group, which is registered by its top-level group's %s", s.Prefix, registerFileName)
+			}
+		}
+		// Only the methodNotAllowed.go handlers of the groups left in the
+		// main file are imported by it.
+		notAllowed = slices.DeleteFunc(slices.Clone(notAllowed), func(h route) bool {
+			return h.Dir != "" && !slices.ContainsFunc(mainGroups, func(g group) bool { return g.MethodNotAllowed == h.Alias+"."+h.Handler })
+		})
+	}
+	imports := handlerImports(slices.Concat(mainRoutes, notAllowed), *groupImports)
+	for _, reg := range registerFiles {
+		if !slices.ContainsFunc(imports, func(imp importEntry) bool { return imp.Path == reg.ImportPath }) {
+			imports = append(imports, packageImport(reg.ImportPath, reg.Alias, *groupImports))
+		}
 	}
 
 	var notFound string
@@ -193,41 +1260,881 @@ func defaultNotFoundHandler(w http.ResponseWriter, r *http.Request) {
 		notFound = *notFoundHandler
 	}
 
-	middlewareList := strings.Split(*middlewares, ",")
-	for i, m := range middlewareList {
-		middlewareList[i] = strings.TrimSpace(m)
+	emitted := append([]string{notFound}, middlewareList...)
+	if usesJSONHandler {
+		emitted = append(emitted, *jsonErrorHandler)
+	}
+	for _, g := range mainGroups {
+		emitted = append(emitted, g.Middlewares...)
+	}
+	for _, rt := range mainRoutes {
+		emitted = append(emitted, rt.HandlerExpr, rt.Guard)
 	}
 
-	var groupMiddlewareMap map[string][]string
-	if *groupMiddlewares != "" {
-		groupMiddlewareMap = make(map[string][]string)
-		if err := json.Unmarshal([]byte(*groupMiddlewares), &groupMiddlewareMap); err != nil {
-			fmt.Fprintln(os.Stderr, "Error parsing groupMiddlewares JSON:", err)
-			os.Exit(1)
+	// Only import the middleware package when something refers to it,
+	// otherwise the generated file fails to compile with an unused import.
+	if *middlewarePkg != "" {
+		gatedUse := slices.ContainsFunc(tagFiles, func(f tagGroupFile) bool {
+			var exprs []string
+			for _, g := range f.Groups {
+				exprs = append(exprs, g.Middlewares...)
+			}
+			for _, rt := range f.Routes {
+				exprs = append(exprs, rt.HandlerExpr, rt.Guard)
+			}
+			return usesPackage("middleware", exprs)
+		}) || slices.ContainsFunc(registerFiles, func(reg packageRegistration) bool {
+			var exprs []string
+			for _, g := range reg.Groups {
+				exprs = append(exprs, g.Middlewares...)
+			}
+			for _, rt := range reg.Routes {
+				exprs = append(exprs, rt.HandlerExpr, rt.Guard)
+			}
+			return usesPackage("middleware", exprs)
+		})
+		if usesPackage("middleware", emitted) {
+			imports = append(imports, packageImport(*middlewarePkg, "middleware", *groupImports))
+		} else if !usesPackage("middleware", devMiddlewareList) && !gatedUse {
+			diag.warnf("-middleware=%s has no effect, no middleware refers to middleware.*", *middlewarePkg)
+		}
+	}
+	if gateway != nil {
+		imports = append(imports, packageImport(gateway.Path, gatewayAlias, *groupImports))
+	}
+	for _, m := range mounts {
+		if m.Path == "" {
+			continue
+		}
+		if i := slices.IndexFunc(imports, func(imp importEntry) bool { return imp.Alias == m.Alias }); i >= 0 {
+			if imports[i].Path != m.Path {
+				diag.fatalf("mount package %s would be imported as %s, which %s is already imported as", m.Path, m.Alias, imports[i].Path)
+			}
+			continue
+		}
+		imports = append(imports, packageImport(m.Path, m.Alias, *groupImports))
+	}
+	if *otelFlag {
+		for _, imp := range otelImports {
+			if i := slices.IndexFunc(imports, func(other importEntry) bool { return other.Alias == imp.Alias }); i >= 0 {
+				diag.fatalf("otel imports %s as %s, which %s is already imported as", imp.Path, imp.Alias, imports[i].Path)
+			}
+			imports = append(imports, packageImport(imp.Path, imp.Alias, *groupImports))
 		}
 	}
 
-	err = tmpl.Execute(f, struct {
-		Package          string
-		Imports          []importEntry
-		Routes           []route
-		NotFound         string
-		Groups           map[string]bool
+	stdImports := []string{"fmt", "net/http"}
+	if *proxyFallback != "" {
+		stdImports = append(stdImports, "net/http/httputil", "net/url")
+	} else if notFound == "" && notFoundHasPath && *notFoundBody != defaultNotFoundBody {
+		stdImports = append(stdImports, "encoding/json")
+	}
+	if usesPackage("time", emitted) {
+		stdImports = append(stdImports, "time")
+	}
+	if usesPackage("os", emitted) {
+		stdImports = append(stdImports, "os")
+	}
+	if len(schemas) > 0 {
+		stdImports = append(stdImports, validateBodyImports...)
+	}
+	if server != nil {
+		stdImports = append(stdImports, serverImports...)
+	}
+	if !*noRecover {
+		stdImports = append(stdImports, recoverImports...)
+	}
+	if *requestID {
+		stdImports = append(stdImports, requestIDImports...)
+	}
+	parsers := paramParsers(routes, *backend)
+	if len(parsers) > 0 {
+		stdImports = append(stdImports, paramParserImports...)
+	}
+	if *caseInsensitive {
+		stdImports = append(stdImports, caseInsensitiveImports...)
+	}
+	if usesJSONHandler {
+		stdImports = append(stdImports, jsonHandlerImports...)
+	}
+	if len(spas) > 0 {
+		stdImports = append(stdImports, spaImports...)
+	}
+	if usesRequestTimeout {
+		stdImports = append(stdImports, requestTimeoutImports...)
+	}
+	if usesGzip {
+		stdImports = append(stdImports, gzipImports...)
+	}
+	if usesRequireJSON {
+		stdImports = append(stdImports, requireJSONImports...)
+	}
+	if reqLog != nil && reqLog.Duration {
+		stdImports = append(stdImports, "time")
+	}
+	if *emitRegistryOnly {
+		stdImports = []string{"net/http"}
+		if usesJSONHandler {
+			stdImports = append(stdImports, "fmt")
+			stdImports = append(stdImports, jsonHandlerImports...)
+		}
+	}
+	slices.Sort(stdImports)
+	stdImports = slices.Compact(stdImports)
+
+	// Version directories stay in the main file but are registered by
+	// functions of their own, declared after RegisterRoutes.
+	mainRoutes, mainGroups, mainCORS, versions := splitVersions(mainRoutes, mainGroups, mainCORS, *profile)
+
+	type registration struct {
+		Raw bool
+	}
+	registrations := []registration{{Raw: false}}
+	if *profile {
+		registrations = append(registrations, registration{Raw: true})
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, struct {
+		Package         string
+		StdImports      []string
+		Imports         []importEntry
+		Routes          []route
+		NotFound        string
+		NotFoundStatus  int
+		NotFoundBody    string
+		NotFoundHasPath bool
+		// NotFoundDefault keeps the default 404 body as it always was,
+		// with the request path written unescaped.
+		NotFoundDefault  bool
+		ProxyFallback    string
+		Groups           []group
+		Redirects        []redirect
+		Schemas          []bodySchema
+		Produces         bool
+		CacheControl     bool
+		StripPrefix      string
+		EncodedPath      bool
+		CORS             *corsConfig
+		Recover          bool
+		ParamParsers     []paramParser
+		RequestID        bool
+		SecurityHeaders  []securityHeader
+		CaseInsensitive  bool
+		MaxBodySize      bool
+		RequestTimeout   bool
+		Gzip             bool
+		Otel             bool
+		RequireJSON      bool
+		CatchAllSlash    bool
+		MethodNotAllowed string
+		RouteProviders   bool
+		RouterOptions    bool
+		RequestLog       *requestLog
+		Deprecated       bool
+		SPAs             []spaGroup
+		SPACache         string
+		Receivers        []receiverVar
+		OptionsPaths     []optionsPath
+		Gateway          *gatewayConfig
+		Mounts           []mount
+		JSONHandler      bool
+		JSONErrorHandler string
+		Server           *serverConfig
+		SpecPath         string
+		Spec             string
+		CORSRouters      []string
+		Versions         []versionRouter
+		TagGroups        bool
+		Tagged           bool
 		Middlewares      []string
-		GroupMiddlewares map[string][]string
+		DevMiddlewares   []string
+		Registrations    []registration
+		HandlerMap       bool
 	}{
 		Package:          *pkg,
+		StdImports:       stdImports,
 		Imports:          imports,
-		Routes:           routes,
+		Routes:           mainRoutes,
 		NotFound:         notFound,
-		Groups:           routeGroups,
+		NotFoundStatus:   *notFoundStatus,
+		NotFoundBody:     *notFoundBody,
+		NotFoundHasPath:  notFoundHasPath,
+		NotFoundDefault:  *notFoundBody == defaultNotFoundBody,
+		ProxyFallback:    *proxyFallback,
+		Groups:           mainGroups,
+		Redirects:        redirects,
+		Schemas:          schemas,
+		Produces:         usesProduces,
+		CacheControl:     usesCache,
+		StripPrefix:      *stripPrefix,
+		EncodedPath:      *encodedPath,
+		CORS:             cors,
+		Recover:          !*noRecover,
+		ParamParsers:     parsers,
+		RequestID:        *requestID,
+		SecurityHeaders:  securityHeaders,
+		CaseInsensitive:  *caseInsensitive,
+		MaxBodySize:      usesMaxBodySize,
+		RequestTimeout:   usesRequestTimeout,
+		Gzip:             usesGzip,
+		Otel:             *otelFlag,
+		RequireJSON:      usesRequireJSON,
+		CatchAllSlash:    usesCatchAllSlash,
+		MethodNotAllowed: methodNotAllowed,
+		RouteProviders:   *routeProviders,
+		RouterOptions:    *routerOptions,
+		RequestLog:       reqLog,
+		Deprecated:       usesDeprecated,
+		SPAs:             spas,
+		SPACache:         *spaCache,
+		Receivers:        receiverVars(mainRoutes),
+		OptionsPaths:     optionsPaths,
+		Gateway:          gateway,
+		Mounts:           mounts,
+		JSONHandler:      usesJSONHandler,
+		JSONErrorHandler: *jsonErrorHandler,
+		Server:           server,
+		SpecPath:         *serveSpecPath,
+		Spec:             spec,
+		CORSRouters:      mainCORS,
+		Versions:         versions,
+		TagGroups:        len(tagFiles) > 0,
+		Tagged:           tagged,
 		Middlewares:      middlewareList,
-		GroupMiddlewares: groupMiddlewareMap,
+		DevMiddlewares:   devMiddlewareList,
+		Registrations:    registrations,
+		HandlerMap:       *emitHandlerMap,
 	})
 
 	if err != nil {
 		panic(err)
 	}
 
-	fmt.Printf("Generated %s with %d routes in %d groups\n", *out, len(routes), len(routeGroups))
+	w := &outputWriter{check: check || *verify, nolint: *nolint, post: post}
+	if *verify {
+		w.diff = os.Stdout
+	}
+	if err := w.write(*out, buf.Bytes()); err != nil {
+		diag.fatal("Error writing output:", err)
+	}
+	if *out == stdoutPath {
+		w.report(*out, "Generated %d routes in %d groups to stdout", len(routes), len(routeGroups))
+	} else {
+		w.report(*out, "Generated %s with %d routes in %d groups", *out, len(routes), len(routeGroups))
+	}
+
+	// -out=- rules out the dev and tag group files, and no stale ones lie
+	// next to stdout.
+	if *out != stdoutPath {
+		devOut := devOutPath(*out)
+		if len(devMiddlewareList) > 0 {
+			if err := writeDevFile(w, devOut, *pkg, *middlewarePkg, devMiddlewareList); err != nil {
+				diag.fatal("Error generating dev file:", err)
+			}
+			w.report(devOut, "Generated %s with %d dev middlewares", devOut, len(devMiddlewareList))
+		} else if err := removeStaleDevFile(w, devOut); err != nil {
+			diag.fatal("Error removing stale dev file:", err)
+		}
+
+		for _, f := range tagFiles {
+			if err := writeTagGroupFile(w, f, *pkg, *middlewarePkg, *profile, *groupImports, *emitHandlerMap); err != nil {
+				diag.fatal("Error generating tag group file:", err)
+			}
+			w.report(f.Path, "Generated %s with %d routes for the %s build tag", f.Path, len(f.Routes), f.Tag)
+		}
+		if err := removeStaleTagGroupFiles(w, *out, tagFiles); err != nil {
+			diag.fatal("Error removing stale tag group files:", err)
+		}
+
+		for _, reg := range registerFiles {
+			if err := writeRegisterFile(w, reg, *middlewarePkg, *groupImports); err != nil {
+				diag.fatal("Error generating register file:", err)
+			}
+			w.report(reg.Path, "Generated %s with %d routes for the %s group", reg.Path, len(reg.Routes), reg.Name)
+		}
+		if err := removeStaleRegisterFiles(w, *src, registerFiles); err != nil {
+			diag.fatal("Error removing stale register files:", err)
+		}
+	}
+
+	if *genClient != "" {
+		if *clientPkg == "" {
+			*clientPkg = *pkg
+		}
+		n, err := writeClient(w, *genClient, *clientPkg, routes, *clientParamCase)
+		if err != nil {
+			diag.at(*genClient).fatal("Error generating client:", err)
+		}
+		w.report(*genClient, "Generated client %s with %d methods", *genClient, n)
+	}
+
+	if *genTS != "" {
+		n, err := writeTS(w, *genTS, routes)
+		if err != nil {
+			diag.at(*genTS).fatal("Error generating TypeScript routes:", err)
+		}
+		w.report(*genTS, "Generated %s with %d route paths", *genTS, n)
+	}
+
+	if *genBench != "" {
+		n, err := writeBench(w, *genBench, *out, *pkg, routes, *stripPrefix, *profile)
+		if err != nil {
+			diag.at(*genBench).fatal("Error generating benchmarks:", err)
+		}
+		w.report(*genBench, "Generated %s with %d route benchmarks", *genBench, n)
+	}
+
+	if *stats {
+		diag.printStats(collectStats(routes, routeGroups, redirects, middlewareList, *finalMiddleware))
+	}
+
+	if w.check {
+		if len(w.stale) > 0 {
+			diag.fatalf("generated files are out of date, run fsrouter generate: %s", strings.Join(w.stale, ", "))
+		}
+		diag.infof("Generated files are up to date")
+	}
+	if *failOnWarnings && *diag.warnings > 0 {
+		diag.fatalf("-failOnWarnings: %d warning(s) printed", *diag.warnings)
+	}
+}
+
+// buildVersion returns the version set via ldflags, falling back to the
+// module version recorded by go install.
+func buildVersion() string {
+	if version != "dev" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return version
+}
+
+// defaultNotFoundBody is the -notFoundBody default. fsrouter has always
+// written it with the request path as is, so it is emitted that way rather
+// than through the JSON-escaping of custom bodies.
+const defaultNotFoundBody = `{"error": "404 not found", "path": "%s"}`
+
+// checkNotFoundBody validates a -notFoundBody template, which may contain at
+// most one %s verb for the request path (and any number of %% escapes). It
+// reports whether the path verb is present.
+func checkNotFoundBody(body string) (bool, error) {
+	verbs := 0
+	for i := 0; i < len(body); i++ {
+		if body[i] != '%' {
+			continue
+		}
+		if i+1 == len(body) {
+			return false, fmt.Errorf("trailing %% in %q", body)
+		}
+		i++
+		switch body[i] {
+		case '%':
+		case 's':
+			verbs++
+		default:
+			return false, fmt.Errorf("unsupported verb %%%c in %q, only %%s is allowed", body[i], body)
+		}
+	}
+	if verbs > 1 {
+		return false, fmt.Errorf("%q has %d %%s verbs, at most one is allowed", body, verbs)
+	}
+	return verbs == 1, nil
+}
+
+// usesPackage reports whether any of the expressions refers to an
+// identifier qualified by the given package alias, including inside the
+// arguments of a middleware factory call.
+func usesPackage(alias string, exprs []string) bool {
+	for _, s := range exprs {
+		expr, err := parser.ParseExpr(s)
+		if err != nil {
+			continue
+		}
+		found := false
+		ast.Inspect(expr, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if id, ok := sel.X.(*ast.Ident); ok && id.Name == alias {
+					found = true
+				}
+			}
+			return !found
+		})
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// checkMiddlewareExpr validates a middleware list entry: a function name,
+// optionally package-qualified, or a simple call returning middleware such
+// as rateLimit(100) or middleware.Timeout(5*time.Second).
+func checkMiddlewareExpr(s string) error {
+	expr, err := parser.ParseExpr(s)
+	if err != nil {
+		return fmt.Errorf("invalid middleware %q: %v", s, err)
+	}
+	if call, ok := expr.(*ast.CallExpr); ok {
+		if call.Ellipsis.IsValid() {
+			return fmt.Errorf("invalid middleware %q: variadic calls are not supported", s)
+		}
+		for _, arg := range call.Args {
+			if !isSimpleArg(arg) {
+				return fmt.Errorf("invalid middleware %q: arguments must be literals, names or arithmetic on them", s)
+			}
+		}
+		expr = call.Fun
+	}
+	if !isQualifiedName(expr) {
+		return fmt.Errorf("invalid middleware %q, want a function name such as authMiddleware or a call such as rateLimit(100)", s)
+	}
+	return nil
+}
+
+// isQualifiedName reports whether expr is an identifier or pkg.Identifier.
+func isQualifiedName(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return true
+	case *ast.SelectorExpr:
+		_, ok := e.X.(*ast.Ident)
+		return ok
+	}
+	return false
+}
+
+// isSimpleArg reports whether expr is a literal, a qualified name, or unary
+// and binary operations on those.
+func isSimpleArg(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		return true
+	case *ast.ParenExpr:
+		return isSimpleArg(e.X)
+	case *ast.UnaryExpr:
+		return e.Op != token.AND && e.Op != token.ARROW && isSimpleArg(e.X)
+	case *ast.BinaryExpr:
+		return isSimpleArg(e.X) && isSimpleArg(e.Y)
+	}
+	return isQualifiedName(expr)
+}
+
+// parseRedirect parses a redirect value of the form "[status] [=>] target",
+// e.g. "=> /new-path" or "302 => /new-path". The status defaults to 301.
+func parseRedirect(from, value string) (redirect, error) {
+	rd := redirect{From: from, Status: 301}
+	fields := strings.Fields(value)
+	if len(fields) > 0 {
+		if status, err := strconv.Atoi(fields[0]); err == nil {
+			switch status {
+			case 301, 302, 303, 307, 308:
+				rd.Status = status
+			default:
+				return rd, fmt.Errorf("redirect for %s has unsupported status %d", from, status)
+			}
+			fields = fields[1:]
+		}
+	}
+	if len(fields) > 0 && fields[0] == "=>" {
+		fields = fields[1:]
+	}
+	if len(fields) != 1 {
+		return rd, fmt.Errorf("redirect for %s must be of the form \"[status] => /target\", got %q", from, strings.TrimSpace(value))
+	}
+	rd.To = fields[0]
+	return rd, nil
+}
+
+// routeGuard returns the condition under which a route is registered, or ""
+// when it is always registered. enabledIf is the -enabledIfFunc predicate,
+// or "" to test the environment variable for a non-empty value.
+func routeGuard(rt route, enabledIf string) string {
+	var conds []string
+	if len(rt.Tags) > 0 {
+		var tags []string
+		for _, tag := range rt.Tags {
+			tags = append(tags, "enabled["+strconv.Quote(tag)+"]")
+		}
+		conds = append(conds, strings.Join(tags, " || "))
+	}
+	if rt.EnabledIf != "" {
+		if enabledIf != "" {
+			conds = append(conds, enabledIf+"("+strconv.Quote(rt.EnabledIf)+")")
+		} else {
+			conds = append(conds, "os.Getenv("+strconv.Quote(rt.EnabledIf)+`) != ""`)
+		}
+	}
+	if len(conds) > 1 {
+		for i, cond := range conds {
+			if strings.Contains(cond, " || ") {
+				conds[i] = "(" + cond + ")"
+			}
+		}
+	}
+	return strings.Join(conds, " && ")
+}
+
+// handlerImports returns the handler package imports for routes, one per
+// package, sorted by import path. The set is derived only from the routes
+// discovered in this run, never from a previously generated file, so
+// deleting a handler directory removes its import on regeneration. With
+// grouped set, the imports are split into one commented block per top-level
+// directory, sorted by path within each.
+func handlerImports(routes []route, grouped bool) []importEntry {
+	seen := make(map[string]bool)
+	var imports []importEntry
+	tops := make(map[string]string)
+	for _, rt := range routes {
+		if rt.ImportPath == "" {
+			// Generated routes such as -autoOptions ones import nothing.
+			continue
+		}
+		if !seen[rt.ImportPath] {
+			seen[rt.ImportPath] = true
+			imports = append(imports, importEntry{Path: rt.ImportPath, Alias: rt.Alias})
+			top, _, _ := strings.Cut(rt.Dir, "/")
+			if top == "" {
+				// Top-level handlers are labeled with their package alias,
+				// the api directory's name.
+				top = rt.Alias
+			}
+			tops[rt.ImportPath] = top
+		}
+	}
+	sort.Slice(imports, func(i, j int) bool {
+		if ti, tj := tops[imports[i].Path], tops[imports[j].Path]; grouped && ti != tj {
+			return ti < tj
+		}
+		return imports[i].Path < imports[j].Path
+	})
+	if grouped {
+		for i := range imports {
+			if i == 0 || tops[imports[i].Path] != tops[imports[i-1].Path] {
+				imports[i].Comment = tops[imports[i].Path]
+			}
+		}
+	}
+	return imports
+}
+
+// packageImport returns the import of a package given by flag, such as
+// -middleware, in a block of its own when the handler imports are grouped.
+func packageImport(path, alias string, grouped bool) importEntry {
+	imp := importEntry{Path: path, Alias: alias}
+	if grouped {
+		imp.Comment = alias
+	}
+	return imp
+}
+
+// routeWrap is a per-route layer around a handler, with the directive or
+// flag it comes from.
+type routeWrap struct {
+	Source string
+	Wrap   func(h string) string
+}
+
+// routeWraps returns the per-route layers of a route from the innermost
+// outwards. With otel, a span covers all of them.
+func routeWraps(rt route, finalMiddleware, scopeMiddleware string, otel bool) []routeWrap {
+	var wraps []routeWrap
+	if finalMiddleware != "" {
+		wraps = append(wraps, routeWrap{"-finalMiddleware", func(h string) string { return finalMiddleware + "(" + h + ")" }})
+	}
+	if rt.Produces != "" {
+		wraps = append(wraps, routeWrap{"//fsrouter:produces", func(h string) string { return "produces(" + strconv.Quote(rt.Produces) + ")(" + h + ")" }})
+	}
+	if rt.CacheControl != "" {
+		wraps = append(wraps, routeWrap{"//fsrouter:cache", func(h string) string { return "cacheControl(" + strconv.Quote(rt.CacheControl) + ")(" + h + ")" }})
+	}
+	if rt.Schema != "" {
+		wraps = append(wraps, routeWrap{"-validateBodies", func(h string) string { return "validateBody(" + rt.Schema + ")(" + h + ")" }})
+	}
+	if rt.RequireJSON {
+		wraps = append(wraps, routeWrap{"-requireJSON", func(h string) string { return requireJSONName + "(" + h + ")" }})
+	}
+	if rt.CatchAllSlash != "" {
+		wraps = append(wraps, routeWrap{"//fsrouter:catchAll", func(h string) string {
+			return catchAllSlashName + "(" + strconv.Quote(rt.CatchAllSlash) + ")(" + h + ")"
+		}})
+	}
+	if rt.ParamParser != "" {
+		wraps = append(wraps, routeWrap{"typed parameters", func(h string) string { return rt.ParamParser + "(" + h + ")" }})
+	}
+	if rt.Timeout > 0 {
+		wraps = append(wraps, routeWrap{"//fsrouter:timeout", func(h string) string {
+			return "http.TimeoutHandler(" + h + ", " + durationExpr(rt.Timeout) + `, "timeout")`
+		}})
+	}
+	if rt.Deprecated {
+		wraps = append(wraps, routeWrap{"//fsrouter:deprecated", func(h string) string {
+			return "deprecated(" + strconv.Quote(rt.Successor) + ", " + strconv.Quote(rt.Sunset) + ")(" + h + ")"
+		}})
+	}
+	if len(rt.Scopes) > 0 {
+		args := make([]string, len(rt.Scopes))
+		for i, scope := range rt.Scopes {
+			args[i] = strconv.Quote(scope)
+		}
+		wraps = append(wraps, routeWrap{"//fsrouter:scopes", func(h string) string {
+			return scopeMiddleware + "(" + strings.Join(args, ", ") + ")(" + h + ")"
+		}})
+	}
+	for _, mw := range slices.Backward(rt.Middlewares) {
+		wraps = append(wraps, routeWrap{"-methodMiddlewares", func(h string) string { return mw + "(" + h + ")" }})
+	}
+	if otel {
+		wraps = append(wraps, routeWrap{"-otel", func(h string) string {
+			return "otelSpan(" + strconv.Quote(rt.Method) + ", " + strconv.Quote(rt.RoutePath) + ", " + h + ")"
+		}})
+	}
+	return wraps
+}
+
+// handlerExpr returns the expression registered for a route, wrapping its
+// handler in the routeWraps layers, or "" when the route needs no
+// wrapping.
+func handlerExpr(rt route, finalMiddleware, scopeMiddleware string, otel bool) string {
+	wraps := routeWraps(rt, finalMiddleware, scopeMiddleware, otel)
+	if len(wraps) == 0 {
+		return ""
+	}
+
+	// jsonHandler already returns an http.HandlerFunc.
+	expr := rt.HandlerFunc()
+	if !rt.JSONAPI {
+		expr = "http.HandlerFunc(" + expr + ")"
+	}
+	for _, wrap := range wraps {
+		expr = wrap.Wrap(expr)
+	}
+	return expr
+}
+
+// methodClasses maps each -methodMiddlewares class to the methods it covers;
+// "*" covers every method.
+var methodClasses = map[string][]string{
+	"read":  {"GET", "HEAD", "OPTIONS"},
+	"write": {"POST", "PUT", "PATCH", "DELETE"},
+	"*":     nil,
+}
+
+// methodMiddlewareList returns the -methodMiddlewares applying to method,
+// those for "*" before those of its class.
+func methodMiddlewareList(method string, classes map[string][]string) []string {
+	list := slices.Clone(classes["*"])
+	for _, class := range []string{"read", "write"} {
+		if slices.Contains(methodClasses[class], method) {
+			list = append(list, classes[class]...)
+		}
+	}
+	return list
+}
+
+// durationExpr renders d as a Go expression using the largest time unit
+// that divides it, e.g. 5*time.Second.
+func durationExpr(d time.Duration) string {
+	units := []struct {
+		unit time.Duration
+		name string
+	}{
+		{time.Hour, "time.Hour"},
+		{time.Minute, "time.Minute"},
+		{time.Second, "time.Second"},
+		{time.Millisecond, "time.Millisecond"},
+		{time.Microsecond, "time.Microsecond"},
+	}
+	for _, u := range units {
+		if d%u.unit == 0 {
+			return strconv.FormatInt(int64(d/u.unit), 10) + "*" + u.name
+		}
+	}
+	return "time.Duration(" + strconv.FormatInt(int64(d), 10) + ")"
+}
+
+// checkProxyURL validates a -proxyFallback target, which must be an
+// absolute http or https URL.
+func checkProxyURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q must be an absolute http or https URL", raw)
+	}
+	return nil
+}
+
+// routePath converts a slash-separated directory relative to the api root
+// into a mux path template, e.g. "users/[userId]" becomes "/users/{userId}".
+// A directory listed in overrides contributes its _path segments instead of
+// its own name.
+func routePath(dir string, overrides map[string]string) string {
+	var parts []string
+	segs := strings.Split(dir, "/")
+	for i, seg := range segs {
+		if override, ok := overrides[strings.Join(segs[:i+1], "/")]; ok {
+			parts = append(parts, override)
+			continue
+		}
+		if seg == "" || seg == "index" {
+			continue
+		}
+		if name, _, ok := parseParamSegment(seg); ok {
+			if param, pattern, ok := catchAll(name); ok {
+				name = param + ":" + pattern
+			}
+			parts = append(parts, "{"+name+"}")
+		} else {
+			parts = append(parts, seg)
+		}
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// relativePath returns a route path relative to the subrouter of the named
+// group, or relative to the top-level router when group is "".
+func relativePath(path, group string, overrides map[string]string) string {
+	if group == "" {
+		if path == "/" {
+			return ""
+		}
+		return path
+	}
+	prefix := routePath(group, overrides)
+	if prefix == "/" {
+		// mux appends a subrouter's templates to its prefix with the
+		// trailing slash trimmed, so under a group served at /, such as
+		// api/index, paths keep their leading slash.
+		return path
+	}
+	return strings.TrimPrefix(path, prefix)
+}
+
+// joinPath appends a relative route path to a group prefix.
+func joinPath(prefix, rel string) string {
+	if rel == "" || rel == "/" {
+		return prefix
+	}
+	return strings.TrimSuffix(prefix, "/") + rel
+}
+
+// parentDir returns the parent of a slash-separated relative directory, or
+// "" for a first-level directory.
+func parentDir(dir string) string {
+	if i := strings.LastIndex(dir, "/"); i >= 0 {
+		return dir[:i]
+	}
+	return ""
+}
+
+// sanitizeIdent turns a slash-separated relative directory into a valid Go
+// identifier, e.g. "users/[userId]" becomes "users_userId". The type of a
+// typed parameter is dropped, so "users/[userId:int]" does too.
+func sanitizeIdent(s string) string {
+	var b strings.Builder
+	inParam, inType := false, false
+	for _, c := range s {
+		switch {
+		case c == '[':
+			inParam = true
+		case c == ']':
+			inParam, inType = false, false
+		case inType:
+		// The dots and ? of catch-alls such as [...path?] are dropped.
+		case inParam && (c == '.' || c == '?'):
+		case inParam && c == ':':
+			inType = true
+		case c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c):
+			b.WriteRune(c)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	ident := b.String()
+	if ident == "" || unicode.IsDigit(rune(ident[0])) {
+		ident = "_" + ident
+	}
+	return ident
+}
+
+// expandChains replaces each @name in list with the middleware of that
+// chain, recursively. stack holds the chains being expanded, to report
+// cycles.
+func expandChains(list []string, chains map[string][]string, stack []string) ([]string, error) {
+	var expanded []string
+	for _, item := range list {
+		name, ok := strings.CutPrefix(item, "@")
+		if !ok {
+			expanded = append(expanded, item)
+			continue
+		}
+		chain, ok := chains[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown chain @%s", name)
+		}
+		if slices.Contains(stack, name) {
+			return nil, fmt.Errorf("chain cycle @%s -> @%s", strings.Join(stack, " -> @"), name)
+		}
+		items, err := expandChains(chain, chains, append(slices.Clone(stack), name))
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, items...)
+	}
+	return expanded, nil
+}
+
+// middlewareValue returns the middleware of one -groupMiddlewares entry,
+// either a comma-separated string or, as fsrouter first accepted, an array
+// of strings.
+func middlewareValue(value json.RawMessage) ([]string, error) {
+	var list string
+	if err := json.Unmarshal(value, &list); err == nil {
+		return splitList(list), nil
+	}
+	var items []string
+	if err := json.Unmarshal(value, &items); err != nil {
+		return nil, fmt.Errorf("want a comma-separated string or an array of strings, got %s", value)
+	}
+	var middlewares []string
+	for _, item := range items {
+		middlewares = append(middlewares, splitList(item)...)
+	}
+	return middlewares, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+// Commas inside parentheses or string literals do not split, so factory
+// calls such as rateLimit(100, time.Minute) stay whole.
+func splitList(s string) []string {
+	var list []string
+	depth, quote, start := 0, rune(0), 0
+	add := func(item string) {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	for i, c := range s {
+		switch {
+		case quote != 0:
+			if c == quote && (i == 0 || s[i-1] != '\\') {
+				quote = 0
+			}
+		case c == '"' || c == '`' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			add(s[start:i])
+			start = i + 1
+		}
+	}
+	add(s[start:])
+	return list
 }
@@ -0,0 +1,921 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testModule is the module the tests generate routers in, with the go.sum
+// lines of the gorilla/mux version the generated code is compiled against.
+const (
+	testModule = "example.com/app"
+	testGoMod  = "module " + testModule + "\n\ngo 1.24\n\nrequire github.com/gorilla/mux v1.8.1\n"
+	testGoSum  = "github.com/gorilla/mux v1.8.1 h1:TuBL49tXwgrFYWhqrNgrUNEY92u81SPhu7sTdzQEiWY=\n" +
+		"github.com/gorilla/mux v1.8.1/go.mod h1:AKf9I4AEqPTmMytcMc0KkNouC66V3BtZ4qD5fmWSiMQ=\n"
+)
+
+// TestMain runs fsrouter itself instead of the tests when FSROUTER_MAIN is
+// set, so that tests can run the command in a module of their own.
+func TestMain(m *testing.M) {
+	if os.Getenv("FSROUTER_MAIN") != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// handlerFile returns the source of a handler file in package pkg whose
+// function name writes body.
+func handlerFile(pkg, name, body string) string {
+	return fmt.Sprintf("package %s\n\nimport \"net/http\"\n\nfunc %s(w http.ResponseWriter, r *http.Request) {\n\tw.Write([]byte(%q))\n}\n", pkg, name, body)
+}
+
+// writeFiles writes files, keyed by slash-separated path, below dir.
+func writeFiles(t testing.TB, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// newModule creates a module holding files in a temporary directory, with
+// an empty main function unless files has a main.go, and returns the
+// directory.
+func newModule(t testing.TB, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"go.mod": testGoMod, "go.sum": testGoSum, "main.go": "package main\n\nfunc main() {}\n"})
+	writeFiles(t, dir, files)
+	return dir
+}
+
+// fsrouter runs fsrouter with args in dir and returns what it printed.
+func fsrouter(dir string, args ...string) (string, error) {
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "FSROUTER_MAIN=1")
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// generate runs fsrouter with args in dir, which newModule created, and
+// returns the generated routes_gen.go and what fsrouter printed.
+func generate(t testing.TB, dir string, args ...string) (src, output string) {
+	t.Helper()
+	output, err := fsrouter(dir, append([]string{"-importPREFIX=" + testModule + "/api"}, args...)...)
+	if err != nil {
+		t.Fatalf("fsrouter: %v\n%s", err, output)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "routes_gen.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b), output
+}
+
+// runGoTest adds test as router_test.go to the module in dir and runs its
+// tests with go test, which compiles the generated router. It needs
+// gorilla/mux in the module cache and is skipped without it or with -short.
+func runGoTest(t *testing.T, dir, test string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("compiles the generated router")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	writeFiles(t, dir, map[string]string{"router_test.go": test})
+	cmd := exec.Command("go", "test", "-count=1", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOWORK=off")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(out), "module lookup disabled") {
+			t.Skip("github.com/gorilla/mux is not in the module cache")
+		}
+		t.Fatalf("go test of the generated router: %v\n%s", err, out)
+	}
+}
+
+func TestUnusedMiddlewarePackageNotImported(t *testing.T) {
+	dir := newModule(t, map[string]string{
+		"api/users/get.go": handlerFile("users", "Get", "users"),
+		"mw/mw.go":         "package mw\n\nimport \"net/http\"\n\nfunc Auth(next http.Handler) http.Handler { return next }\n",
+	})
+	src, output := generate(t, dir, "-middleware="+testModule+"/mw")
+	if strings.Contains(src, testModule+"/mw") {
+		t.Errorf("unused middleware package imported:\n%s", src)
+	}
+	if !strings.Contains(output, "has no effect") {
+		t.Errorf("no warning about the unused -middleware, got %q", output)
+	}
+	runGoTest(t, dir, "package main\n")
+}
+
+func TestUsedMiddlewarePackageImported(t *testing.T) {
+	dir := newModule(t, map[string]string{
+		"api/users/get.go": handlerFile("users", "Get", "users"),
+		"mw/mw.go":         "package mw\n\nimport \"net/http\"\n\nfunc Auth(next http.Handler) http.Handler { return next }\n",
+	})
+	src, output := generate(t, dir, "-middleware="+testModule+"/mw", "-middlewares=middleware.Auth")
+	if !strings.Contains(src, `middleware "`+testModule+`/mw"`) {
+		t.Errorf("middleware package not imported:\n%s", src)
+	}
+	if strings.Contains(output, "has no effect") {
+		t.Errorf("warning about a used -middleware: %q", output)
+	}
+	runGoTest(t, dir, "package main\n")
+}
+
+func TestRegenerateDropsRemovedHandlerImport(t *testing.T) {
+	dir := newModule(t, map[string]string{
+		"api/users/get.go":  handlerFile("users", "Get", "users"),
+		"api/orders/get.go": handlerFile("orders", "Get", "orders"),
+	})
+	if src, _ := generate(t, dir); !strings.Contains(src, `orders "`+testModule+`/api/orders"`) {
+		t.Fatalf("orders not imported:\n%s", src)
+	}
+	if err := os.RemoveAll(filepath.Join(dir, "api", "orders")); err != nil {
+		t.Fatal(err)
+	}
+	src, _ := generate(t, dir)
+	if strings.Contains(src, "api/orders") || strings.Contains(src, "orders.Get") {
+		t.Errorf("removed handler still referenced:\n%s", src)
+	}
+	runGoTest(t, dir, "package main\n")
+}
+
+func BenchmarkGenerate(b *testing.B) {
+	dir := newModule(b, nil)
+	if err := os.CopyFS(filepath.Join(dir, "api"), syntheticTree(200)); err != nil {
+		b.Fatal(err)
+	}
+	for b.Loop() {
+		generate(b, dir)
+	}
+}
+
+func TestEmptyAPIDirectory(t *testing.T) {
+	dir := newModule(t, nil)
+	if err := os.Mkdir(filepath.Join(dir, "api"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	src, output := generate(t, dir)
+	if !strings.Contains(output, "no handler files found") {
+		t.Errorf("no warning about the empty api directory, got %q", output)
+	}
+	if strings.Contains(src, testModule+"/api") {
+		t.Errorf("empty api directory imported:\n%s", src)
+	}
+	runGoTest(t, dir, `package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestRoutes(t *testing.T) {
+	r := RegisterRoutes()
+	n := 0
+	r.Walk(func(*mux.Route, *mux.Router, []*mux.Route) error {
+		n++
+		return nil
+	})
+	if n != 0 {
+		t.Errorf("router has %d routes, want none", n)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != 404 {
+		t.Errorf("GET / = %d, want 404", w.Code)
+	}
+}
+`)
+}
+
+func TestUnicodePath(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args []string
+		path string
+	}{
+		{"decoded", nil, `"/café"`},
+		{"encodedPath", []string{"-encodedPath"}, `"/caf%C3%A9"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := newModule(t, map[string]string{
+				"api/cafe/_path":  "/café\n",
+				"api/cafe/get.go": handlerFile("cafe", "Get", "cafe"),
+			})
+			src, _ := generate(t, dir, tc.args...)
+			for _, want := range []string{`cafe "` + testModule + `/api/cafe"`, tc.path, "cafe.Get"} {
+				if !strings.Contains(src, want) {
+					t.Errorf("generated router lacks %s:\n%s", want, src)
+				}
+			}
+			runGoTest(t, dir, `package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	r := RegisterRoutes()
+	for _, path := range []string{"/café", "/caf%C3%A9"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != 200 || w.Body.String() != "cafe" {
+			t.Errorf("GET %s = %d %q, want 200 \"cafe\"", path, w.Code, w.Body.String())
+		}
+	}
+}
+`)
+		})
+	}
+}
+
+func TestDedupeSymlinkedHandler(t *testing.T) {
+	dir := newModule(t, map[string]string{
+		"api/users/get.go": handlerFile("users", "Get", "users"),
+	})
+	if err := os.Mkdir(filepath.Join(dir, "api", "people"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("..", "users", "get.go"), filepath.Join(dir, "api", "people", "get.go")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	src, _ := generate(t, dir, "-dedupeHandlers")
+	for _, want := range []string{`peopleRouter.HandleFunc("", users.Get)`, `usersRouter.HandleFunc("", users.Get)`} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated router lacks %s:\n%s", want, src)
+		}
+	}
+	if strings.Contains(src, testModule+"/api/people") {
+		t.Errorf("symlink package imported:\n%s", src)
+	}
+	runGoTest(t, dir, `package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	r := RegisterRoutes()
+	for _, path := range []string{"/people", "/users"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != 200 || w.Body.String() != "users" {
+			t.Errorf("GET %s = %d %q, want 200 \"users\"", path, w.Code, w.Body.String())
+		}
+	}
+}
+`)
+}
+
+func TestInternalRouteLeftOutOfDocs(t *testing.T) {
+	dir := newModule(t, map[string]string{
+		"api/users/get.go":  handlerFile("users", "Get", "users"),
+		"api/health/get.go": "//fsrouter:internal\n" + handlerFile("health", "Get", "ok"),
+	})
+	src, _ := generate(t, dir, "-serveSpec=/openapi.json", "-genClient=client_gen.go", "-genTS=routes.ts")
+	if !strings.Contains(src, `healthRouter.HandleFunc("", health.Get)`) {
+		t.Errorf("internal route not registered:\n%s", src)
+	}
+	for _, name := range []string{"client_gen.go", "routes.ts"} {
+		out, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(out), "users") || strings.Contains(strings.ToLower(string(out)), "health") {
+			t.Errorf("%s should list only /users:\n%s", name, out)
+		}
+	}
+	runGoTest(t, dir, `package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	r := RegisterRoutes()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+	if w.Code != 200 || w.Body.String() != "ok" {
+		t.Errorf("GET /health = %d %q, want 200 \"ok\"", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+	if spec := w.Body.String(); !strings.Contains(spec, "\"/users\"") || strings.Contains(spec, "/health") {
+		t.Errorf("OpenAPI description should list only /users: %s", spec)
+	}
+}
+`)
+}
+
+func TestIndexGroupServesRoot(t *testing.T) {
+	dir := newModule(t, map[string]string{
+		"api/index/get.go":     handlerFile("index", "Get", "index"),
+		"api/index/sub/get.go": handlerFile("sub", "Get", "sub"),
+	})
+	src, _ := generate(t, dir)
+	if !strings.Contains(src, `indexRouter.HandleFunc("/", index.Get)`) {
+		t.Errorf("index route not registered at /:\n%s", src)
+	}
+	runGoTest(t, dir, `package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	r := RegisterRoutes()
+	for path, want := range map[string]string{"/": "index", "/sub": "sub"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != 200 || w.Body.String() != want {
+			t.Errorf("GET %s = %d %q, want 200 %q", path, w.Code, w.Body.String(), want)
+		}
+	}
+}
+`)
+}
+
+func TestCORSUnknownPathFallsBack(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"notFound", nil, 404},
+		{"proxyFallback", []string{"-proxyFallback=http://127.0.0.1:1"}, 502},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := newModule(t, map[string]string{
+				"api/users/get.go": handlerFile("users", "Get", "users"),
+			})
+			generate(t, dir, append([]string{`-cors={"origins":["*"]}`}, tc.args...)...)
+			runGoTest(t, dir, fmt.Sprintf(`package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	r := RegisterRoutes()
+	preflight := httptest.NewRequest("OPTIONS", "/nope", nil)
+	preflight.Header.Set("Origin", "https://app.example.com")
+	preflight.Header.Set("Access-Control-Request-Method", "GET")
+	for req, want := range map[*http.Request]int{
+		httptest.NewRequest("GET", "/nope", nil):     %[1]d,
+		httptest.NewRequest("OPTIONS", "/nope", nil): %[1]d,
+		preflight: 204,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("%%s %%s = %%d, want %%d", req.Method, req.URL.Path, w.Code, want)
+		}
+	}
+}
+`, tc.want))
+		})
+	}
+}
+
+func TestCORSHeaders(t *testing.T) {
+	dir := newModule(t, map[string]string{
+		"api/users/get.go":  handlerFile("users", "Get", "users"),
+		"api/users/post.go": handlerFile("users", "Post", "created"),
+	})
+	src, _ := generate(t, dir, `-cors={"origins":["https://app.example.com"],"methods":["GET","POST","DELETE"],"credentials":true,"maxAge":600}`, "-autoOptions")
+	if !strings.Contains(src, `"/users": {"GET, OPTIONS, POST", "GET, OPTIONS, POST"}`) {
+		t.Errorf("corsPathMethods lacks /users:\n%s", src)
+	}
+	runGoTest(t, dir, `package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	r := RegisterRoutes()
+	const app = "https://app.example.com"
+	for _, tc := range []struct {
+		name, method, path, origin, requestMethod string
+		code                                      int
+		headers                                   map[string]string
+	}{
+		{"preflight", "OPTIONS", "/users", app, "POST", 204, map[string]string{
+			"Access-Control-Allow-Origin":      app,
+			"Access-Control-Allow-Credentials": "true",
+			"Access-Control-Allow-Methods":     "GET, OPTIONS, POST",
+			"Access-Control-Max-Age":           "600",
+			"Allow":                            "GET, OPTIONS, POST",
+		}},
+		{"preflight without a route", "OPTIONS", "/nope", app, "GET", 204, map[string]string{
+			"Access-Control-Allow-Origin":  app,
+			"Access-Control-Allow-Methods": "GET, POST, DELETE, OPTIONS",
+		}},
+		{"plain OPTIONS", "OPTIONS", "/users", app, "", 204, map[string]string{
+			"Access-Control-Allow-Origin":  app,
+			"Access-Control-Allow-Methods": "",
+			"Access-Control-Max-Age":       "",
+			"Allow":                        "GET, OPTIONS, POST",
+		}},
+		{"plain OPTIONS without a route", "OPTIONS", "/nope", app, "", 404, nil},
+		{"simple request", "GET", "/users", app, "", 200, map[string]string{
+			"Access-Control-Allow-Origin":  app,
+			"Access-Control-Allow-Methods": "",
+		}},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		req.Header.Set("Origin", tc.origin)
+		if tc.requestMethod != "" {
+			req.Header.Set("Access-Control-Request-Method", tc.requestMethod)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Errorf("%s: status %d, want %d", tc.name, w.Code, tc.code)
+		}
+		for name, want := range tc.headers {
+			if got := w.Header().Get(name); got != want {
+				t.Errorf("%s: %s = %q, want %q", tc.name, name, got, want)
+			}
+		}
+	}
+
+	for _, method := range []string{"OPTIONS", "GET"} {
+		req := httptest.NewRequest(method, "/users", nil)
+		req.Header.Set("Origin", "https://evil.example.com")
+		req.Header.Set("Access-Control-Request-Method", "GET")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		for name := range w.Header() {
+			if strings.HasPrefix(name, "Access-Control-") {
+				t.Errorf("%s from a disallowed origin got %s: %q", method, name, w.Header().Get(name))
+			}
+		}
+	}
+}
+`)
+}
+
+func TestDefaultNotFoundBody(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args []string
+		code int
+		body string
+	}{
+		{"default", nil, 404, `{"error": "404 not found", "path": "/a"b"}`},
+		{"custom", []string{`-notFoundStatus=410`, `-notFoundBody={"gone": "%s"}`}, 410, `{"gone": "/a\"b"}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := newModule(t, map[string]string{
+				"api/users/get.go": handlerFile("users", "Get", "users"),
+			})
+			generate(t, dir, tc.args...)
+			runGoTest(t, dir, fmt.Sprintf(`package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	w := httptest.NewRecorder()
+	RegisterRoutes().ServeHTTP(w, httptest.NewRequest("GET", "/a%%22b", nil))
+	if w.Code != %d || w.Body.String() != %q {
+		t.Errorf("GET /a%%%%22b = %%d %%s", w.Code, w.Body.String())
+	}
+}
+`, tc.code, tc.body))
+		})
+	}
+}
+
+func TestGroupMiddlewaresForms(t *testing.T) {
+	var srcs []string
+	for _, value := range []string{
+		`{"users":"authMiddleware,rateLimit(100, time.Minute)"}`,
+		`{"users":["authMiddleware","rateLimit(100, time.Minute)"]}`,
+	} {
+		dir := newModule(t, map[string]string{
+			"api/users/get.go": handlerFile("users", "Get", "users"),
+		})
+		src, _ := generate(t, dir, "-groupMiddlewares="+value)
+		if !strings.Contains(src, "usersRouter.Use(authMiddleware)") || !strings.Contains(src, "usersRouter.Use(rateLimit(100, time.Minute))") {
+			t.Errorf("%s: group middleware missing:\n%s", value, src)
+		}
+		srcs = append(srcs, src)
+	}
+	if srcs[0] != srcs[1] {
+		t.Error("string and array values generate different routers")
+	}
+	dir := newModule(t, map[string]string{
+		"api/users/get.go": handlerFile("users", "Get", "users"),
+	})
+	if out, err := fsrouter(dir, "-importPREFIX="+testModule+"/api", `-groupMiddlewares={"users":1}`); err == nil || !strings.Contains(out, "array of strings") {
+		t.Errorf("numeric value accepted: %v\n%s", err, out)
+	}
+}
+
+func TestRedirectUnderStripPrefix(t *testing.T) {
+	dir := newModule(t, map[string]string{
+		"api/users/get.go":           handlerFile("users", "Get", "users"),
+		"api/old-users/redirect.txt": "=> /users\n",
+	})
+	generate(t, dir, "-stripPrefix=/api", `-redirects={"/docs":"302 => https://docs.example.com/"}`)
+	runGoTest(t, dir, `package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	r := RegisterRoutes()
+	for path, want := range map[string]string{
+		"/api/old-users": "/api/users",
+		"/api/docs":      "https://docs.example.com/",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if loc := w.Header().Get("Location"); loc != want {
+			t.Errorf("GET %s redirects to %q, want %q", path, loc, want)
+		}
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/users", nil))
+	if w.Code != 200 || w.Body.String() != "users" {
+		t.Errorf("GET /api/users = %d %q", w.Code, w.Body.String())
+	}
+}
+`)
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	for _, tc := range []struct {
+		name, prefix string
+	}{
+		{"root", ""},
+		{"stripPrefix", "/api"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := newModule(t, map[string]string{
+				"api/users/get.go": `package users
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+var Paths = []string{"/", "/{userId}"}
+
+func Get(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(r.URL.Path + " " + mux.Vars(r)["userId"] + " " + r.Header.Get("X-Original-Path")))
+}
+`,
+				"main.go": `package main
+
+import "net/http"
+
+func main() {}
+
+// contextPath reports the path caseInsensitive kept in the context.
+func contextPath(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Context-Path", OriginalPathFromContext(r.Context()))
+		next.ServeHTTP(w, r)
+	})
+}
+`,
+			})
+			args := []string{"-caseInsensitive", "-middlewares=contextPath"}
+			if tc.prefix != "" {
+				args = append(args, "-stripPrefix="+tc.prefix)
+			}
+			generate(t, dir, args...)
+			runGoTest(t, dir, fmt.Sprintf(`package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	const prefix = %q
+	r := RegisterRoutes()
+	for path, want := range map[string]string{
+		"/users":     "/users  " + prefix + "/users",
+		"/Users":     "/users  " + prefix + "/Users",
+		"/USERS/AbC": "/users/abc abc " + prefix + "/USERS/AbC",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", prefix+path, nil))
+		if w.Code != 200 || w.Body.String() != want {
+			t.Errorf("GET %%s = %%d %%q, want 200 %%q", prefix+path, w.Code, w.Body.String(), want)
+		}
+		if got := w.Header().Get("X-Context-Path"); got != prefix+path {
+			t.Errorf("GET %%s: OriginalPathFromContext = %%q, want %%q", prefix+path, got, prefix+path)
+		}
+	}
+}
+`, tc.prefix))
+		})
+	}
+}
+
+func TestGzip(t *testing.T) {
+	dir := newModule(t, map[string]string{
+		"api/big/get.go": `package big
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func Get(w http.ResponseWriter, r *http.Request) {
+	body := strings.Repeat("compressible ", 200)
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Write([]byte(body))
+}
+`,
+		"api/small/get.go": handlerFile("small", "Get", "small"),
+	})
+	generate(t, dir, "-gzip")
+	runGoTest(t, dir, `package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	r := RegisterRoutes()
+	want := strings.Repeat("compressible ", 200)
+	get := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if vary := w.Header().Get("Vary"); vary != "Accept-Encoding" {
+			t.Errorf("GET %s (Accept-Encoding %q): Vary = %q", path, acceptEncoding, vary)
+		}
+		return w
+	}
+
+	w := get("/big", "gzip")
+	if enc := w.Header().Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("GET /big with gzip: Content-Encoding = %q", enc)
+	}
+	if cl := w.Header().Get("Content-Length"); cl != "" && cl != strconv.Itoa(w.Body.Len()) {
+		t.Errorf("GET /big with gzip: Content-Length %s for a %d byte body", cl, w.Body.Len())
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := io.ReadAll(zr); err != nil || string(b) != want {
+		t.Errorf("GET /big with gzip: body %q, %v", b, err)
+	}
+
+	for _, acceptEncoding := range []string{"", "br", "gzip;q=0"} {
+		w := get("/big", acceptEncoding)
+		if enc := w.Header().Get("Content-Encoding"); enc != "" {
+			t.Errorf("GET /big (Accept-Encoding %q): Content-Encoding = %q", acceptEncoding, enc)
+		}
+		if w.Body.String() != want || w.Header().Get("Content-Length") != strconv.Itoa(len(want)) {
+			t.Errorf("GET /big (Accept-Encoding %q): Content-Length %s for a %d byte body", acceptEncoding, w.Header().Get("Content-Length"), w.Body.Len())
+		}
+	}
+
+	if w := get("/small", "gzip"); w.Header().Get("Content-Encoding") != "" || w.Body.String() != "small" {
+		t.Errorf("GET /small with gzip = %q, Content-Encoding %q", w.Body.String(), w.Header().Get("Content-Encoding"))
+	}
+}
+`)
+}
+
+func TestFallback(t *testing.T) {
+	dir := newModule(t, map[string]string{
+		"api/users/get.go":      handlerFile("users", "Get", "users"),
+		"api/users/fallback.go": handlerFile("users", "Fallback", "fallback"),
+		"api/posts/get.go":      handlerFile("posts", "Get", "posts"),
+	})
+	src, _ := generate(t, dir, "-autoOptions")
+	get := strings.Index(src, `usersRouter.HandleFunc("", users.Get).Methods("GET")`)
+	fallback := strings.Index(src, `usersRouter.HandleFunc("", users.Fallback)`+"\n")
+	if get < 0 || fallback < get {
+		t.Errorf("fallback not registered after GET /users:\n%s", src)
+	}
+	runGoTest(t, dir, `package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	r := RegisterRoutes()
+	for _, tc := range []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{"GET", "/users", 200, "users"},
+		{"POST", "/users", 200, "fallback"},
+		{"DELETE", "/users", 200, "fallback"},
+		{"OPTIONS", "/users", 200, "fallback"},
+		{"GET", "/posts", 200, "posts"},
+		{"POST", "/posts", 405, ""},
+		{"POST", "/nope", 404, ""},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		if w.Code != tc.code || tc.body != "" && w.Body.String() != tc.body {
+			t.Errorf("%s %s = %d %q, want %d %q", tc.method, tc.path, w.Code, w.Body.String(), tc.code, tc.body)
+		}
+	}
+}
+`)
+}
+
+func TestCatchAll(t *testing.T) {
+	dir := newModule(t, map[string]string{
+		"api/files/[...path]/get.go": handlerFile("files_path", "Get", "path"),
+		"api/files/[id]/get.go":      handlerFile("files_id", "Get", "id"),
+		"api/files/readme/get.go":    handlerFile("readme", "Get", "readme"),
+	})
+	src, _ := generate(t, dir)
+	var last int
+	for _, reg := range []string{
+		`filesRouter.HandleFunc("/readme", files_readme.Get)`,
+		`filesRouter.HandleFunc("/{id}", files_id.Get)`,
+		`filesRouter.HandleFunc("/{path:.*}", files_path.Get)`,
+	} {
+		i := strings.Index(src, reg)
+		if i < last {
+			t.Fatalf("%s missing or registered too early:\n%s", reg, src)
+		}
+		last = i
+	}
+
+	// Bracketed directories cannot be imported by a compiled router, so the
+	// requests go to a catch-all from a Paths variable instead.
+	dir = newModule(t, map[string]string{
+		"api/docs/get.go": `package docs
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+var Paths = []string{"/{path:.*}"}
+
+func Get(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("path=" + mux.Vars(r)["path"]))
+}
+`,
+		"api/docs/readme/get.go": handlerFile("readme", "Get", "readme"),
+	})
+	generate(t, dir)
+	runGoTest(t, dir, `package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	r := RegisterRoutes()
+	for path, want := range map[string]string{
+		"/docs/":                     "path=",
+		"/docs/intro":                "path=intro",
+		"/docs/guides/2024/setup.md": "path=guides/2024/setup.md",
+		"/docs/readme":               "readme",
+		"/docs/readme/more":          "path=readme/more",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != 200 || w.Body.String() != want {
+			t.Errorf("GET %s = %d %q, want 200 %q", path, w.Code, w.Body.String(), want)
+		}
+	}
+}
+`)
+}
+
+func TestPackagesTarget(t *testing.T) {
+	const usersGet = `package users
+
+import "net/http"
+
+// Name is shared with the handlers below users.
+const Name = "users"
+
+func Get(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(Name))
+}
+`
+	files := map[string]string{
+		"api/users/get.go":                usersGet,
+		"api/users/settings/get.go":       handlerFile("settings", "Get", "settings"),
+		"api/users/settings/theme/get.go": handlerFile("theme", "Get", "theme"),
+	}
+	dir := newModule(t, files)
+	src, _ := generate(t, dir, "-target=packages")
+	if !strings.Contains(src, "users.Register(usersRouter)") {
+		t.Errorf("main file does not call users.Register:\n%s", src)
+	}
+	runGoTest(t, dir, `package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	r := RegisterRoutes()
+	for path, want := range map[string]string{
+		"/users":                "users",
+		"/users/settings":       "settings",
+		"/users/settings/theme": "theme",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != 200 || w.Body.String() != want {
+			t.Errorf("GET %s = %d %q, want 200 %q", path, w.Code, w.Body.String(), want)
+		}
+	}
+}
+`)
+
+	const importsUsers = `package %s
+
+import (
+	"net/http"
+
+	"example.com/app/api/users"
+)
+
+func %s(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(users.Name))
+}
+`
+	for _, tc := range []struct {
+		name  string
+		files map[string]string
+		err   string
+	}{
+		{"direct", map[string]string{
+			"api/users/settings/get.go": fmt.Sprintf(importsUsers, "settings", "Get"),
+		}, "import cycle: example.com/app/api/users would import example.com/app/api/users/settings to register its routes, but example.com/app/api/users/settings, which imports example.com/app/api/users"},
+		{"through another package", map[string]string{
+			"api/users/settings/theme/get.go": "package theme\n\nimport (\n\t\"net/http\"\n\n\t\"example.com/app/api/shared\"\n)\n\nfunc Get(w http.ResponseWriter, r *http.Request) {\n\tw.Write([]byte(shared.Name()))\n}\n",
+			"api/shared/shared.go":            "package shared\n\nimport \"example.com/app/api/users\"\n\nfunc Name() string { return users.Name }\n",
+		}, "but example.com/app/api/users/settings/theme, which imports example.com/app/api/shared, which imports example.com/app/api/users"},
+	} {
+		dir := newModule(t, files)
+		writeFiles(t, dir, tc.files)
+		out, err := fsrouter(dir, "-importPREFIX="+testModule+"/api", "-target=packages")
+		if err == nil || !strings.Contains(out, tc.err) {
+			t.Errorf("%s: got %v\n%s\nwant an error containing %q", tc.name, err, out, tc.err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "api/users", registerFileName)); err == nil {
+			t.Errorf("%s: %s written despite the cycle", tc.name, registerFileName)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+)
+
+// methodNotAllowedFile is the file of a group directory whose
+// MethodNotAllowed function answers requests matching a path of the group
+// but none of its methods. At the api root it answers for every group
+// without one of its own.
+const methodNotAllowedFile = "methodNotAllowed.go"
+
+// scanMethodNotAllowed parses the methodNotAllowed.go file at p. The
+// returned route only carries the package and handler of the function.
+func scanMethodNotAllowed(fsys fs.FS, p, root, importPre string) (route, error) {
+	display := filepath.Join(root, filepath.FromSlash(p))
+	dir := slashDir(p)
+	rt := route{Dir: dir, ImportPath: path.Join(importPre, dir), Alias: sanitizeIdent(dir), Handler: "MethodNotAllowed"}
+	if dir == "" {
+		rt.Alias = sanitizeIdent(filepath.Base(root))
+	}
+
+	src, err := fs.ReadFile(fsys, p)
+	if err != nil {
+		return rt, err
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, display, src, 0)
+	if err != nil {
+		return rt, err
+	}
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == rt.Handler {
+			if !isHandlerFunc(fn.Type, importName(file, "net/http")) {
+				return rt, fmt.Errorf("%s: MethodNotAllowed must have the signature func(w http.ResponseWriter, r *http.Request)", fset.Position(fn.Pos()))
+			}
+			return rt, nil
+		}
+	}
+	return rt, fmt.Errorf("%s: no handler function, want func MethodNotAllowed(w http.ResponseWriter, r *http.Request)", display)
+}
+
+// assignMethodNotAllowed sets the MethodNotAllowed handler of the group of
+// each methodNotAllowed.go file and returns the one of the api root, which
+// the router falls back to.
+func assignMethodNotAllowed(groups []group, handlers []route, root string) (string, error) {
+	var global string
+	for _, h := range handlers {
+		expr := h.Alias + "." + h.Handler
+		if h.Dir == "" {
+			global = expr
+			continue
+		}
+		display := filepath.Join(root, filepath.FromSlash(h.Dir), methodNotAllowedFile)
+		i := slices.IndexFunc(groups, func(g group) bool { return g.Name == h.Dir })
+		switch {
+		case i < 0:
+			return "", fmt.Errorf("%s: %s is not a route group, only groups have their own 405 handler", display, h.Dir)
+		case groups[i].Gate != "":
+			return "", fmt.Errorf("%s: %s is compiled in by the %s build tag, groups with their own 405 handler must always be", display, h.Dir, groups[i].Gate)
+		}
+		groups[i].MethodNotAllowed = expr
+	}
+	return global, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// findModule returns the path of the module containing dir, an absolute
+// path, and the directory of its go.mod, or empty strings when no go.mod is
+// found above dir.
+func findModule(dir string) (modPath, modDir string, err error) {
+	for d := dir; ; d = filepath.Dir(d) {
+		gomod := filepath.Join(d, "go.mod")
+		data, err := os.ReadFile(gomod)
+		switch {
+		case err == nil:
+			modPath := moduleDirective(data)
+			if modPath == "" {
+				return "", "", fmt.Errorf("%s has no module directive", gomod)
+			}
+			return modPath, d, nil
+		case !errors.Is(err, fs.ErrNotExist):
+			return "", "", err
+		}
+		if filepath.Dir(d) == d {
+			return "", "", nil
+		}
+	}
+}
+
+// moduleDirective returns the module path declared by a go.mod file, or ""
+// if it declares none.
+func moduleDirective(gomod []byte) string {
+	sc := bufio.NewScanner(bytes.NewReader(gomod))
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "//")
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != "module" {
+			continue
+		}
+		if p, err := strconv.Unquote(fields[1]); err == nil {
+			return p
+		}
+		return fields[1]
+	}
+	return ""
+}
+
+// checkImportPrefix reports an -importPREFIX that is not the import path of
+// the api directory in the module it belongs to, which may be a sibling of
+// the module of the generated file. An api directory outside the generated
+// file's module that belongs to no module cannot be imported at all. Trees
+// without go.mod files are not checked.
+func checkImportPrefix(api, out, importPre string) error {
+	apiDir, err := filepath.Abs(api)
+	if err != nil {
+		return err
+	}
+	outDir := "."
+	if out != stdoutPath {
+		outDir = filepath.Dir(out)
+	}
+	if outDir, err = filepath.Abs(outDir); err != nil {
+		return err
+	}
+	apiMod, apiModDir, err := findModule(apiDir)
+	if err != nil {
+		return fmt.Errorf("cannot determine the module of %s: %w", api, err)
+	}
+	outMod, outModDir, err := findModule(outDir)
+	if err != nil {
+		return fmt.Errorf("cannot determine the module of %s: %w", out, err)
+	}
+
+	switch {
+	case apiModDir == "" && outModDir != "":
+		return fmt.Errorf("%s is outside module %s of %s and belongs to no module, so its packages cannot be imported; give it a go.mod", api, outMod, out)
+	case apiModDir == "":
+		return nil
+	}
+	rel, err := filepath.Rel(apiModDir, apiDir)
+	if err != nil {
+		return err
+	}
+	want := path.Join(apiMod, filepath.ToSlash(rel))
+	switch {
+	case importPre == want:
+		return nil
+	case outModDir != "" && apiModDir != outModDir:
+		return fmt.Errorf("%s belongs to module %s in %s, not to the module of %s, so its import path is %s, got %s", api, apiMod, apiModDir, out, want, importPre)
+	}
+	return fmt.Errorf("the import path of %s in module %s is %s, got %s", api, apiMod, want, importPre)
+}
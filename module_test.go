@@ -0,0 +1,44 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckImportPrefix(t *testing.T) {
+	dir := t.TempDir()
+	if modPath, _, err := findModule(dir); err != nil || modPath != "" {
+		t.Skipf("temporary directory is in module %q: %v", modPath, err)
+	}
+	writeFiles(t, dir, map[string]string{
+		"app/go.mod":          "module example.com/app\n",
+		"app/api/get.go":      handlerFile("api", "Get", "app"),
+		"lib/go.mod":          "module example.com/lib // shared handlers\n",
+		"lib/api/get.go":      handlerFile("api", "Get", "lib"),
+		"loose/api/get.go":    handlerFile("api", "Get", "loose"),
+		"loose/routes_gen.go": "package main\n",
+	})
+	for _, tc := range []struct {
+		name, api, out, importPre string
+		// err is a substring of the error, "" for none
+		err string
+	}{
+		{"same module", "app/api", "app/routes_gen.go", "example.com/app/api", ""},
+		{"same module, wrong prefix", "app/api", "app/routes_gen.go", "example.com/app", "the import path of " + filepath.Join(dir, "app/api") + " in module example.com/app is example.com/app/api, got example.com/app"},
+		{"sibling module", "lib/api", "app/routes_gen.go", "example.com/lib/api", ""},
+		{"sibling module, wrong prefix", "lib/api", "app/routes_gen.go", "example.com/app/api", "belongs to module example.com/lib in " + filepath.Join(dir, "lib") + ", not to the module of"},
+		{"no module", "loose/api", "app/routes_gen.go", "example.com/app/api", "belongs to no module"},
+		{"no modules at all", "loose/api", "loose/routes_gen.go", "example.com/anything", ""},
+	} {
+		api := filepath.Join(dir, filepath.FromSlash(tc.api))
+		out := filepath.Join(dir, filepath.FromSlash(tc.out))
+		err := checkImportPrefix(api, out, tc.importPre)
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("%s: %v", tc.name, err)
+		case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+			t.Errorf("%s: got error %v, want one containing %q", tc.name, err, tc.err)
+		}
+	}
+}
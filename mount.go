@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"maps"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// mount is a separately built http.Handler, such as a debug mux or an admin
+// UI, served at a prefix of the router, from -mount.
+type mount struct {
+	Prefix string
+	// Func is the call target returning the handler, e.g. debugHandler or
+	// adminMount.Handler.
+	Func string
+	// Path is the import path of the package declaring Func, or empty for
+	// a function of the generated package.
+	Path  string
+	Alias string
+}
+
+// parseMounts parses a -mount value mapping static prefixes to functions
+// returning the handler mounted there, given as Func for the generated
+// package or import/path.Func. Each package is imported once, as its last
+// path element followed by Mount.
+func parseMounts(value string) ([]mount, error) {
+	var raw map[string]string
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		return nil, err
+	}
+
+	var mounts []mount
+	aliases := make(map[string]string)
+	taken := make(map[string]bool)
+	for _, from := range slices.Sorted(maps.Keys(raw)) {
+		prefix := strings.TrimSuffix(from, "/")
+		if !strings.HasPrefix(prefix, "/") || strings.ContainsAny(prefix, "{}") {
+			return nil, fmt.Errorf("mount prefix must be a static path below / such as /debug, got %q", from)
+		}
+		for _, m := range mounts {
+			if strings.HasPrefix(prefix+"/", m.Prefix+"/") {
+				return nil, fmt.Errorf("mount prefix %s is under %s, mount one handler at each", prefix, m.Prefix)
+			}
+		}
+
+		m := mount{Prefix: prefix}
+		target := raw[from]
+		i := strings.LastIndex(target, ".")
+		switch {
+		case i < 0 && token.IsIdentifier(target):
+			m.Func = target
+		case i > strings.LastIndex(target, "/") && token.IsIdentifier(target[i+1:]) && token.IsExported(target[i+1:]):
+			m.Path = target[:i]
+			alias, ok := aliases[m.Path]
+			if !ok {
+				base := sanitizeIdent(path.Base(m.Path)) + "Mount"
+				alias = base
+				for n := 2; taken[alias]; n++ {
+					alias = base + strconv.Itoa(n)
+				}
+				aliases[m.Path], taken[alias] = alias, true
+			}
+			m.Alias, m.Func = alias, alias+"."+target[i+1:]
+		default:
+			return nil, fmt.Errorf("mount %s: want a function name such as debugHandler or an import path and exported function such as yourmodule/admin.Handler, got %q", prefix, target)
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts, nil
+}
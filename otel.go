@@ -0,0 +1,66 @@
+package main
+
+// otelImports are the OpenTelemetry packages used by otelTemplate, imported
+// under these aliases. They are never imported without -otel, so other
+// projects need no dependency on OpenTelemetry.
+var otelImports = []importEntry{
+	{Alias: "otel", Path: "go.opentelemetry.io/otel"},
+	{Alias: "otelattribute", Path: "go.opentelemetry.io/otel/attribute"},
+	{Alias: "otelcodes", Path: "go.opentelemetry.io/otel/codes"},
+	{Alias: "otelpropagation", Path: "go.opentelemetry.io/otel/propagation"},
+	{Alias: "oteltrace", Path: "go.opentelemetry.io/otel/trace"},
+}
+
+const otelTemplate = `
+// otelSpan starts a server span named after the route around each request
+// to next, continuing a trace propagated in the request headers. The span
+// records the route and response status and is an error for 5xx responses.
+// An empty method, as for fallback routes, names spans after the request's
+func otelSpan(method, route string, next http.Handler) http.Handler {
+	tracer := otel.Tracer("github.com/aquaticcalf/fsrouter")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := method + " " + route
+		if method == "" {
+			name = r.Method + " " + route
+		}
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), otelpropagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, name, oteltrace.WithSpanKind(oteltrace.SpanKindServer), oteltrace.WithAttributes(
+			otelattribute.String("http.request.method", r.Method),
+			otelattribute.String("http.route", route),
+			otelattribute.String("url.path", r.URL.Path),
+		))
+		defer span.End()
+		rec := &otelRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+		span.SetAttributes(otelattribute.Int("http.response.status_code", rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(otelcodes.Error, http.StatusText(rec.status))
+		}
+	})
+}
+
+// otelRecorder captures the status code of a response for otelSpan
+type otelRecorder struct {
+	http.ResponseWriter
+	status int
+	wrote  bool
+}
+
+func (w *otelRecorder) WriteHeader(status int) {
+	if !w.wrote {
+		w.status, w.wrote = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *otelRecorder) Write(b []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush
+func (w *otelRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+`
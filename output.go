@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// stdoutPath is the -out value writing the generated router to stdout.
+const stdoutPath = "-"
+
+// outputWriter writes generated files. In check mode it writes nothing and
+// instead records the files whose contents on disk differ from what would
+// be generated, writing a unified diff of each to diff when it is set.
+type outputWriter struct {
+	check bool
+	stale []string
+	diff  io.Writer
+	// nolint is the -nolint value added to every generated file.
+	nolint string
+	// post is the -postProcess command, or nil.
+	post *postProcessor
+}
+
+// write writes data to path, or compares it with path in check mode. The
+// stdoutPath is written to stdout; check mode rejects it beforehand.
+func (w *outputWriter) write(path string, data []byte) error {
+	if w.nolint != "" {
+		data = insertNolint(data, w.nolint)
+	}
+	if w.post != nil {
+		var err error
+		if data, err = w.post.run(path, data); err != nil {
+			return err
+		}
+	}
+	if path == stdoutPath {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if !w.check {
+		return os.WriteFile(path, data, 0o644)
+	}
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err != nil || !bytes.Equal(existing, data) {
+		w.stale = append(w.stale, path)
+		if w.diff != nil {
+			oldName := path
+			if existing == nil {
+				oldName = "/dev/null"
+			}
+			writeUnifiedDiff(w.diff, oldName, path+" (generated)", existing, data)
+		}
+	}
+	return nil
+}
+
+// remove deletes a previously generated file, or records it as stale in
+// check mode.
+func (w *outputWriter) remove(path string) error {
+	if w.check {
+		w.stale = append(w.stale, path)
+		if w.diff != nil {
+			existing, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			writeUnifiedDiff(w.diff, path, "/dev/null", existing, nil)
+		}
+		return nil
+	}
+	return os.Remove(path)
+}
+
+// report prints a progress message about the file at path unless checking.
+func (w *outputWriter) report(path, format string, args ...any) {
+	if !w.check {
+		diag.at(path).infof(format, args...)
+	}
+}
+
+// insertNolint adds a //nolint comment for linters right before the package
+// clause of a generated file, where golangci-lint applies it to the whole
+// file.
+func insertNolint(src []byte, linters string) []byte {
+	i := 0
+	for !bytes.HasPrefix(src[i:], []byte("package ")) {
+		next := bytes.IndexByte(src[i:], '\n')
+		if next < 0 {
+			return src
+		}
+		i += next + 1
+	}
+	out := append([]byte{}, src[:i]...)
+	out = append(out, "//nolint:"+linters+"\n"...)
+	return append(out, src[i:]...)
+}
+
+// checkNolint validates a -nolint value: all or a comma-separated list of
+// linter names.
+func checkNolint(linters string) error {
+	for _, name := range strings.Split(linters, ",") {
+		if name == "" || strings.IndexFunc(name, func(c rune) bool {
+			return !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_')
+		}) >= 0 {
+			return fmt.Errorf("nolint must be all or a comma-separated list of linter names, got %q", linters)
+		}
+	}
+	return nil
+}
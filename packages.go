@@ -0,0 +1,445 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// targets are the values -target accepts, the default first.
+var targets = []string{"file", "packages"}
+
+// registerFileName is the file -target=packages writes into the directory
+// of each top-level group.
+const registerFileName = "register_gen.go"
+
+// packageRegistration is the register_gen.go of a top-level group for
+// -target=packages. Its Register function adds the group's routes and
+// nested groups to the subrouter RegisterRoutes creates for the group, so
+// the main file only imports the group's package.
+type packageRegistration struct {
+	Name       string
+	Path       string
+	Package    string
+	Alias      string
+	ImportPath string
+	Prefix     string
+	Groups     []group
+	Routes     []route
+	// NotAllowed are the methodNotAllowed.go handlers of the nested
+	// groups, imported by the file.
+	NotAllowed []route
+}
+
+const registerTemplate = `// Code generated by fsrouter; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{range .StdImports}}	"{{.}}"
+{{end}}{{if .StdImports}}
+{{end}}{{range .Imports}}{{if .Comment}}
+	// {{.Comment}}
+{{end}}	{{.Alias}} "{{.Path}}"
+{{end}}
+	"github.com/gorilla/mux"
+)
+
+// Register adds the routes of the {{.Name}} directory to r, the subrouter
+// RegisterRoutes creates for {{.Prefix}} with the group's middleware
+func Register(r *mux.Router) {
+{{range .Groups}}	// Route group for {{.Name}}{{if .Doc}}
+	// {{.Doc}}{{end}}
+	{{.Var}} := {{.Parent}}.PathPrefix("{{.Prefix}}").Subrouter()
+{{if .MethodNotAllowed}}	{{.Var}}.MethodNotAllowedHandler = http.HandlerFunc({{.MethodNotAllowed}})
+{{end}}{{$router := .Var}}{{range .Middlewares}}	{{$router}}.Use({{.}})
+{{end}}
+{{end}}{{range .Routes}}{{if .Guard}}	if {{.Guard}} {
+	{{end}}	{{.Router}}.{{if .HandlerExpr}}Handle("{{.SubPath}}", {{.HandlerExpr}}){{else}}HandleFunc("{{.SubPath}}", {{.HandlerFunc}}){{end}}{{if .Method}}.Methods("{{.Method}}"){{end}}{{.Matchers}}
+{{if .Guard}}	}
+{{end}}{{end}}}
+`
+
+// splitPackages moves the nested groups and the routes of each top-level
+// group named in names into the packageRegistration of its directory below
+// root, and marks the top-level group with the alias the main file calls
+// Register through. The -skip variants of groups stay in the main file.
+// Handlers of the group's own package lose their qualifier. Anything else
+// the registration refers to must be qualified, since a handler package
+// cannot import the router's package, which imports it.
+func splitPackages(routes []route, groups []group, names []string, notAllowed []route, root, importPre string) ([]route, []group, []packageRegistration, error) {
+	var regs []*packageRegistration
+	index := make(map[string]*packageRegistration)
+	tops := make(map[string]string)
+
+	var mainGroups []group
+	for _, g := range groups {
+		if g.Parent == "r" && !slices.Contains(names, g.Name) {
+			mainGroups = append(mainGroups, g)
+			continue
+		}
+		if g.Parent == "r" {
+			dir := filepath.Join(root, filepath.FromSlash(g.Name))
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				return nil, nil, nil, fmt.Errorf("the %s group has no directory to write %s into", g.Name, registerFileName)
+			}
+			pkg, err := registerPackage(dir, sanitizeIdent(g.Name))
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			reg := &packageRegistration{
+				Name:       g.Name,
+				Path:       filepath.Join(dir, registerFileName),
+				Package:    pkg,
+				Alias:      sanitizeIdent(g.Name),
+				ImportPath: path.Join(importPre, g.Name),
+				Prefix:     g.Prefix,
+			}
+			regs = append(regs, reg)
+			index[g.Var] = reg
+			tops[g.Var] = g.Var
+			g.Register = reg.Alias
+			mainGroups = append(mainGroups, g)
+			continue
+		}
+		top := tops[g.Parent]
+		tops[g.Var] = top
+		reg := index[top]
+		if err := checkQualified(reg, "the "+g.Name+" group", slices.Concat(g.Middlewares, []string{g.MethodNotAllowed})); err != nil {
+			return nil, nil, nil, err
+		}
+		if g.Parent == top {
+			g.Parent = "r"
+		}
+		if g.MethodNotAllowed != "" {
+			for _, h := range notAllowed {
+				if h.Alias+"."+h.Handler == g.MethodNotAllowed {
+					reg.NotAllowed = append(reg.NotAllowed, h)
+				}
+			}
+		}
+		reg.Groups = append(reg.Groups, g)
+	}
+
+	var mainRoutes []route
+	for _, rt := range routes {
+		top := tops[rt.Router]
+		if top == "" {
+			mainRoutes = append(mainRoutes, rt)
+			continue
+		}
+		reg := index[top]
+		what := rt.methodLabel() + " " + rt.RoutePath
+		switch {
+		case len(rt.Tags) > 0:
+			return nil, nil, nil, fmt.Errorf("%s is tagged, and %s has no tags to check", what, registerFileName)
+		case rt.Receiver != "":
+			return nil, nil, nil, fmt.Errorf("%s is a method of %s, whose struct RegisterRoutes builds", what, rt.Receiver)
+		}
+		if err := checkQualified(reg, what, []string{rt.HandlerExpr, rt.HandlerFunc(), rt.Guard}); err != nil {
+			return nil, nil, nil, err
+		}
+		if rt.Router == top {
+			rt.Router = "r"
+		}
+		if rt.ImportPath == reg.ImportPath {
+			rt.HandlerExpr = unqualify(rt.HandlerExpr, rt.Alias)
+			rt.Alias, rt.ImportPath = "", ""
+		}
+		reg.Routes = append(reg.Routes, rt)
+	}
+
+	var files []packageRegistration
+	for _, reg := range regs {
+		files = append(files, *reg)
+	}
+	if err := checkRegisterCycles(files, root, importPre); err != nil {
+		return nil, nil, nil, err
+	}
+	return mainRoutes, mainGroups, files, nil
+}
+
+// checkRegisterCycles reports a group package that a package it would
+// import for Register imports in turn, directly or through other packages
+// below root. Go rejects the cycle, which the handlers could not have had
+// before the group's package imported them. Imports are read from the
+// files of each package, with the planned register_gen.go files in place
+// of those on disk.
+func checkRegisterCycles(regs []packageRegistration, root, importPre string) error {
+	planned := make(map[string][]string)
+	for _, reg := range regs {
+		for _, rt := range slices.Concat(reg.Routes, reg.NotAllowed) {
+			if rt.ImportPath != "" && rt.ImportPath != reg.ImportPath && !slices.Contains(planned[reg.ImportPath], rt.ImportPath) {
+				planned[reg.ImportPath] = append(planned[reg.ImportPath], rt.ImportPath)
+			}
+		}
+	}
+	imports := func(pkg string) ([]string, error) {
+		deps, err := packageImports(root, importPre, pkg)
+		if err != nil {
+			return nil, err
+		}
+		return append(deps, planned[pkg]...), nil
+	}
+
+	for _, reg := range regs {
+		// chain holds the packages from reg to the one being visited, and
+		// done those whose imports lead nowhere back to reg.
+		done := make(map[string]bool)
+		var visit func(chain []string) ([]string, error)
+		visit = func(chain []string) ([]string, error) {
+			pkg := chain[len(chain)-1]
+			deps, err := imports(pkg)
+			if err != nil {
+				return nil, err
+			}
+			for _, dep := range deps {
+				switch {
+				case dep == reg.ImportPath:
+					return append(chain, dep), nil
+				case done[dep] || slices.Contains(chain, dep):
+					continue
+				}
+				if cycle, err := visit(append(slices.Clip(chain), dep)); cycle != nil || err != nil {
+					return cycle, err
+				}
+			}
+			done[pkg] = true
+			return nil, nil
+		}
+		cycle, err := visit([]string{reg.ImportPath})
+		if err != nil {
+			return err
+		}
+		if cycle != nil {
+			return fmt.Errorf("import cycle: %s would import %s to register its routes, but %s; move what the handlers share out of %s", reg.ImportPath, cycle[1], strings.Join(cycle[1:], ", which imports "), reg.ImportPath)
+		}
+	}
+	return nil
+}
+
+// packageImports returns the imports below importPre of the package with
+// import path pkg, read from its Go files other than tests and
+// register_gen.go. Packages outside root have none.
+func packageImports(root, importPre, pkg string) ([]string, error) {
+	rel, ok := strings.CutPrefix(pkg, importPre)
+	if !ok || rel != "" && !strings.HasPrefix(rel, "/") {
+		return nil, nil
+	}
+	dir := filepath.Join(root, filepath.FromSlash(rel))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var deps []string
+	for _, e := range entries {
+		if e.IsDir() || e.Name() == registerFileName || !strings.HasSuffix(e.Name(), ".go") || strings.HasSuffix(e.Name(), "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), filepath.Join(dir, e.Name()), nil, parser.ImportsOnly)
+		if err != nil {
+			return nil, err
+		}
+		for _, spec := range file.Imports {
+			imp, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				return nil, err
+			}
+			if (imp == importPre || strings.HasPrefix(imp, importPre+"/")) && !slices.Contains(deps, imp) {
+				deps = append(deps, imp)
+			}
+		}
+	}
+	return deps, nil
+}
+
+// registerPackage returns the package name of the Go files in dir, or name
+// when it has none, and reports a Register function they declare already.
+func registerPackage(dir, name string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	pkg := ""
+	for _, e := range entries {
+		if e.IsDir() || e.Name() == registerFileName || !strings.HasSuffix(e.Name(), ".go") || strings.HasSuffix(e.Name(), "_test.go") {
+			continue
+		}
+		p := filepath.Join(dir, e.Name())
+		file, err := parser.ParseFile(token.NewFileSet(), p, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return "", err
+		}
+		pkg = file.Name.Name
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == "Register" {
+				return "", fmt.Errorf("%s declares Register, which %s would declare too", p, registerFileName)
+			}
+		}
+	}
+	if pkg == "" {
+		pkg = name
+	}
+	return pkg, nil
+}
+
+// checkQualified reports an expression of what that refers to an
+// unqualified name. Such names are declared by the router's package, for
+// example the generated produces wrapper or a middleware listed without
+// the middleware package, and the group's package cannot import it.
+func checkQualified(reg *packageRegistration, what string, exprs []string) error {
+	for _, s := range exprs {
+		if s == "" {
+			continue
+		}
+		expr, err := parser.ParseExpr(s)
+		if err != nil {
+			return err
+		}
+		var local string
+		ast.Inspect(expr, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.SelectorExpr:
+				if _, ok := n.X.(*ast.Ident); ok {
+					return false
+				}
+			case *ast.Ident:
+				if n.Name != "nil" && n.Name != "true" && n.Name != "false" && local == "" {
+					local = n.Name
+				}
+			}
+			return local == ""
+		})
+		if local != "" {
+			return fmt.Errorf("%s needs %s from the router's package, which the %s package cannot import", what, local, reg.Package)
+		}
+	}
+	return nil
+}
+
+// unqualify drops the alias qualifier from the names expr refers to, for
+// handlers registered from within their own package.
+func unqualify(expr, alias string) string {
+	if expr == "" {
+		return ""
+	}
+	type tok struct {
+		off int
+		tok token.Token
+		lit string
+	}
+	var toks []tok
+	fset := token.NewFileSet()
+	var s scanner.Scanner
+	s.Init(fset.AddFile("", fset.Base(), len(expr)), []byte(expr), nil, 0)
+	for {
+		pos, t, lit := s.Scan()
+		if t == token.EOF {
+			break
+		}
+		toks = append(toks, tok{fset.Position(pos).Offset, t, lit})
+	}
+	var b strings.Builder
+	last := 0
+	for i, t := range toks {
+		if t.tok == token.IDENT && t.lit == alias && i+1 < len(toks) && toks[i+1].tok == token.PERIOD && (i == 0 || toks[i-1].tok != token.PERIOD) {
+			b.WriteString(expr[last:t.off])
+			last = toks[i+1].off + 1
+		}
+	}
+	b.WriteString(expr[last:])
+	return b.String()
+}
+
+// writeRegisterFile generates the register_gen.go of a top-level group,
+// importing only the packages it refers to.
+func writeRegisterFile(w *outputWriter, reg packageRegistration, middlewarePkg string, groupImports bool) error {
+	var emitted []string
+	for _, g := range reg.Groups {
+		emitted = append(emitted, g.Middlewares...)
+		if g.MethodNotAllowed != "" {
+			emitted = append(emitted, "http.HandlerFunc("+g.MethodNotAllowed+")")
+		}
+	}
+	for _, rt := range reg.Routes {
+		emitted = append(emitted, rt.HandlerExpr, rt.Guard)
+	}
+
+	var stdImports []string
+	if usesPackage("http", emitted) {
+		stdImports = append(stdImports, "net/http")
+	}
+	if usesPackage("time", emitted) {
+		stdImports = append(stdImports, "time")
+	}
+	if usesPackage("os", emitted) {
+		stdImports = append(stdImports, "os")
+	}
+	imports := handlerImports(slices.Concat(reg.Routes, reg.NotAllowed), groupImports)
+	if middlewarePkg != "" && usesPackage("middleware", emitted) {
+		imports = append(imports, packageImport(middlewarePkg, "middleware", groupImports))
+	}
+
+	var buf bytes.Buffer
+	tmpl := template.Must(template.New("register").Parse(registerTemplate))
+	err := tmpl.Execute(&buf, struct {
+		packageRegistration
+		StdImports []string
+		Imports    []importEntry
+	}{
+		packageRegistration: reg,
+		StdImports:          stdImports,
+		Imports:             imports,
+	})
+	if err != nil {
+		return err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("formatting generated %s: %w", reg.Path, err)
+	}
+	return w.write(reg.Path, src)
+}
+
+// removeStaleRegisterFiles deletes the register_gen.go files of top-level
+// directories below root that -target=packages no longer generates, since
+// they register routes the main file registers itself again. Files not
+// generated by fsrouter are left alone.
+func removeStaleRegisterFiles(w *outputWriter, root string, current []packageRegistration) error {
+	matches, err := filepath.Glob(filepath.Join(root, "*", registerFileName))
+	if err != nil {
+		return err
+	}
+	for _, m := range matches {
+		if slices.ContainsFunc(current, func(reg packageRegistration) bool { return reg.Path == m }) {
+			continue
+		}
+		data, err := os.ReadFile(m)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if bytes.HasPrefix(data, []byte("// Code generated by fsrouter; DO NOT EDIT.")) && bytes.Contains(data, []byte("func Register(r *mux.Router)")) {
+			if err := w.remove(m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// typedParam is a [name:type] directory segment, whose value is parsed
+// before the handler runs.
+type typedParam struct {
+	Name string
+	Type string
+}
+
+// paramTypes maps the types a [name:type] segment may declare to the
+// strconv call parsing the parameter value and the OpenAPI schema type.
+var paramTypes = map[string]struct {
+	parse   string
+	openAPI string
+}{
+	"int":     {"strconv.Atoi(%s)", "integer"},
+	"int64":   {"strconv.ParseInt(%s, 10, 64)", "integer"},
+	"uint64":  {"strconv.ParseUint(%s, 10, 64)", "integer"},
+	"float64": {"strconv.ParseFloat(%s, 64)", "number"},
+	"bool":    {"strconv.ParseBool(%s)", "boolean"},
+}
+
+// paramParserImports are the standard library packages used by
+// paramParserTemplate.
+var paramParserImports = []string{"context", "strconv"}
+
+const paramParserTemplate = `{{range .ParamParsers}}
+// {{.Func}} parses the typed path parameters of {{.Route}}
+// into the request context, answering 400 Bad Request when one does not parse
+func {{.Func}}(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+{{range $i, $p := .Params}}		p{{$i}}, err := {{$p.Parse}}
+		if err != nil {
+			http.Error(w, {{printf "%q" (print "invalid " $p.Name ", want " $p.Type)}}, http.StatusBadRequest)
+			return
+		}
+		ctx = context.WithValue(ctx, {{printf "%q" $p.Name}}, p{{$i}})
+{{end}}		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+{{end}}`
+
+// paramParser is a generated middleware parsing the typed parameters of one
+// directory's routes.
+type paramParser struct {
+	Func   string
+	Route  string
+	Params []paramParse
+}
+
+// paramParse is one typed parameter of a paramParser with the expression
+// parsing its value.
+type paramParse struct {
+	Name  string
+	Type  string
+	Parse string
+}
+
+// parseParamSegment splits a directory segment such as [userId:int] into
+// its parameter name and type. ok is false for segments that are not
+// parameters; the type is empty for untyped ones.
+func parseParamSegment(seg string) (name, typ string, ok bool) {
+	if !strings.HasPrefix(seg, "[") || !strings.HasSuffix(seg, "]") {
+		return "", "", false
+	}
+	name, typ, _ = strings.Cut(seg[1:len(seg)-1], ":")
+	return name, typ, true
+}
+
+// dirParams returns the typed parameters of a slash-separated directory,
+// outermost first.
+func dirParams(dir string) ([]typedParam, error) {
+	var params []typedParam
+	for _, seg := range strings.Split(dir, "/") {
+		name, typ, ok := parseParamSegment(seg)
+		if param, _, all := catchAll(name); ok && all {
+			switch {
+			case param == "":
+				return nil, fmt.Errorf("catch-all %s needs a parameter name, such as [...path]", seg)
+			case typ != "":
+				return nil, fmt.Errorf("catch-all parameter %s cannot have a type, it matches the rest of the path", param)
+			}
+		}
+		if !ok || typ == "" {
+			continue
+		}
+		if _, known := paramTypes[typ]; !known {
+			return nil, fmt.Errorf("parameter %s has unknown type %q, want one of %s", name, typ, strings.Join(slices.Sorted(maps.Keys(paramTypes)), ", "))
+		}
+		params = append(params, typedParam{Name: name, Type: typ})
+	}
+	return params, nil
+}
+
+// paramParsers returns one parser per directory with typed parameters,
+// reading parameter values the way backend exposes them.
+func paramParsers(routes []route, backend string) []paramParser {
+	var parsers []paramParser
+	for _, rt := range routes {
+		if rt.ParamParser == "" || slices.ContainsFunc(parsers, func(p paramParser) bool { return p.Func == rt.ParamParser }) {
+			continue
+		}
+		p := paramParser{Func: rt.ParamParser, Route: rt.RoutePath}
+		for _, tp := range rt.Params {
+			value := "mux.Vars(r)[" + strconv.Quote(tp.Name) + "]"
+			if backend == "echo" {
+				value = "r.PathValue(" + strconv.Quote(tp.Name) + ")"
+			}
+			p.Params = append(p.Params, paramParse{tp.Name, tp.Type, fmt.Sprintf(paramTypes[tp.Type].parse, value)})
+		}
+		parsers = append(parsers, p)
+	}
+	return parsers
+}
+
+// renameParam registers the {from} segment of rt's path as {to}, for
+// //fsrouter:paramName. A renamed typed parameter is parsed under its new
+// name, so the route gets a parser of its own rather than its directory's.
+func renameParam(rt *route, from, to string) error {
+	segs := strings.Split(rt.RoutePath, "/")
+	i := slices.Index(segs, "{"+from+"}")
+	if i < 0 {
+		return fmt.Errorf("%s is not a parameter of %s", from, rt.RoutePath)
+	}
+	if slices.Contains(segs, "{"+to+"}") {
+		return fmt.Errorf("%s already has a parameter %s", rt.RoutePath, to)
+	}
+	segs[i] = "{" + to + "}"
+	rt.RoutePath = strings.Join(segs, "/")
+	for j := range rt.Params {
+		if rt.Params[j].Name == from {
+			rt.Params[j].Name = to
+			rt.ParamParser = rt.Alias + rt.Handler + "Params"
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// pathCases are the values -pathCase accepts, the default first.
+var pathCases = []string{"preserve", "kebab", "snake", "lower"}
+
+// casePathSegment converts a directory name to the URL segment it
+// contributes under pathCase. Kebab and snake case split words at existing
+// dashes and underscores and before an uppercase letter that starts a word,
+// so "userProfiles" becomes "user-profiles" and "APIKeys" "api-keys". Other
+// characters are kept within their word.
+func casePathSegment(seg, pathCase string) string {
+	var sep rune
+	switch pathCase {
+	case "lower":
+		return strings.ToLower(seg)
+	case "kebab":
+		sep = '-'
+	case "snake":
+		sep = '_'
+	default:
+		return seg
+	}
+
+	runes := []rune(seg)
+	var b strings.Builder
+	for i, c := range runes {
+		if c == '-' || c == '_' {
+			b.WriteRune(sep)
+			continue
+		}
+		if unicode.IsUpper(c) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && nextLower {
+				b.WriteRune(sep)
+			}
+		}
+		b.WriteRune(unicode.ToLower(c))
+	}
+	return b.String()
+}
+
+// checkPathCaseCollisions reports two handler directories whose names
+// convert to the same path under -pathCase, such as userProfiles and
+// user-profiles. paths holds the _path and -pathCase segments of the tree.
+func checkPathCaseCollisions(routes []route, paths map[string]string, root, pathCase string) error {
+	dirs := make(map[string]string)
+	for _, rt := range routes {
+		p := routePath(rt.Dir, paths)
+		if other, ok := dirs[p]; ok && other != rt.Dir {
+			return fmt.Errorf("%s and %s both serve %s with -pathCase=%s, rename one or give it a %s file",
+				filepath.Join(root, filepath.FromSlash(other)), filepath.Join(root, filepath.FromSlash(rt.Dir)), p, pathCase, pathFileName)
+		}
+		dirs[p] = rt.Dir
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// pathPolicies are the values -pathPolicy accepts besides the empty
+// default, which checks nothing.
+var pathPolicies = []string{"lowercase", "kebab-lowercase", "snake-lowercase"}
+
+// pathViolation is a URL segment, or a trailing slash, breaking the
+// -pathPolicy. Source is the slash-separated directory or handler file
+// below the api directory that contributes it.
+type pathViolation struct {
+	Source  string
+	Path    string
+	Segment string
+}
+
+// checkPathPolicy returns the violations of policy in the paths of routes,
+// each segment reported once per directory or file. A directory's segment
+// is its name, or its _path or -pathCase segments from paths; the segments
+// a Paths variable adds are the handler file's. {param} segments do not
+// appear in URLs and are not checked.
+func checkPathPolicy(routes []route, paths map[string]string, policy string) []pathViolation {
+	var violations []pathViolation
+	seen := make(map[[2]string]bool)
+	report := func(v pathViolation) {
+		if key := [2]string{v.Source, v.Segment}; !seen[key] {
+			seen[key] = true
+			violations = append(violations, v)
+		}
+	}
+
+	for _, rt := range routes {
+		n := 0
+		dirs := strings.Split(rt.Dir, "/")
+		for i, name := range dirs {
+			dir := strings.Join(dirs[:i+1], "/")
+			override, ok := paths[dir]
+			switch {
+			case ok:
+			case name == "" || name == "index":
+				continue
+			default:
+				override = name
+			}
+			for _, seg := range strings.Split(override, "/") {
+				if !segmentFollows(seg, policy) {
+					report(pathViolation{Source: dir, Path: rt.RoutePath, Segment: seg})
+				}
+				n++
+			}
+		}
+
+		// Segments past the directory's come from a Paths variable.
+		segs := strings.Split(strings.Trim(rt.RoutePath, "/"), "/")
+		for _, seg := range segs[min(n, len(segs)):] {
+			if !segmentFollows(seg, policy) {
+				report(pathViolation{Source: rt.File, Path: rt.RoutePath, Segment: seg})
+			}
+		}
+		if rt.RoutePath != "/" && strings.HasSuffix(rt.RoutePath, "/") {
+			report(pathViolation{Source: rt.File, Path: rt.RoutePath})
+		}
+	}
+	return violations
+}
+
+// segmentFollows reports whether a URL segment follows policy. Dots may
+// separate the parts of a segment, as in openapi.json, and {param}
+// segments always follow it.
+func segmentFollows(seg, policy string) bool {
+	if _, _, ok := parseParamSegment(seg); ok || seg == "" || strings.HasPrefix(seg, "{") {
+		return true
+	}
+	sep := "-"
+	switch policy {
+	case "lowercase":
+		return strings.IndexFunc(seg, unicode.IsUpper) < 0
+	case "snake-lowercase":
+		sep = "_"
+	}
+	for _, part := range strings.Split(seg, ".") {
+		for _, word := range strings.Split(part, sep) {
+			if word == "" || strings.IndexFunc(word, notLowerAlnum) >= 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// notLowerAlnum reports whether c is anything but a-z or 0-9.
+func notLowerAlnum(c rune) bool {
+	return (c < 'a' || c > 'z') && (c < '0' || c > '9')
+}
+
+// describe returns the warning for the violation of policy.
+func (v pathViolation) describe(policy string) string {
+	if v.Segment == "" {
+		return fmt.Sprintf("path %s ends with a slash, which -pathPolicy=%s does not allow", v.Path, policy)
+	}
+	return fmt.Sprintf("segment %q of %s does not follow -pathPolicy=%s", v.Segment, v.Path, policy)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// handlerPaths returns the paths listed by a handler file's Paths variable,
+// e.g. var Paths = []string{"/", "/index.html"}, or nil if it declares none.
+// Files of one directory share a package, so a file may name the variable
+// after its handler instead, e.g. GetPaths, when several of them list paths.
+// The paths are relative to the handler's directory, with "/" being the
+// directory itself.
+func handlerPaths(fset *token.FileSet, file *ast.File, handler string) ([]string, error) {
+	var paths []string
+	found := ""
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				if name.Name != "Paths" && name.Name != handler+"Paths" {
+					continue
+				}
+				pos := fset.Position(name.Pos())
+				if found != "" {
+					return nil, fmt.Errorf("%s: %s and %s both list paths, declare only one", pos, found, name.Name)
+				}
+				found = name.Name
+				if i >= len(vs.Values) {
+					return nil, fmt.Errorf("%s: %s must be initialized with a []string literal", pos, name.Name)
+				}
+				list, err := stringList(vs.Values[i])
+				if err != nil {
+					return nil, fmt.Errorf("%s: %s %v", pos, name.Name, err)
+				}
+				if len(list) == 0 {
+					return nil, fmt.Errorf("%s: %s must list at least one path", pos, name.Name)
+				}
+				for _, p := range list {
+					if !strings.HasPrefix(p, "/") || strings.ContainsAny(p, " \t\n") {
+						return nil, fmt.Errorf("%s: invalid path %q in %s, want a path such as /index.html", pos, p, name.Name)
+					}
+					if slices.Contains(paths, p) {
+						return nil, fmt.Errorf("%s: %s lists %s twice", pos, name.Name, p)
+					}
+					paths = append(paths, p)
+				}
+			}
+		}
+	}
+	return paths, nil
+}
+
+// stringList returns the elements of a []string composite literal whose
+// elements are all string literals.
+func stringList(expr ast.Expr) ([]string, error) {
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		return nil, fmt.Errorf("must be a []string literal")
+	}
+	if arr, ok := lit.Type.(*ast.ArrayType); !ok || arr.Len != nil || !isIdent(arr.Elt, "string") {
+		return nil, fmt.Errorf("must be a []string literal")
+	}
+	var list []string
+	for _, elt := range lit.Elts {
+		bl, ok := elt.(*ast.BasicLit)
+		if !ok || bl.Kind != token.STRING {
+			return nil, fmt.Errorf("may only contain string literals")
+		}
+		s, err := strconv.Unquote(bl.Value)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// isIdent reports whether expr is the identifier name.
+func isIdent(expr ast.Expr, name string) bool {
+	id, ok := expr.(*ast.Ident)
+	return ok && id.Name == name
+}
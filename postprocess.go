@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// postProcessor is the -postProcess command run on every generated file.
+type postProcessor struct {
+	// name is the command as given, path the executable it resolved to.
+	name string
+	path string
+	args []string
+}
+
+// parsePostProcess splits a -postProcess value into the command and its
+// leading arguments and resolves the command, so a missing executable is
+// reported before anything is generated. Arguments are split on spaces
+// without shell quoting.
+func parsePostProcess(value string) (*postProcessor, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no command given")
+	}
+	path, err := exec.LookPath(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("cannot run %s: %w", fields[0], err)
+	}
+	return &postProcessor{name: fields[0], path: path, args: fields[1:]}, nil
+}
+
+// run passes data, generated for path, through the command. The command
+// gets the path of a temporary copy next to path as its last argument and
+// rewrites it in place, so check mode compares processed output without
+// touching path. Its output goes to stderr, keeping -out=- clean.
+func (p *postProcessor) run(path string, data []byte) ([]byte, error) {
+	dir, pattern := filepath.Dir(path), "."+filepath.Base(path)+".*"+filepath.Ext(path)
+	if path == stdoutPath {
+		dir, pattern = "", "fsrouter-*.go"
+	}
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(p.path, slices.Concat(p.args, []string{f.Name()})...)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("postProcess %s failed for %s: %w", p.name, path, err)
+	}
+	return os.ReadFile(f.Name())
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// dynamicLastOrder compares two route paths for -dynamicLast: at the first
+// segment where one path has a static segment and the other a {param}, the
+// static one comes first. Paths whose segments are of the same kinds compare
+// equal, so a stable sort keeps their walk order. A path that is a prefix
+// of the other in segment kinds comes first, keeping parent groups before
+// their children.
+func dynamicLastOrder(a, b string) int {
+	return slices.Compare(segmentKinds(a), segmentKinds(b))
+}
+
+// segmentKinds returns 0 for each static segment of a route path, 1 for
+// each {param} segment and 2 for each catch-all, which thus comes after the
+// {param}s beside it.
+func segmentKinds(path string) []int {
+	var kinds []int
+	for _, seg := range strings.Split(strings.Trim(path, "/"), "/") {
+		if seg == "" {
+			continue
+		}
+		switch {
+		case isCatchAllSegment(seg):
+			kinds = append(kinds, 2)
+		case strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}"):
+			kinds = append(kinds, 1)
+		default:
+			kinds = append(kinds, 0)
+		}
+	}
+	return kinds
+}
+
+// orderGroups stably moves the groups listed in -groupOrder, with their
+// nested groups, ahead of the others in the listed order. Entries must name
+// top-level groups; nested ones keep following their parent.
+func orderGroups(names, order []string) error {
+	rank := make(map[string]int)
+	for i, name := range order {
+		if _, dup := rank[name]; dup {
+			return fmt.Errorf("%s is listed twice", name)
+		}
+		if strings.Contains(name, "/") || !slices.Contains(names, name) {
+			return fmt.Errorf("%s is not a top-level route group", name)
+		}
+		rank[name] = i
+	}
+	rankOf := func(name string) int {
+		top, _, _ := strings.Cut(name, "/")
+		if r, ok := rank[top]; ok {
+			return r
+		}
+		return len(order)
+	}
+	slices.SortStableFunc(names, func(a, b string) int { return rankOf(a) - rankOf(b) })
+	return nil
+}
+
+// matchersFirst moves the routes matching //fsrouter:header or
+// //fsrouter:scheme directives ahead of the routes without them for the same
+// method and path, keeping the positions the routes of each method and path
+// occupy. mux uses the first route matching, so the route without matchers
+// then serves the other requests.
+func matchersFirst(routes []route) {
+	positions := make(map[string][]int)
+	var keys []string
+	for i, rt := range routes {
+		key := rt.Method + " " + rt.RoutePath
+		if positions[key] == nil {
+			keys = append(keys, key)
+		}
+		positions[key] = append(positions[key], i)
+	}
+	for _, key := range keys {
+		idx := positions[key]
+		if len(idx) < 2 {
+			continue
+		}
+		same := make([]route, len(idx))
+		for j, i := range idx {
+			same[j] = routes[i]
+		}
+		slices.SortStableFunc(same, func(a, b route) int {
+			return min(len(b.Headers)+len(b.Schemes), 1) - min(len(a.Headers)+len(a.Schemes), 1)
+		})
+		for j, i := range idx {
+			routes[i] = same[j]
+		}
+	}
+}
+
+// routeOrders are the values -routeOrder accepts, the default first.
+var routeOrders = []string{"fs", "alpha", "rest"}
+
+// restMethods are the methods in the order -routeOrder=rest lists them for
+// a path. Other methods follow, and fallbacks come last.
+var restMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// orderRoutes reorders routes for -routeOrder, alpha sorting them by path
+// and method and rest by resource: each collection's GET and POST, then its
+// items' GET, PUT, PATCH and DELETE. fs keeps the order of the directory
+// walk. A route only moves past routes that cannot match the same request,
+// so the routes mux tries first for a request stay the same.
+func orderRoutes(routes []route, order string) {
+	var compare func(a, b route) int
+	switch order {
+	case "alpha":
+		compare = func(a, b route) int {
+			return cmp.Or(strings.Compare(a.RoutePath, b.RoutePath), strings.Compare(a.Method, b.Method))
+		}
+	case "rest":
+		compare = restOrder
+	default:
+		return
+	}
+	for i := 1; i < len(routes); i++ {
+		for j := i; j > 0 && compare(routes[j], routes[j-1]) < 0 && !routesOverlap(routes[j], routes[j-1]); j-- {
+			routes[j], routes[j-1] = routes[j-1], routes[j]
+		}
+	}
+}
+
+// restOrder compares two routes for -routeOrder=rest: by path, segment by
+// segment with static segments before {param}s, then by method. A
+// collection thus precedes its items, and its static children such as
+// /users/settings come between them, as -dynamicLast registers them.
+func restOrder(a, b route) int {
+	as := strings.Split(strings.Trim(a.RoutePath, "/"), "/")
+	bs := strings.Split(strings.Trim(b.RoutePath, "/"), "/")
+	for i := range min(len(as), len(bs)) {
+		aParam := strings.HasPrefix(as[i], "{")
+		bParam := strings.HasPrefix(bs[i], "{")
+		switch {
+		case aParam != bParam && aParam:
+			return 1
+		case aParam != bParam:
+			return -1
+		case !aParam && as[i] != bs[i]:
+			return strings.Compare(as[i], bs[i])
+		}
+	}
+	return cmp.Or(len(as)-len(bs), restMethodRank(a.Method)-restMethodRank(b.Method))
+}
+
+// restMethodRank returns the position of a method in restMethods.
+func restMethodRank(method string) int {
+	if method == "" {
+		return len(restMethods) + 1
+	}
+	if i := slices.Index(restMethods, method); i >= 0 {
+		return i
+	}
+	return len(restMethods)
+}
+
+// routesOverlap reports whether some request could match both routes: their
+// methods match, or one is a fallback, and their paths agree on every
+// static segment. A {param} with a pattern may span segments, so paths
+// with one always overlap when their lengths differ.
+func routesOverlap(a, b route) bool {
+	if a.Method != b.Method && a.Method != "" && b.Method != "" {
+		return false
+	}
+	as := strings.Split(strings.Trim(a.RoutePath, "/"), "/")
+	bs := strings.Split(strings.Trim(b.RoutePath, "/"), "/")
+	if len(as) != len(bs) {
+		return strings.Contains(a.RoutePath+b.RoutePath, ":")
+	}
+	for i := range as {
+		aParam := strings.HasPrefix(as[i], "{") && strings.HasSuffix(as[i], "}")
+		bParam := strings.HasPrefix(bs[i], "{") && strings.HasSuffix(bs[i], "}")
+		if !aParam && !bParam && as[i] != bs[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path"
+	"path/filepath"
+)
+
+// receiverConstructor is the function a -handlerReceiver package builds its
+// handler struct with.
+const receiverConstructor = "New"
+
+// receiverVar is the variable RegisterRoutes holds a package's handler
+// struct in.
+type receiverVar struct {
+	Var   string
+	Alias string
+}
+
+// receiverType returns the name of the exported type a method is declared
+// on, either T or *T, or "" for functions and other receivers.
+func receiverType(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) != 1 {
+		return ""
+	}
+	typ := fn.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	if id, ok := typ.(*ast.Ident); ok && id.IsExported() {
+		return id.Name
+	}
+	return ""
+}
+
+// checkReceivers verifies that the method handlers of each directory belong
+// to a single type and that its package declares func New() *T or
+// func New() T to build it.
+func checkReceivers(fsys fs.FS, root string, routes []route) error {
+	types := make(map[string]string)
+	var dirs []string
+	for _, rt := range routes {
+		if rt.Receiver == "" {
+			continue
+		}
+		display := filepath.Join(root, filepath.FromSlash(rt.Dir))
+		switch prev, ok := types[rt.Dir]; {
+		case !ok:
+			types[rt.Dir] = rt.Receiver
+			dirs = append(dirs, rt.Dir)
+		case prev != rt.Receiver:
+			return fmt.Errorf("%s: handlers are methods of both %s and %s, use a single type", display, prev, rt.Receiver)
+		}
+	}
+
+	for _, dir := range dirs {
+		found, err := hasConstructor(fsys, dir, types[dir])
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("%s: handlers are methods of %s, but the package has no func %s() *%s", filepath.Join(root, filepath.FromSlash(dir)), types[dir], receiverConstructor, types[dir])
+		}
+	}
+	return nil
+}
+
+// hasConstructor reports whether a Go file in dir declares New returning typ
+// or a pointer to it, without parameters.
+func hasConstructor(fsys fs.FS, dir, typ string) (bool, error) {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.go"))
+	if err != nil {
+		return false, err
+	}
+	for _, p := range files {
+		src, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return false, err
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), p, src, parser.SkipObjectResolution)
+		if err != nil {
+			return false, err
+		}
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || fn.Name.Name != receiverConstructor || fn.Type.TypeParams != nil {
+				continue
+			}
+			results := fieldTypes(fn.Type.Results)
+			if len(fieldTypes(fn.Type.Params)) != 0 || len(results) != 1 {
+				continue
+			}
+			result := results[0]
+			if star, ok := result.(*ast.StarExpr); ok {
+				result = star.X
+			}
+			if isIdent(result, typ) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// receiverVars returns the handler struct variables the routes refer to,
+// one per package in route order.
+func receiverVars(routes []route) []receiverVar {
+	var vars []receiverVar
+	seen := make(map[string]bool)
+	for _, rt := range routes {
+		if rt.Receiver != "" && !seen[rt.Alias] {
+			seen[rt.Alias] = true
+			vars = append(vars, receiverVar{Var: rt.receiverVar(), Alias: rt.Alias})
+		}
+	}
+	return vars
+}
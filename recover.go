@@ -0,0 +1,29 @@
+package main
+
+// recoverMiddlewareName is the middleware generated unless -noRecover is set.
+const recoverMiddlewareName = "recoverMiddleware"
+
+// recoverImports are the standard library packages used by recoverTemplate.
+var recoverImports = []string{"log", "runtime/debug"}
+
+const recoverTemplate = `
+// recoverMiddleware turns a panic in a handler or inner middleware into a
+// 500 response, logging the panic with its stack trace
+func recoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				// Deliberate aborts stay silent, as with net/http itself.
+				panic(err)
+			}
+			log.Printf("panic serving %s %s: %v\n%s", r.Method, r.URL.Path, err, debug.Stack())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+`
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// registryTemplate replaces the router with the route list alone for
+// -emitRegistryOnly, leaving dispatch to the caller.
+const registryTemplate = `// Code generated by fsrouter; DO NOT EDIT.
+package {{.Package}}
+
+import (
+{{range .StdImports}}	"{{.}}"
+{{end}}{{range .Imports}}{{if .Comment}}
+	// {{.Comment}}
+{{end}}	{{.Alias}} "{{.Path}}"
+{{end}})
+
+// RouteInfo describes a route discovered by fsrouter, for servers doing
+// their own dispatch
+type RouteInfo struct {
+	// Method is the HTTP method, empty for a fallback answering the others,
+	// and Path the route path, whose {param} segments are named in Params,
+	// outermost first
+	Method string
+	Path   string
+	Params []string
+	// Headers are the values of //fsrouter:header directives the request
+	// must carry, an empty value matching any
+	Headers map[string]string
+	// Schemes are the URL schemes of //fsrouter:scheme or -scheme the
+	// request must use, empty for any
+	Schemes []string
+	// Group is the route group, empty at the root
+	Group string
+	// Tags are the //fsrouter:tag tags and EnabledIf the environment
+	// variable of //fsrouter:enabledIf, which the generated router decides
+	// registration by
+	Tags      []string
+	EnabledIf string
+	// Handler is the handler itself, without middleware or the wrapping of
+	// other directives
+	Handler http.HandlerFunc
+}
+
+// Routes lists the discovered routes in the order the generated router
+// would register them
+var Routes = []RouteInfo{
+{{range .Routes}}	{{registryEntry .}},
+{{end}}}
+{{if .JSONHandler}}{{template "jsonHandler" .}}{{end}}`
+
+// registryFlags are the flags that still apply with -emitRegistryOnly:
+// those deciding what is discovered and the companion files built from the
+// discovered routes. The others configure the router, which is not
+// generated.
+var registryFlags = []string{
+	"api", "out", "pkg", "importPREFIX", "middleware", "emitRegistryOnly",
+	"handlerStyle", "jsonErrorHandler", "handlerReceiver", "defaultMethod",
+	"multiMethodFiles", "maxDepth", "concurrency", "pathCase",
+	"pathPolicy", "groupPaths", "strict", "dynamicLast", "routeOrder",
+	"groupOrder", "groupImports", "nolint", "postProcess", "genClient",
+	"clientPkg", "clientParamCase", "genTS", "stats", "printTree",
+	"failOnWarnings", "verify", "logFormat", "config", "scheme",
+}
+
+// checkRegistryFlags reports the first flag set alongside -emitRegistryOnly
+// that only configures the router. Flags set by a config file shared with
+// other packages, fromConfig, are not reported.
+func checkRegistryFlags(flags *flag.FlagSet, fromConfig map[string]bool) error {
+	var unused []string
+	flags.Visit(func(f *flag.Flag) {
+		if !slices.Contains(registryFlags, f.Name) && !fromConfig[f.Name] {
+			unused = append(unused, f.Name)
+		}
+	})
+	if len(unused) > 0 {
+		return fmt.Errorf("-%s configures the router, which -emitRegistryOnly does not generate", unused[0])
+	}
+	return nil
+}
+
+// registryEntry returns the RouteInfo literal of a route.
+func registryEntry(rt route) string {
+	fields := []string{"Method: " + strconv.Quote(rt.Method), "Path: " + strconv.Quote(rt.RoutePath)}
+	var params []string
+	for _, seg := range strings.Split(rt.RoutePath, "/") {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			name, _, _ := strings.Cut(seg[1:len(seg)-1], ":")
+			params = append(params, strconv.Quote(name))
+		}
+	}
+	if len(params) > 0 {
+		fields = append(fields, "Params: []string{"+strings.Join(params, ", ")+"}")
+	}
+	if len(rt.Headers) > 0 {
+		var pairs []string
+		for i := 0; i < len(rt.Headers); i += 2 {
+			pairs = append(pairs, strconv.Quote(rt.Headers[i])+": "+strconv.Quote(rt.Headers[i+1]))
+		}
+		fields = append(fields, "Headers: map[string]string{"+strings.Join(pairs, ", ")+"}")
+	}
+	if len(rt.Schemes) > 0 {
+		schemes := make([]string, len(rt.Schemes))
+		for i, s := range rt.Schemes {
+			schemes[i] = strconv.Quote(s)
+		}
+		fields = append(fields, "Schemes: []string{"+strings.Join(schemes, ", ")+"}")
+	}
+	if rt.Group != "" {
+		fields = append(fields, "Group: "+strconv.Quote(rt.Group))
+	}
+	if len(rt.Tags) > 0 {
+		tags := make([]string, len(rt.Tags))
+		for i, tag := range rt.Tags {
+			tags[i] = strconv.Quote(tag)
+		}
+		fields = append(fields, "Tags: []string{"+strings.Join(tags, ", ")+"}")
+	}
+	if rt.EnabledIf != "" {
+		fields = append(fields, "EnabledIf: "+strconv.Quote(rt.EnabledIf))
+	}
+	fields = append(fields, "Handler: "+rt.NewHandlerFunc())
+	return "{" + strings.Join(fields, ", ") + "}"
+}
@@ -0,0 +1,62 @@
+package main
+
+// requestIDMiddlewareName is the middleware generated by -requestID.
+const requestIDMiddlewareName = "requestIDMiddleware"
+
+// requestIDImports are the standard library packages used by
+// requestIDTemplate.
+var requestIDImports = []string{"context", "crypto/rand", "fmt"}
+
+const requestIDTemplate = `
+// RequestIDHeader carries the request ID set by requestIDMiddleware
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDKey is the context key under which requestIDMiddleware stores the
+// request ID as a string
+type RequestIDKey struct{}
+
+// RequestIDFromContext returns the request ID stored by requestIDMiddleware,
+// or "" if there is none
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(RequestIDKey{}).(string)
+	return id
+}
+
+// requestIDMiddleware keeps a valid incoming X-Request-ID or assigns a new
+// UUID, and sets it on the response, the request context and the request
+// header, where handlers in other packages can read it
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+			r.Header.Set(RequestIDHeader, id)
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), RequestIDKey{}, id)))
+	})
+}
+
+// validRequestID accepts incoming IDs of up to 128 printable ASCII
+// characters, so clients cannot inject arbitrary data into logs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random version 4 UUID
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+`
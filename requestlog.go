@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const requestLogTemplate = `// Default middleware for logging requests as {{printf "%q" .RequestLog.Source}}
+func loggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+{{if .RequestLog.Duration}}		start := time.Now()
+{{end}}{{if .RequestLog.Recorder}}		rec := &logRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+{{else}}		next.ServeHTTP(w, r)
+{{end}}		fmt.Printf({{printf "%q" .RequestLog.Format}}{{range .RequestLog.Args}}, {{.}}{{end}})
+	})
+}
+{{if .RequestLog.Recorder}}
+// logRecorder captures the status code and size of a response for
+// loggingMiddleware
+type logRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+	wrote  bool
+}
+
+func (w *logRecorder) WriteHeader(status int) {
+	if !w.wrote {
+		w.status, w.wrote = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *logRecorder) Write(b []byte) (int, error) {
+	w.wrote = true
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush
+func (w *logRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+{{end}}`
+
+// requestLogFields maps each {field} of a -requestLogFormat to its Printf
+// verb and argument in the generated loggingMiddleware.
+var requestLogFields = map[string]struct{ verb, arg string }{
+	"method":   {"%s", "r.Method"},
+	"path":     {"%s", "r.URL.Path"},
+	"query":    {"%s", "r.URL.RawQuery"},
+	"remote":   {"%s", "r.RemoteAddr"},
+	"status":   {"%d", "rec.status"},
+	"bytes":    {"%d", "rec.bytes"},
+	"duration": {"%s", "time.Since(start)"},
+}
+
+// requestLog is the loggingMiddleware generated for a -requestLogFormat.
+type requestLog struct {
+	Source string
+	// Format and Args are the fmt.Printf arguments printing a request.
+	Format string
+	Args   []string
+	// Recorder is set when a field needs the response, Duration when the
+	// start time is needed.
+	Recorder bool
+	Duration bool
+}
+
+// parseRequestLogFormat expands a -requestLogFormat such as
+// "{method} {path} {status} {duration}" into Printf arguments. Text outside
+// braces is printed as is.
+func parseRequestLogFormat(source string) (*requestLog, error) {
+	rl := &requestLog{Source: source}
+	var format strings.Builder
+	rest := source
+	for rest != "" {
+		open := strings.IndexAny(rest, "{}")
+		if open < 0 {
+			format.WriteString(strings.ReplaceAll(rest, "%", "%%"))
+			break
+		}
+		format.WriteString(strings.ReplaceAll(rest[:open], "%", "%%"))
+		if rest[open] == '}' {
+			return nil, fmt.Errorf("unmatched } in %q", source)
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated { in %q", source)
+		}
+		name := rest[open+1 : open+end]
+		field, ok := requestLogFields[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown field {%s}, want one of {method}, {path}, {query}, {remote}, {status}, {bytes} or {duration}", name)
+		}
+		format.WriteString(field.verb)
+		rl.Args = append(rl.Args, field.arg)
+		rl.Recorder = rl.Recorder || strings.HasPrefix(field.arg, "rec.")
+		rl.Duration = rl.Duration || name == "duration"
+		rest = rest[open+end+1:]
+	}
+	if len(rl.Args) == 0 {
+		return nil, fmt.Errorf("%q has no {field}", source)
+	}
+	rl.Format = format.String() + "\n"
+	return rl, nil
+}
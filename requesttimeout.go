@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// requestTimeoutName is the middleware factory generated for
+// -requestTimeout.
+const requestTimeoutName = "requestTimeout"
+
+// requestTimeoutImports are the standard library packages used by
+// requestTimeoutTemplate.
+var requestTimeoutImports = []string{"context", "time"}
+
+const requestTimeoutTemplate = `
+// requestTimeout cancels each request's context after d, so handlers and the
+// calls they make observe the deadline. Unlike http.TimeoutHandler it writes
+// no response of its own
+func requestTimeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+`
+
+// parseRequestTimeout parses a -requestTimeout duration into the
+// requestTimeout call applying it, e.g. requestTimeout(30*time.Second).
+func parseRequestTimeout(value string) (string, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return "", fmt.Errorf("invalid duration %q, want a positive duration such as 30s", value)
+	}
+	return requestTimeoutName + "(" + durationExpr(d) + ")", nil
+}
+
+// isRequestTimeoutCall reports whether a middleware entry is a call of the
+// generated requestTimeout factory.
+func isRequestTimeoutCall(mw string) bool {
+	return strings.HasPrefix(mw, requestTimeoutName+"(")
+}
@@ -0,0 +1,27 @@
+package main
+
+// requireJSONName is the middleware generated for -requireJSON, which
+// //fsrouter:skipMiddleware may name to exempt a route.
+const requireJSONName = "requireJSON"
+
+// requireJSONImports are the standard library packages used by
+// requireJSONTemplate.
+var requireJSONImports = []string{"mime", "strings"}
+
+const requireJSONTemplate = `
+// requireJSON answers 415 Unsupported Media Type to requests with a body
+// whose Content-Type is not application/json or a +json type, before the
+// handler reads it
+func requireJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength != 0 {
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+				http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+`
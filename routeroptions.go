@@ -0,0 +1,38 @@
+package main
+
+const routerOptionsTemplate = `
+// Option configures the router built by RegisterRoutes at call time
+type Option func(*routerOptions)
+
+// routerOptions holds the settings of the Options passed to RegisterRoutes
+type routerOptions struct {
+	notFound    http.Handler
+	middlewares []func(http.Handler) http.Handler
+}
+
+// WithNotFound answers requests no route matches with h instead of the
+// generated 404 handler
+func WithNotFound(h http.Handler) Option {
+	return func(o *routerOptions) {
+		o.notFound = h
+	}
+}
+
+// WithMiddleware adds global middleware, run in the order given after the
+// generated global middleware. RegisterRoutesRaw ignores it
+func WithMiddleware(mw ...func(http.Handler) http.Handler) Option {
+	return func(o *routerOptions) {
+		o.middlewares = append(o.middlewares, mw...)
+	}
+}
+
+// applyOptions returns the settings of opts, later Options overriding
+// earlier ones
+func applyOptions(opts []Option) routerOptions {
+	var o routerOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+`
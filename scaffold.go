@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const stubTemplate = `package {{.Package}}
+
+import "net/http"
+
+// {{.Handler}} handles {{.Method}} {{.Route}}.
+func {{.Handler}}(w http.ResponseWriter, r *http.Request) {
+	// TODO: implement
+	http.Error(w, "not implemented", http.StatusNotImplemented)
+}
+`
+
+// openAPIMethods are the operation keys of an OpenAPI path item.
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// specOperation is one path and method declared by an OpenAPI spec.
+type specOperation struct {
+	Path   string
+	Method string
+}
+
+// runScaffold implements "fsrouter scaffold": it creates a stub handler file
+// under the api directory for every operation in an OpenAPI spec. Existing
+// handler files are left untouched.
+func runScaffold(args []string) {
+	fset := flag.NewFlagSet("scaffold", flag.ExitOnError)
+	spec := fset.String("openapi", "", "OpenAPI spec (YAML or JSON) to scaffold handlers from")
+	src := fset.String("api", "api", "directory the handler tree is created in")
+	logFormat := fset.String("logFormat", "text", "format of warnings, errors and summaries: text or json")
+	fset.Parse(args)
+	if err := diag.setFormat(*logFormat); err != nil {
+		diag.fatal(err)
+	}
+
+	if *spec == "" {
+		diag.fatal("scaffold: -openapi is required")
+	}
+	data, err := os.ReadFile(*spec)
+	if err != nil {
+		diag.fatal("scaffold:", err)
+	}
+	ops, err := parseOpenAPIPaths(data)
+	if err != nil {
+		diag.at(*spec).fatalf("scaffold: %s: %v", *spec, err)
+	}
+	if len(ops) == 0 {
+		diag.at(*spec).warnf("%s declares no operations", *spec)
+	}
+
+	created, skipped := 0, 0
+	for _, op := range ops {
+		ok, err := writeStub(*src, op)
+		if err != nil {
+			diag.fatal("scaffold:", err)
+		}
+		if ok {
+			created++
+		} else {
+			skipped++
+		}
+	}
+	diag.at(*src).infof("Scaffolded %d handlers in %s (%d already existed)", created, *src, skipped)
+}
+
+// writeStub creates the handler file for op unless it already exists, and
+// reports whether it was created.
+func writeStub(root string, op specOperation) (bool, error) {
+	dir, err := specPathDir(op.Path)
+	if err != nil {
+		return false, err
+	}
+	dirPath := filepath.Join(root, filepath.FromSlash(dir))
+	file := filepath.Join(dirPath, op.Method+".go")
+	if _, err := os.Stat(file); err == nil {
+		return false, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+
+	pkg, err := existingPackage(dirPath)
+	if err != nil {
+		return false, err
+	}
+	if pkg == "" {
+		pkg = stubPackageName(dirPath)
+	}
+
+	var buf bytes.Buffer
+	tmpl := template.Must(template.New("stub").Parse(stubTemplate))
+	err = tmpl.Execute(&buf, struct {
+		Package string
+		Handler string
+		Method  string
+		Route   string
+	}{
+		Package: pkg,
+		Handler: strings.Title(op.Method),
+		Method:  strings.ToUpper(op.Method),
+		Route:   op.Path,
+	})
+	if err != nil {
+		return false, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return false, fmt.Errorf("formatting stub for %s %s: %w", strings.ToUpper(op.Method), op.Path, err)
+	}
+
+	if err := os.MkdirAll(dirPath, 0o755); err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(file, src, 0o644)
+}
+
+// specPathDir converts an OpenAPI path into the api directory serving it,
+// e.g. "/users/{userId}" becomes "users/[userId]".
+func specPathDir(p string) (string, error) {
+	if !strings.HasPrefix(p, "/") {
+		return "", fmt.Errorf("path %q must start with /", p)
+	}
+	var segs []string
+	for _, seg := range strings.Split(strings.Trim(p, "/"), "/") {
+		if seg == "" {
+			continue
+		}
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			name := seg[1 : len(seg)-1]
+			if name == "" || sanitizeIdent(name) != name {
+				return "", fmt.Errorf("path %q: parameter %q must be a Go identifier", p, name)
+			}
+			segs = append(segs, "["+name+"]")
+			continue
+		}
+		for _, c := range seg {
+			if !isPathChar(c) {
+				return "", fmt.Errorf("path %q: segment %q cannot be a directory name, only whole-segment {param}s and the characters -._~ are supported", p, seg)
+			}
+		}
+		segs = append(segs, seg)
+	}
+	return strings.Join(segs, "/"), nil
+}
+
+// existingPackage returns the package name of the Go files already in dir, or
+// "" when there are none.
+func existingPackage(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), filepath.Join(dir, e.Name()), nil, parser.PackageClauseOnly)
+		if err != nil {
+			return "", err
+		}
+		return f.Name.Name, nil
+	}
+	return "", nil
+}
+
+// stubPackageName derives a package name for a new handler directory from its
+// last element, e.g. "api/users/[userId]" becomes "userId".
+func stubPackageName(dir string) string {
+	name := sanitizeIdent(filepath.Base(dir))
+	if name == "" || name == "_" || token.IsKeyword(name) {
+		return "handler"
+	}
+	if c := name[0]; c >= '0' && c <= '9' {
+		return "p" + name
+	}
+	return name
+}
+
+// parseOpenAPIPaths returns the operations under the spec's paths object,
+// sorted by path and then method. JSON specs are decoded fully; YAML specs are
+// read with a line-based scan that only needs the keys of paths and their
+// operations, so fsrouter does not depend on a YAML library.
+func parseOpenAPIPaths(data []byte) ([]specOperation, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var doc struct {
+			Paths map[string]map[string]json.RawMessage `json:"paths"`
+		}
+		if err := json.Unmarshal(trimmed, &doc); err != nil {
+			return nil, err
+		}
+		var ops []specOperation
+		for p, item := range doc.Paths {
+			if !strings.HasPrefix(p, "/") {
+				continue
+			}
+			for key := range item {
+				if slices.Contains(openAPIMethods, key) {
+					ops = append(ops, specOperation{Path: p, Method: key})
+				}
+			}
+		}
+		sortOperations(ops)
+		return ops, nil
+	}
+	return scanYAMLPaths(string(data))
+}
+
+// scanYAMLPaths finds the block-style paths mapping of a YAML spec and
+// collects the HTTP method keys of each path item.
+func scanYAMLPaths(src string) ([]specOperation, error) {
+	var ops []specOperation
+	inPaths := false
+	pathIndent, methodIndent := -1, -1
+	current := ""
+
+	for i, line := range strings.Split(src, "\n") {
+		content := strings.TrimRight(line, " \t\r")
+		trimmed := strings.TrimLeft(content, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		indent := len(content) - len(trimmed)
+
+		if indent == 0 {
+			key, value, ok := yamlKey(trimmed)
+			inPaths = ok && key == "paths"
+			if inPaths && value != "" && value != "{}" {
+				return nil, fmt.Errorf("line %d: paths must be a block mapping", i+1)
+			}
+			continue
+		}
+		if !inPaths {
+			continue
+		}
+
+		if pathIndent < 0 {
+			pathIndent = indent
+		}
+		switch {
+		case indent < pathIndent:
+			return nil, fmt.Errorf("line %d: unexpected indentation in paths", i+1)
+		case indent == pathIndent:
+			key, _, ok := yamlKey(trimmed)
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected a path key", i+1)
+			}
+			// Keys such as x-extensions are not paths.
+			current, methodIndent = "", -1
+			if strings.HasPrefix(key, "/") {
+				current = key
+			}
+		case current == "":
+		default:
+			if methodIndent < 0 {
+				methodIndent = indent
+			}
+			if indent != methodIndent {
+				continue
+			}
+			key, _, ok := yamlKey(trimmed)
+			if ok && slices.Contains(openAPIMethods, key) {
+				ops = append(ops, specOperation{Path: current, Method: key})
+			}
+		}
+	}
+	sortOperations(ops)
+	return ops, nil
+}
+
+// yamlKey splits a "key: value" mapping line, unquoting a quoted key.
+func yamlKey(line string) (key, value string, ok bool) {
+	if line[0] == '"' || line[0] == '\'' {
+		end := strings.IndexByte(line[1:], line[0])
+		if end < 0 {
+			return "", "", false
+		}
+		key, rest := line[:end+2], line[end+2:]
+		if line[0] == '"' {
+			unquoted, err := strconv.Unquote(key)
+			if err != nil {
+				return "", "", false
+			}
+			key = unquoted
+		} else {
+			key = key[1 : len(key)-1]
+		}
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", false
+		}
+		return key, yamlValue(rest[1:]), true
+	}
+
+	if strings.HasSuffix(line, ":") {
+		return line[:len(line)-1], "", true
+	}
+	key, value, found := strings.Cut(line, ": ")
+	if !found {
+		return "", "", false
+	}
+	return key, yamlValue(value), true
+}
+
+// yamlValue trims a scalar value and drops a trailing comment.
+func yamlValue(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "#") {
+		return ""
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return s
+}
+
+// sortOperations orders ops by path and then method.
+func sortOperations(ops []specOperation) {
+	slices.SortFunc(ops, func(a, b specOperation) int {
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Method, b.Method)
+	})
+}
@@ -0,0 +1,589 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"mime"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// apiTree is everything discovered under the api directory.
+type apiTree struct {
+	Routes    []route
+	Redirects []redirect
+	// Schemas maps a directory to the contents of its request.schema.json.
+	Schemas map[string][]byte
+	// Paths maps a directory to the URL segments replacing its name, from
+	// its _path file or -pathCase.
+	Paths map[string]string
+	// MethodNotAllowed are the handlers of methodNotAllowed.go files.
+	MethodNotAllowed []route
+}
+
+// scanOptions control how scanAPI reads the api tree.
+type scanOptions struct {
+	// concurrency is the number of handler files parsed in parallel.
+	concurrency int
+	// maxDepth above zero limits how many directory levels the tree may
+	// have.
+	maxDepth int
+	// defaultMethod, when set, is the method of the handler file in
+	// directories holding a single file not named after a method.
+	defaultMethod string
+	// strict makes handler files without exported functions an error
+	// instead of skipping them with a warning.
+	strict bool
+	// receivers allows handlers to be methods of a struct built by the
+	// package's New function. Files not named after a method then hold
+	// the struct and helpers instead of a handler.
+	receivers bool
+	// pathCase converts the names of static directories to their URL
+	// segments; a _path file still takes precedence.
+	pathCase string
+	// multiMethod makes files not named after a method register each of
+	// their exported functions named after one.
+	multiMethod bool
+	// groupPaths maps group directories to the URL prefixes replacing
+	// their paths, from -groupPaths.
+	groupPaths map[string]string
+}
+
+// scanAPI walks the api tree in fsys and parses its handler files. Routes
+// keep the walk order, so the result does not depend on concurrency. Paths
+// in an fs.FS are always slash-separated, so routes and identifiers are
+// derived identically on every OS. root is the -api directory, used for the
+// import alias of top-level handlers and in error messages.
+func scanAPI(fsys fs.FS, root, importPre string, opts scanOptions) (*apiTree, error) {
+	tree := &apiTree{Schemas: make(map[string][]byte), Paths: make(map[string]string)}
+	var handlers, redirectFiles []string
+	pathFiles := make(map[string]bool)
+
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if depth := strings.Count(p, "/") + 1; p != "." && opts.maxDepth > 0 && depth > opts.maxDepth {
+				return fmt.Errorf("%s is %d levels deep, exceeding -maxDepth=%d", filepath.Join(root, filepath.FromSlash(p)), depth, opts.maxDepth)
+			}
+			// Directories are visited before their files, so a _path file
+			// overwrites the converted name.
+			if _, _, param := parseParamSegment(d.Name()); p != "." && !param {
+				if seg := casePathSegment(d.Name(), opts.pathCase); seg != d.Name() {
+					tree.Paths[p] = seg
+				}
+			}
+			return nil
+		}
+		display := filepath.Join(root, filepath.FromSlash(p))
+		dir := slashDir(p)
+
+		if d.Name() == "redirect.txt" {
+			// Parsed once the walk is done, since the route path depends
+			// on _path files that may not have been visited yet.
+			redirectFiles = append(redirectFiles, p)
+			return nil
+		}
+		if d.Name() == pathFileName {
+			data, err := fs.ReadFile(fsys, p)
+			if err != nil {
+				return err
+			}
+			if dir == "" {
+				return fmt.Errorf("%s: the api root cannot have a %s file", display, pathFileName)
+			}
+			override, err := parsePathOverride(string(data))
+			if err != nil {
+				return fmt.Errorf("%s: %w", display, err)
+			}
+			tree.Paths[dir] = override
+			pathFiles[dir] = true
+			return nil
+		}
+		if d.Name() == "request.schema.json" {
+			data, err := fs.ReadFile(fsys, p)
+			if err != nil {
+				return err
+			}
+			tree.Schemas[dir] = data
+			return nil
+		}
+		if d.Name() == registerFileName {
+			// Generated by -target=packages into the directories of the
+			// top-level groups.
+			return nil
+		}
+		if d.Name() == methodNotAllowedFile {
+			rt, err := scanMethodNotAllowed(fsys, p, root, importPre)
+			if err != nil {
+				return err
+			}
+			tree.MethodNotAllowed = append(tree.MethodNotAllowed, rt)
+			return nil
+		}
+		if strings.HasSuffix(d.Name(), ".go") && !strings.HasSuffix(d.Name(), "_test.go") {
+			handlers = append(handlers, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := applyGroupPaths(fsys, root, opts.groupPaths, tree.Paths, pathFiles); err != nil {
+		return nil, fmt.Errorf("groupPaths: %w", err)
+	}
+
+	for _, p := range redirectFiles {
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return nil, err
+		}
+		display := filepath.Join(root, filepath.FromSlash(p))
+		rd, err := parseRedirect(routePath(slashDir(p), tree.Paths), string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", display, err)
+		}
+		rd.Source = display
+		tree.Redirects = append(tree.Redirects, rd)
+	}
+
+	methods := handlerMethods(handlers, opts.defaultMethod, opts.receivers, opts.multiMethod)
+	if err := checkMethodCollisions(root, handlers, methods); err != nil {
+		return nil, err
+	}
+	routes := make([][]route, len(handlers))
+	errs := make([]error, len(handlers))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(opts.concurrency, len(handlers)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if methods[i] != "" {
+					routes[i], errs[i] = scanHandler(fsys, handlers[i], methods[i], root, importPre, tree.Paths, opts.receivers)
+				}
+			}
+		}()
+	}
+	for i := range handlers {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, err := range errs {
+		if errors.Is(err, errEmptyHandler) && !opts.strict {
+			diag.at(filepath.Join(root, filepath.FromSlash(handlers[i]))).warnf("%v, skipping it", err)
+			errs[i] = nil
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	tree.Routes = slices.Concat(routes...)
+	if opts.multiMethod {
+		// The methods of multi-method files are only known now.
+		var files, fileMethods []string
+		for _, rt := range tree.Routes {
+			if n := len(files); n == 0 || files[n-1] != rt.File || fileMethods[n-1] != rt.Method {
+				files, fileMethods = append(files, rt.File), append(fileMethods, rt.Method)
+			}
+		}
+		if err := checkMethodCollisions(root, files, fileMethods); err != nil {
+			return nil, err
+		}
+	}
+
+	// Directory names are folded into identifiers, so distinct directories
+	// such as a-b and a_b may end up with the same import alias.
+	aliasDirs := make(map[string]string)
+	for _, rt := range tree.Routes {
+		// Go import paths must be ASCII, so a handler package in café
+		// could not be imported; its URL comes from a _path file instead.
+		if strings.IndexFunc(rt.Dir, func(c rune) bool { return c >= utf8.RuneSelf }) >= 0 {
+			return nil, fmt.Errorf("%s: Go import paths must be ASCII, name the directory in ASCII and give it a _path file with the non-ASCII path", filepath.Join(root, filepath.FromSlash(rt.Dir)))
+		}
+		if dir, ok := aliasDirs[rt.Alias]; ok && dir != rt.Dir {
+			return nil, fmt.Errorf("%s and %s both get the identifier %s, rename one of them", filepath.Join(root, filepath.FromSlash(dir)), filepath.Join(root, filepath.FromSlash(rt.Dir)), rt.Alias)
+		}
+		aliasDirs[rt.Alias] = rt.Dir
+	}
+	if err := checkReceivers(fsys, root, tree.Routes); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// multiMethods is the method of a -multiMethodFiles file, whose functions
+// name the methods it registers.
+const multiMethods = "*"
+
+// handlerMethods returns the method each handler file registers for: its
+// upper-cased name, fallbackMethod for fallback.go, or defaultMethod for the only file of a directory when
+// that file is not named after a standard method. With multiMethod, files
+// not named after a standard method get multiMethods instead. With
+// receivers, other files not named after a standard method get "", as they
+// hold no handler.
+func handlerMethods(handlers []string, defaultMethod string, receivers, multiMethod bool) []string {
+	perDir := make(map[string]int)
+	for _, p := range handlers {
+		perDir[slashDir(p)]++
+	}
+	methods := make([]string, len(handlers))
+	for i, p := range handlers {
+		methods[i] = strings.ToUpper(strings.TrimSuffix(path.Base(p), ".go"))
+		if slices.Contains(standardMethods, methods[i]) || methods[i] == fallbackMethod {
+			continue
+		}
+		switch {
+		case multiMethod:
+			methods[i] = multiMethods
+		case defaultMethod != "" && perDir[slashDir(p)] == 1:
+			methods[i] = defaultMethod
+		case receivers:
+			methods[i] = ""
+		}
+	}
+	return methods
+}
+
+// checkMethodCollisions reports two handler files of a directory that
+// register the same method, such as Get.go and get.go. Case-insensitive file
+// systems cannot hold both, so a tree that does was likely merged from
+// another system.
+func checkMethodCollisions(root string, handlers, methods []string) error {
+	seen := make(map[string]string)
+	for i, p := range handlers {
+		if methods[i] == "" || methods[i] == multiMethods {
+			continue
+		}
+		key := slashDir(p) + " " + methods[i]
+		if prev, ok := seen[key]; ok {
+			return fmt.Errorf("%s: %s and %s both register %s, keep only one", filepath.Join(root, filepath.FromSlash(slashDir(p))), path.Base(prev), path.Base(p), methods[i])
+		}
+		seen[key] = p
+	}
+	return nil
+}
+
+// standardMethods are the HTTP methods of RFC 9110 and PATCH.
+var standardMethods = []string{"CONNECT", "DELETE", "GET", "HEAD", "OPTIONS", "PATCH", "POST", "PUT", "TRACE"}
+
+// scanHandler derives the routes served by the handler file at p for method
+// and applies its directives. A file serves its directory's path unless its
+// Paths variable lists others. paths holds the _path overrides of the whole
+// tree. receivers allows the handler to be a method.
+func scanHandler(fsys fs.FS, p, method, root, importPre string, paths map[string]string, receivers bool) ([]route, error) {
+	display := filepath.Join(root, filepath.FromSlash(p))
+	dir := slashDir(p)
+	fileName := strings.TrimSuffix(path.Base(p), ".go")
+
+	alias := sanitizeIdent(dir)
+	if dir == "" {
+		alias = sanitizeIdent(filepath.Base(root))
+	}
+
+	params, err := dirParams(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", display, err)
+	}
+
+	rt := route{
+		Method:     method,
+		RoutePath:  routePath(dir, paths),
+		Dir:        dir,
+		File:       p,
+		ImportPath: path.Join(importPre, dir),
+		Alias:      alias,
+		Params:     params,
+	}
+	if len(params) > 0 {
+		rt.ParamParser = alias + "Params"
+	}
+	if method == fallbackMethod {
+		rt.Method = ""
+	}
+
+	src, err := fs.ReadFile(fsys, p)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, display, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	// A file named after no standard method, such as helpers.go, only
+	// registers its upper-cased name when it declares the function that
+	// name asks for. Otherwise it holds helpers for the handlers next to
+	// it.
+	if method == strings.ToUpper(fileName) && method != fallbackMethod && !slices.Contains(standardMethods, method) && !declaresFunc(file, fileName) {
+		return nil, nil
+	}
+	if !hasExportedFunc(file) {
+		return nil, fmt.Errorf("%s: %w, want func %s(w http.ResponseWriter, r *http.Request)", display, errEmptyHandler, exportedName(strings.ToLower(fileName)))
+	}
+	variants := []route{rt}
+	if method == multiMethods {
+		variants, err = findMethodHandlers(fset, file, rt, receivers)
+	} else {
+		variants[0].Handler, variants[0].Receiver, variants[0].JSONAPI, err = findHandler(fset, file, fileName, receivers)
+	}
+	if err != nil {
+		return nil, err
+	}
+	directives, err := parseDirectives(fset, file)
+	if err != nil {
+		return nil, err
+	}
+	var routes []route
+	for _, v := range variants {
+		// Params are renamed in place, so each handler needs its own.
+		v.Params = slices.Clone(v.Params)
+		extraPaths, err := handlerPaths(fset, file, v.Handler)
+		if err != nil {
+			return nil, err
+		}
+		if err := applyDirectives(&v, directives); err != nil {
+			return nil, err
+		}
+		if extraPaths == nil {
+			routes = append(routes, v)
+			continue
+		}
+		for _, extra := range extraPaths {
+			r := v
+			if extra != "/" {
+				r.RoutePath = joinPath(v.RoutePath, extra)
+			}
+			routes = append(routes, r)
+		}
+	}
+	return routes, nil
+}
+
+// applyDirectives sets the fields of rt that its file's directives
+// configure.
+func applyDirectives(rt *route, directives []directive) error {
+	renamed := make(map[string]bool)
+	for _, dv := range directives {
+		switch dv.Name {
+		case "timeout":
+			if rt.Timeout != 0 {
+				return fmt.Errorf("%s: duplicate timeout directive", dv.Pos)
+			}
+			timeout, err := time.ParseDuration(dv.Value)
+			if err != nil || timeout <= 0 {
+				return fmt.Errorf("%s: invalid timeout %q, want a positive duration such as 5s", dv.Pos, dv.Value)
+			}
+			rt.Timeout = timeout
+		case "group":
+			if rt.Group != "" {
+				return fmt.Errorf("%s: duplicate group directive", dv.Pos)
+			}
+			name := strings.Trim(dv.Value, "/")
+			if name == "" || strings.ContainsAny(name, " \t") {
+				return fmt.Errorf("%s: invalid group %q, want a single group name such as admin", dv.Pos, dv.Value)
+			}
+			rt.Group = name
+		case "produces":
+			if rt.Produces != "" {
+				return fmt.Errorf("%s: duplicate produces directive, a handler has a single default content type", dv.Pos)
+			}
+			if _, _, err := mime.ParseMediaType(dv.Value); err != nil || strings.Contains(dv.Value, ",") {
+				return fmt.Errorf("%s: invalid produces %q, want a single media type such as application/json", dv.Pos, dv.Value)
+			}
+			rt.Produces = dv.Value
+		case "cache":
+			if rt.CacheControl != "" {
+				return fmt.Errorf("%s: duplicate cache directive, list all Cache-Control directives in one", dv.Pos)
+			}
+			if err := checkCacheControl(dv.Value); err != nil {
+				return fmt.Errorf("%s: invalid cache %q: %w", dv.Pos, dv.Value, err)
+			}
+			rt.CacheControl = dv.Value
+		case "skipMiddleware":
+			var names []string
+			for _, item := range splitList(dv.Value) {
+				if strings.Contains(item, "(") {
+					names = append(names, item)
+				} else {
+					names = append(names, strings.Fields(item)...)
+				}
+			}
+			if len(names) == 0 {
+				return fmt.Errorf("%s: skipMiddleware directive needs at least one middleware", dv.Pos)
+			}
+			for _, name := range names {
+				if !slices.Contains(rt.Skip, name) {
+					rt.Skip = append(rt.Skip, name)
+				}
+			}
+		case "deprecated":
+			if rt.Deprecated {
+				return fmt.Errorf("%s: duplicate deprecated directive", dv.Pos)
+			}
+			successor, sunset, err := parseDeprecated(dv.Value)
+			if err != nil {
+				return fmt.Errorf("%s: invalid deprecated directive: %w", dv.Pos, err)
+			}
+			rt.Deprecated, rt.Successor, rt.Sunset = true, successor, sunset
+		case "catchAll":
+			if rt.CatchAllSlash != "" {
+				return fmt.Errorf("%s: duplicate catchAll directive", dv.Pos)
+			}
+			if dv.Value != "slash" {
+				return fmt.Errorf("%s: invalid catchAll %q, want slash to keep the leading slash", dv.Pos, dv.Value)
+			}
+			if rt.CatchAllSlash = lastCatchAll(rt.RoutePath); rt.CatchAllSlash == "" {
+				return fmt.Errorf("%s: catchAll directive on %s, which has no [...name] directory", dv.Pos, rt.RoutePath)
+			}
+		case "internal":
+			if rt.Internal {
+				return fmt.Errorf("%s: duplicate internal directive", dv.Pos)
+			}
+			if dv.Value != "" {
+				return fmt.Errorf("%s: internal directive takes no value, got %q", dv.Pos, dv.Value)
+			}
+			rt.Internal = true
+		case "header":
+			name, val, err := parseHeaderMatch(dv.Value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", dv.Pos, err)
+			}
+			for i := 0; i < len(rt.Headers); i += 2 {
+				if rt.Headers[i] == name {
+					return fmt.Errorf("%s: duplicate header directive for %s", dv.Pos, name)
+				}
+			}
+			rt.Headers = append(rt.Headers, name, val)
+		case "scheme":
+			list, err := parseSchemes(dv.Value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", dv.Pos, err)
+			}
+			for _, s := range list {
+				if !slices.Contains(rt.Schemes, s) {
+					rt.Schemes = append(rt.Schemes, s)
+				}
+			}
+		case "paramName":
+			fields := strings.Fields(dv.Value)
+			if len(fields) != 2 || !isParamName(fields[1]) {
+				return fmt.Errorf("%s: invalid paramName %q, want the folder's parameter and the name to register, such as user userId", dv.Pos, dv.Value)
+			}
+			if renamed[fields[0]] {
+				return fmt.Errorf("%s: duplicate paramName directive for %s", dv.Pos, fields[0])
+			}
+			if err := renameParam(rt, fields[0], fields[1]); err != nil {
+				return fmt.Errorf("%s: %w", dv.Pos, err)
+			}
+			renamed[fields[0]] = true
+		case "scopes":
+			scopes := strings.Fields(dv.Value)
+			if len(scopes) == 0 {
+				return fmt.Errorf("%s: scopes directive needs at least one scope", dv.Pos)
+			}
+			for _, scope := range scopes {
+				if !isScope(scope) {
+					return fmt.Errorf("%s: invalid scope %q", dv.Pos, scope)
+				}
+				if !slices.Contains(rt.Scopes, scope) {
+					rt.Scopes = append(rt.Scopes, scope)
+				}
+			}
+		case "enabledIf":
+			if rt.EnabledIf != "" {
+				return fmt.Errorf("%s: duplicate enabledIf directive", dv.Pos)
+			}
+			if !isEnvName(dv.Value) {
+				return fmt.Errorf("%s: invalid enabledIf %q, want an environment variable name such as FEATURE_X", dv.Pos, dv.Value)
+			}
+			rt.EnabledIf = dv.Value
+		case "tag":
+			tags := strings.Fields(strings.ReplaceAll(dv.Value, ",", " "))
+			if len(tags) == 0 {
+				return fmt.Errorf("%s: tag directive needs at least one tag", dv.Pos)
+			}
+			for _, tag := range tags {
+				if !slices.Contains(rt.Tags, tag) {
+					rt.Tags = append(rt.Tags, tag)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// slashDir returns the directory of the slash-separated path p, or "" at the
+// api root.
+func slashDir(p string) string {
+	dir := path.Dir(p)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// pathFileName is the file overriding the URL segment of its directory.
+const pathFileName = "_path"
+
+// parsePathOverride validates the contents of a _path file and returns its
+// segments without surrounding slashes.
+func parsePathOverride(contents string) (string, error) {
+	raw := strings.TrimSpace(contents)
+	override := strings.Trim(raw, "/")
+	if override == "" || strings.Contains(raw, "\n") {
+		return "", fmt.Errorf("%s must contain a single path such as /v2/users, got %q", pathFileName, raw)
+	}
+	for _, seg := range strings.Split(override, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			return "", fmt.Errorf("invalid path %q: empty, . and .. segments are not allowed", raw)
+		}
+		for _, c := range seg {
+			if !isPathChar(c) {
+				return "", fmt.Errorf("invalid path %q: character %q is not allowed", raw, c)
+			}
+		}
+	}
+	return override, nil
+}
+
+// isPathChar reports whether c may appear in a _path segment: letters,
+// digits and the URL-safe punctuation -._~. Letters and digits outside
+// ASCII are allowed since Go import paths, and so directory names, cannot
+// hold them.
+func isPathChar(c rune) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	case c >= utf8.RuneSelf:
+		return unicode.IsLetter(c) || unicode.IsDigit(c)
+	}
+	return strings.ContainsRune("-._~", c)
+}
+
+// isEnvName reports whether name is a portable environment variable name:
+// letters, digits and underscores, not starting with a digit.
+func isEnvName(name string) bool {
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return name != ""
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// mapHandler returns a MapFS file holding a handler file of package pkg.
+func mapHandler(pkg, name string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(handlerFile(pkg, name, pkg))}
+}
+
+func TestScanWindowsRoot(t *testing.T) {
+	fsys := fstest.MapFS{
+		"get.go":                      mapHandler("api", "Get"),
+		"users/get.go":                mapHandler("users", "Get"),
+		"users/[userId]/posts/get.go": mapHandler("posts", "Get"),
+	}
+	tree, err := scanAPI(fsys, `C:\src\app\api`, "example.com/app/api", scanOptions{concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"/":                     "example.com/app/api",
+		"/users":                "example.com/app/api/users",
+		"/users/{userId}/posts": "example.com/app/api/users/[userId]/posts",
+	}
+	if len(tree.Routes) != len(want) {
+		t.Fatalf("got %d routes, want %d", len(tree.Routes), len(want))
+	}
+	for _, rt := range tree.Routes {
+		if imp, ok := want[rt.RoutePath]; !ok || rt.ImportPath != imp {
+			t.Errorf("route %s imports %s, want one of %v", rt.RoutePath, rt.ImportPath, want)
+		}
+		if strings.Contains(rt.Dir, `\`) {
+			t.Errorf("route %s has directory %s, want a slash-separated path", rt.RoutePath, rt.Dir)
+		}
+		if !token.IsIdentifier(rt.Alias) {
+			t.Errorf("route %s has alias %q, want an identifier", rt.RoutePath, rt.Alias)
+		}
+	}
+}
+
+// syntheticTree returns an api tree of n resources, each with a collection
+// and a {id} member handler per method.
+func syntheticTree(n int) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for i := range n {
+		pkg := fmt.Sprintf("res%d", i)
+		for _, m := range []string{"Get", "Post"} {
+			fsys[pkg+"/"+strings.ToLower(m)+".go"] = mapHandler(pkg, m)
+		}
+		for _, m := range []string{"Get", "Put", "Delete"} {
+			fsys[pkg+"/[id]/"+strings.ToLower(m)+".go"] = mapHandler("id", m)
+		}
+	}
+	return fsys
+}
+
+func BenchmarkScan(b *testing.B) {
+	fsys := syntheticTree(200)
+	for _, concurrency := range []int{1, 4} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			for b.Loop() {
+				if _, err := scanAPI(fsys, "api", "example.com/app/api", scanOptions{concurrency: concurrency}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestScanSkipsHelperFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"users/get.go":      {Data: []byte(handlerFile("users", "Get", "users") + "\nfunc Helper() string { return \"\" }\n")},
+		"users/helpers.go":  {Data: []byte("package users\n\nfunc Format(name string) string { return name }\n")},
+		"users/get_test.go": {Data: []byte("package users\n\nimport \"testing\"\n\nfunc TestGet(t *testing.T) {}\n")},
+	}
+	tree, err := scanAPI(fsys, "api", "example.com/app/api", scanOptions{concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.Routes) != 1 || tree.Routes[0].Method != "GET" || tree.Routes[0].RoutePath != "/users" || tree.Routes[0].Handler != "Get" {
+		for _, rt := range tree.Routes {
+			t.Logf("%s %s -> %s", rt.Method, rt.RoutePath, rt.Handler)
+		}
+		t.Fatalf("got %d routes, want only GET /users -> Get", len(tree.Routes))
+	}
+}
+
+func TestScanRejectsMisshapedHandler(t *testing.T) {
+	for name, src := range map[string]string{
+		"missing":   "package users\n\nfunc Helper() {}\n",
+		"misshaped": "package users\n\nfunc Get() {}\n",
+	} {
+		fsys := fstest.MapFS{"users/get.go": {Data: []byte(src)}}
+		if _, err := scanAPI(fsys, "api", "example.com/app/api", scanOptions{concurrency: 2}); err == nil {
+			t.Errorf("%s handler: no error", name)
+		}
+	}
+}
+
+func TestScanNonASCIIDirectory(t *testing.T) {
+	fsys := fstest.MapFS{
+		"café/get.go": mapHandler("cafe", "Get"),
+	}
+	_, err := scanAPI(fsys, "api", "example.com/app/api", scanOptions{concurrency: 2})
+	if err == nil || !strings.Contains(err.Error(), "café") || !strings.Contains(err.Error(), "_path") {
+		t.Fatalf("got error %v, want one naming café and suggesting a _path file", err)
+	}
+}
+
+func TestScanIdentifierCollision(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a-b/get.go": mapHandler("ab", "Get"),
+		"a_b/get.go": mapHandler("ab", "Get"),
+	}
+	_, err := scanAPI(fsys, "api", "example.com/app/api", scanOptions{concurrency: 2})
+	if err == nil || !strings.Contains(err.Error(), "a_b") {
+		t.Fatalf("got error %v, want one naming both directories", err)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+)
+
+// bodySchema is a request.schema.json emitted into the generated file.
+type bodySchema struct {
+	Var  string
+	JSON string
+}
+
+// validateBodyTemplate is the generated body-validation middleware. It
+// supports the "type", "properties", "required", "items" and "enum"
+// keywords of JSON Schema.
+const validateBodyTemplate = `
+{{range .Schemas}}var {{.Var}} = mustParseSchema({{.JSON}})
+{{end}}
+// validateBody rejects requests whose JSON body does not match schema with
+// 400 Bad Request
+func validateBody(schema map[string]any) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+{{if .MaxBodySize}}				if _, ok := err.(*http.MaxBytesError); ok {
+					http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+					return
+				}
+{{end}}				http.Error(w, "could not read request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			var v any
+			if err := json.Unmarshal(body, &v); err != nil {
+				http.Error(w, "invalid JSON body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := validateSchema(schema, v, "body"); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func mustParseSchema(s string) map[string]any {
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(s), &schema); err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+// validateSchema checks v against the supported subset of JSON Schema
+func validateSchema(schema map[string]any, v any, at string) error {
+	if t, ok := schema["type"].(string); ok && !schemaTypeMatches(t, v) {
+		return fmt.Errorf("%s: expected %s", at, t)
+	}
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, allowed := range enum {
+			found = found || reflect.DeepEqual(allowed, v)
+		}
+		if !found {
+			return fmt.Errorf("%s: value is not one of the allowed values", at)
+		}
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if s, ok := name.(string); ok {
+				if _, present := val[s]; !present {
+					return fmt.Errorf("%s: missing required property %q", at, s)
+				}
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for name, sub := range properties {
+			subSchema, ok := sub.(map[string]any)
+			if field, present := val[name]; ok && present {
+				if err := validateSchema(subSchema, field, at+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range val {
+				if err := validateSchema(items, item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func schemaTypeMatches(t string, v any) bool {
+	switch t {
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "null":
+		return v == nil
+	}
+	return true
+}
+`
+
+// validateBodyImports are the standard library packages used by the
+// generated validation middleware.
+var validateBodyImports = []string{"bytes", "encoding/json", "io", "math", "reflect"}
+
+// compileSchemas checks each directory's request.schema.json and attaches
+// it to the routes of that directory that take a request body.
+func compileSchemas(routes []route, schemas map[string][]byte, root string) ([]bodySchema, error) {
+	var compiled []bodySchema
+	vars := make(map[string]string)
+	for i, rt := range routes {
+		data, ok := schemas[rt.Dir]
+		if !ok || !hasBody(rt.Method) {
+			continue
+		}
+		if v, done := vars[rt.Dir]; done {
+			routes[i].Schema = v
+			continue
+		}
+
+		var schema map[string]any
+		if err := json.Unmarshal(data, &schema); err != nil {
+			return nil, fmt.Errorf("%s: invalid JSON schema: %w", path.Join(root, rt.Dir, "request.schema.json"), err)
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, data); err != nil {
+			return nil, err
+		}
+
+		v := rt.Alias + "Schema"
+		vars[rt.Dir] = v
+		routes[i].Schema = v
+		compiled = append(compiled, bodySchema{Var: v, JSON: strconv.Quote(compact.String())})
+	}
+	return compiled, nil
+}
+
+// hasBody reports whether requests with the method carry a body.
+func hasBody(method string) bool {
+	return method == "POST" || method == "PUT" || method == "PATCH"
+}
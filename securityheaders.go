@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// securityHeadersMiddlewareName is the middleware generated by
+// -securityHeaders.
+const securityHeadersMiddlewareName = "securityHeadersMiddleware"
+
+// securityHeader is a response header set by the generated
+// securityHeadersMiddleware, with the comment explaining it.
+type securityHeader struct {
+	Name    string
+	Value   string
+	Comment string
+}
+
+// defaultSecurityHeaders are the headers -securityHeaders sets unless
+// -securityHeaderValues replaces or drops them. They suit an API serving
+// JSON rather than pages.
+var defaultSecurityHeaders = []securityHeader{
+	{"Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'", "responses load nothing and cannot be embedded"},
+	{"Cross-Origin-Opener-Policy", "same-origin", "no shared browsing context with other origins"},
+	{"Referrer-Policy", "strict-origin-when-cross-origin", "only the origin is sent to other sites"},
+	{"Strict-Transport-Security", "max-age=63072000; includeSubDomains", "HTTPS only for two years, ignored over plain HTTP"},
+	{"X-Content-Type-Options", "nosniff", "browsers keep to the declared Content-Type"},
+	{"X-Frame-Options", "DENY", "no framing, for browsers without frame-ancestors"},
+}
+
+const securityHeadersTemplate = `
+// securityHeaders are set on every response by securityHeadersMiddleware
+var securityHeaders = [][2]string{
+{{range .SecurityHeaders}}	{{"{"}}{{printf "%q" .Name}}, {{printf "%q" .Value}}}, // {{.Comment}}
+{{end}}}
+
+// securityHeadersMiddleware sets securityHeaders before calling the handler,
+// so a handler can still replace or delete one for its responses
+func securityHeadersMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		for _, kv := range securityHeaders {
+			h.Set(kv[0], kv[1])
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+`
+
+// parseSecurityHeaders returns the default security headers with the
+// -securityHeaderValues overrides applied: a JSON mapping of header name
+// to value, where a default header mapped to "" is dropped and other names
+// are added, sorted by name.
+func parseSecurityHeaders(overrides string) ([]securityHeader, error) {
+	headers := slices.Clone(defaultSecurityHeaders)
+	if overrides == "" {
+		return headers, nil
+	}
+	var raw map[string]string
+	if err := json.Unmarshal([]byte(overrides), &raw); err != nil {
+		return nil, err
+	}
+	seen := make(map[string]string)
+	for name, value := range raw {
+		if name == "" || strings.IndexFunc(name, func(c rune) bool { return !isTokenChar(c) }) >= 0 {
+			return nil, fmt.Errorf("invalid header name %q", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("value of %s must be a single line", name)
+		}
+		canonical := http.CanonicalHeaderKey(name)
+		if prev, ok := seen[canonical]; ok {
+			return nil, fmt.Errorf("%s and %s are the same header", min(prev, name), max(prev, name))
+		}
+		seen[canonical] = name
+		name = canonical
+		i := slices.IndexFunc(headers, func(h securityHeader) bool { return h.Name == name })
+		switch {
+		case i < 0 && value == "":
+			return nil, fmt.Errorf("%s is not a default header, there is nothing to drop", name)
+		case i < 0:
+			headers = append(headers, securityHeader{name, value, "from -securityHeaderValues"})
+		case value == "":
+			headers = slices.Delete(headers, i, i+1)
+		default:
+			headers[i].Value, headers[i].Comment = value, "from -securityHeaderValues"
+		}
+	}
+	slices.SortFunc(headers, func(a, b securityHeader) int { return strings.Compare(a.Name, b.Name) })
+	return headers, nil
+}
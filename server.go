@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const serverTemplate = `
+// NewServer returns an http.Server for addr serving RegisterRoutes, with
+// timeouts guarding against slow clients
+func NewServer(addr string{{if .Tagged}}, tags ...string{{end}}) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           RegisterRoutes({{if .Tagged}}tags...{{end}}),
+{{with .Server.ReadHeader}}		ReadHeaderTimeout: {{.}},
+{{end}}{{with .Server.Read}}		ReadTimeout:       {{.}},
+{{end}}{{with .Server.Write}}		WriteTimeout:      {{.}},
+{{end}}{{with .Server.Idle}}		IdleTimeout:       {{.}},
+{{end}}	}
+}
+
+// Run serves srv until ctx is cancelled, then shuts it down gracefully,
+// giving in-flight requests {{.Server.ShutdownText}} to finish
+func Run(ctx context.Context, srv *http.Server) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), {{.Server.Shutdown}})
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+`
+
+// serverImports are the standard library packages used by serverTemplate.
+var serverImports = []string{"context", "errors", "time"}
+
+// ServerTimeouts configures the generated NewServer and Run, parsed from
+// -serverTimeouts. Values use time.ParseDuration syntax; "0" disables a
+// server timeout.
+type ServerTimeouts struct {
+	ReadHeader string `json:"readHeader"`
+	Read       string `json:"read"`
+	Write      string `json:"write"`
+	Idle       string `json:"idle"`
+	Shutdown   string `json:"shutdown"`
+}
+
+// defaultServerTimeouts apply to every timeout -serverTimeouts leaves out.
+var defaultServerTimeouts = ServerTimeouts{
+	ReadHeader: "5s",
+	Read:       "30s",
+	Write:      "60s",
+	Idle:       "120s",
+	Shutdown:   "10s",
+}
+
+// serverConfig is ServerTimeouts rendered as Go duration expressions, empty
+// when a timeout is disabled.
+type serverConfig struct {
+	ReadHeader   string
+	Read         string
+	Write        string
+	Idle         string
+	Shutdown     string
+	ShutdownText string
+}
+
+// parseServerTimeouts parses the -serverTimeouts JSON, which may be empty to
+// use the defaults.
+func parseServerTimeouts(raw string) (*serverConfig, error) {
+	opts := defaultServerTimeouts
+	if raw != "" {
+		dec := json.NewDecoder(strings.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&opts); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg := &serverConfig{}
+	fields := []struct {
+		name  string
+		value string
+		expr  *string
+	}{
+		{"readHeader", opts.ReadHeader, &cfg.ReadHeader},
+		{"read", opts.Read, &cfg.Read},
+		{"write", opts.Write, &cfg.Write},
+		{"idle", opts.Idle, &cfg.Idle},
+		{"shutdown", opts.Shutdown, &cfg.Shutdown},
+	}
+	for _, f := range fields {
+		d, err := time.ParseDuration(f.value)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid %s timeout %q, want a duration such as 5s or 0 to disable it", f.name, f.value)
+		}
+		if d > 0 {
+			*f.expr = durationExpr(d)
+		}
+		if f.name == "shutdown" {
+			if d == 0 {
+				return nil, fmt.Errorf("shutdown timeout must be positive")
+			}
+			cfg.ShutdownText = d.String()
+		}
+	}
+	return cfg, nil
+}
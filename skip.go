@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// applySkips honours //fsrouter:skipMiddleware directives. mux applies group
+// middleware to every route of a subrouter, so a route skipping some of its
+// group's middleware moves to a sibling subrouter with the same prefix that
+// runs the rest of the group chain. Those sibling groups are returned for
+// registration after the regular groups, so requests they do not serve fall
+// through to them only after the regular group found no match. Method
+// middleware and requireJSON are skipped by leaving them out of the route's
+// wrapping.
+func applySkips(routes []route, groups []group, global []string, methodClasses map[string][]string, paths map[string]string, root string) ([]group, error) {
+	byVar := make(map[string]group, len(groups))
+	for _, g := range groups {
+		byVar[g.Var] = g
+	}
+
+	var variants []group
+	for i, rt := range routes {
+		if len(rt.Skip) == 0 {
+			continue
+		}
+		display := filepath.Join(root, filepath.FromSlash(rt.File))
+
+		// The middleware of every enclosing group, outermost group first.
+		var chain []string
+		for v := rt.Router; v != "r"; v = byVar[v].Parent {
+			chain = append(slices.Clone(byVar[v].Middlewares), chain...)
+		}
+		methods := methodMiddlewareList(rt.Method, methodClasses)
+
+		skipsGroup := false
+		for _, mw := range rt.Skip {
+			switch {
+			case slices.Contains(chain, mw):
+				skipsGroup = true
+			case slices.Contains(methods, mw):
+			case mw == requireJSONName && rt.RequireJSON:
+				routes[i].RequireJSON = false
+			case slices.Contains(global, mw):
+				return nil, fmt.Errorf("%s: cannot skip %s, global middleware runs before routing", display, mw)
+			default:
+				return nil, fmt.Errorf("%s: cannot skip %s, it is not applied to this route", display, mw)
+			}
+		}
+		if !skipsGroup {
+			continue
+		}
+
+		skipped := slices.Clone(rt.Skip)
+		slices.Sort(skipped)
+		v := sanitizeIdent(rt.Group) + "_no_" + sanitizeIdent(strings.Join(skipped, "_")) + "Router"
+		if !slices.ContainsFunc(variants, func(g group) bool { return g.Var == v }) {
+			variants = append(variants, group{
+				Name:   rt.Group + " without " + strings.Join(skipped, ", "),
+				Var:    v,
+				Parent: "r",
+				Prefix: routePath(rt.Group, paths),
+				Gate:   byVar[rt.Router].Gate,
+				// Answer wrong methods like the group the route left.
+				MethodNotAllowed: byVar[rt.Router].MethodNotAllowed,
+				Middlewares: slices.DeleteFunc(chain, func(mw string) bool {
+					return slices.Contains(rt.Skip, mw)
+				}),
+			})
+		}
+		routes[i].Router = v
+	}
+	return variants, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// spaImports are the standard library packages used by spaTemplate.
+var spaImports = []string{"embed", "io/fs", "path", "strings"}
+
+const spaTemplate = `
+// spaHandler serves the files under dir in fsys for the group mounted at
+// prefix, answering paths that match no file with index.html so client-side
+// routing works
+func spaHandler(fsys embed.FS, dir, prefix string) http.Handler {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	files := http.StripPrefix(prefix, http.FileServer(http.FS(sub)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean("/"+strings.TrimPrefix(r.URL.Path, prefix)), "/")
+		if name == "" {
+			name = "."
+		}
+{{if .SPACache}}		info, err := fs.Stat(sub, name)
+		if err != nil || info.IsDir() {
+			// index.html is revalidated, so a deploy takes effect at once
+			w.Header().Set("Cache-Control", "no-cache")
+		} else {
+			w.Header().Set("Cache-Control", {{printf "%q" .SPACache}})
+		}
+		if err != nil {
+{{else}}		if _, err := fs.Stat(sub, name); err != nil {
+{{end}}			http.ServeFileFS(w, r, sub, "index.html")
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+{{range .SPAs}}
+//go:embed {{printf "%q" .Dir}}
+var {{.Var}} embed.FS
+{{end}}`
+
+// spaGroup is a group whose unmatched GET requests are answered from an
+// embedded single-page app, from -spa.
+type spaGroup struct {
+	// Router is the group's router variable, Prefix its full path.
+	Router string
+	Prefix string
+	// Dir is the embedded directory, relative to the generated file.
+	Dir string
+	// Var is the embed.FS variable holding Dir.
+	Var string
+}
+
+// parseSPA parses a -spa value mapping group names to the directories of
+// their built apps, relative to outDir, the generated file's directory.
+// Groups must have a static prefix and not be gated by -tagGroups.
+func parseSPA(value string, groups []group, outDir string) ([]spaGroup, error) {
+	var raw map[string]string
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		return nil, err
+	}
+	prefixes := make(map[string]string)
+	for _, g := range groups {
+		prefixes[g.Var] = prefixes[g.Parent] + g.Prefix
+	}
+
+	var spas []spaGroup
+	for _, name := range slices.Sorted(maps.Keys(raw)) {
+		i := slices.IndexFunc(groups, func(g group) bool { return g.Name == strings.Trim(name, "/") })
+		if i < 0 {
+			return nil, fmt.Errorf("%s is not a route group", name)
+		}
+		g := groups[i]
+		prefix := prefixes[g.Var]
+		switch {
+		case strings.Contains(prefix, "{"):
+			return nil, fmt.Errorf("%s: the prefix %s has parameters, an app needs a static one", name, prefix)
+		case g.Gate != "":
+			return nil, fmt.Errorf("%s is compiled in by the %s build tag, -spa groups must always be", name, g.Gate)
+		case g.Version != "":
+			return nil, fmt.Errorf("%s is in a -versionDirs directory, -spa groups must be registered by RegisterRoutes itself", name)
+		}
+
+		dir := path.Clean(filepath.ToSlash(raw[name]))
+		if !fs.ValidPath(dir) || dir == "." {
+			return nil, fmt.Errorf("%s: %q must be a subdirectory of the generated file's directory, as go:embed requires", name, raw[name])
+		}
+		info, err := os.Stat(filepath.Join(outDir, filepath.FromSlash(dir)))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("%s: %s is not a directory", name, dir)
+		}
+		if _, err := os.Stat(filepath.Join(outDir, filepath.FromSlash(dir), "index.html")); err != nil {
+			diag.at(filepath.Join(outDir, filepath.FromSlash(dir))).warnf("no index.html in the -spa directory of %s, unknown paths will answer 404", name)
+		}
+		spas = append(spas, spaGroup{Router: g.Var, Prefix: prefix, Dir: dir, Var: strings.TrimSuffix(g.Var, "Router") + "SPAFiles"})
+	}
+	return spas, nil
+}
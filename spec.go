@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const specTemplate = `
+// openAPISpec is the OpenAPI description of the generated routes
+const openAPISpec = {{.Spec}}
+
+// serveSpec returns the OpenAPI description of the generated routes
+func serveSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, openAPISpec)
+}
+`
+
+// openAPIDoc is the OpenAPI skeleton served by -serveSpec.
+type openAPIDoc struct {
+	OpenAPI string                                 `json:"openapi"`
+	Info    openAPIInfo                            `json:"info"`
+	Servers []openAPIServer                        `json:"servers,omitempty"`
+	Paths   map[string]map[string]openAPIOperation `json:"paths"`
+	Tags    []openAPITag                           `json:"tags,omitempty"`
+}
+
+type openAPITag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIServer struct {
+	URL string `json:"url"`
+}
+
+type openAPIOperation struct {
+	OperationID string             `json:"operationId"`
+	Tags        []string           `json:"tags,omitempty"`
+	Deprecated  bool               `json:"deprecated,omitempty"`
+	Parameters  []openAPIParameter `json:"parameters,omitempty"`
+	RequestBody *openAPIBody       `json:"requestBody,omitempty"`
+	Responses   map[string]any     `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required"`
+	Schema   map[string]any `json:"schema"`
+}
+
+type openAPIBody struct {
+	Required bool                      `json:"required"`
+	Content  map[string]map[string]any `json:"content"`
+}
+
+// buildSpec renders the routes as an OpenAPI document and returns it as a
+// quoted Go string literal. title is the -importPREFIX. Route paths are
+// relative to stripPrefix, which becomes the server URL. request.schema.json
+// files describe request bodies. Groups with a Doc become tags describing
+// their routes. Fallback routes are left out.
+func buildSpec(routes []route, groups []group, schemas map[string][]byte, title, stripPrefix, root string) (string, error) {
+	doc := openAPIDoc{
+		OpenAPI: "3.0.3",
+		Info:    openAPIInfo{Title: title, Version: "1.0.0"},
+		Paths:   make(map[string]map[string]openAPIOperation),
+	}
+	if stripPrefix != "" {
+		doc.Servers = []openAPIServer{{URL: stripPrefix}}
+	}
+
+	described := make(map[string]bool)
+	for _, g := range groups {
+		if g.Doc != "" {
+			doc.Tags = append(doc.Tags, openAPITag{Name: g.Name, Description: g.Doc})
+			described[g.Name] = true
+		}
+	}
+
+	for _, rt := range routes {
+		// OpenAPI has no operation for any method, and internal routes are
+		// not part of the public API.
+		if rt.Method == "" || rt.Internal {
+			continue
+		}
+		// Method names do not depend on the parameter case, so any case
+		// gives the client's name and camel cannot fail.
+		m, _ := newClientMethod(rt, "camel")
+		op := openAPIOperation{
+			OperationID: m.Name,
+			Tags:        rt.Tags,
+			Deprecated:  rt.Deprecated,
+			Responses:   map[string]any{"default": map[string]string{"description": "response from " + rt.Alias + "." + rt.Handler}},
+		}
+		if described[rt.Group] {
+			op.Tags = append(slices.Clone(op.Tags), rt.Group)
+		}
+		for _, seg := range strings.Split(rt.RoutePath, "/") {
+			if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+				name := seg[1 : len(seg)-1]
+				typ := "string"
+				if i := slices.IndexFunc(rt.Params, func(p typedParam) bool { return p.Name == name }); i >= 0 {
+					typ = paramTypes[rt.Params[i].Type].openAPI
+				}
+				op.Parameters = append(op.Parameters, openAPIParameter{
+					Name:     name,
+					In:       "path",
+					Required: true,
+					Schema:   map[string]any{"type": typ},
+				})
+			}
+		}
+		if data, ok := schemas[rt.Dir]; ok && hasBody(rt.Method) {
+			var schema map[string]any
+			if err := json.Unmarshal(data, &schema); err != nil {
+				return "", fmt.Errorf("%s: invalid JSON schema: %w", path.Join(root, rt.Dir, "request.schema.json"), err)
+			}
+			op.RequestBody = &openAPIBody{
+				Required: true,
+				Content:  map[string]map[string]any{"application/json": {"schema": schema}},
+			}
+		}
+
+		if doc.Paths[rt.RoutePath] == nil {
+			doc.Paths[rt.RoutePath] = make(map[string]openAPIOperation)
+		}
+		doc.Paths[rt.RoutePath][strings.ToLower(rt.Method)] = op
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Quote(string(data)), nil
+}
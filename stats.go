@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// apiStats summarizes the generated API for -stats.
+type apiStats struct {
+	Routes  int            `json:"routes"`
+	Methods map[string]int `json:"methods"`
+	Groups  int            `json:"groups"`
+	// Params counts distinct {param} segments, so /users/{id} and
+	// /users/{id}/posts share one.
+	Params int `json:"params"`
+	// Depth is the largest number of segments in a route path.
+	Depth int `json:"depth"`
+	// Middlewares counts distinct middleware functions applied anywhere.
+	Middlewares int `json:"middlewares"`
+	Redirects   int `json:"redirects"`
+}
+
+// collectStats computes the -stats summary from the route model.
+// middlewares are the global ones, final the -finalMiddleware.
+func collectStats(routes []route, groups []group, redirects []redirect, middlewares []string, final string) apiStats {
+	s := apiStats{Routes: len(routes), Methods: make(map[string]int), Groups: len(groups), Redirects: len(redirects)}
+	params := make(map[string]bool)
+	mws := make(map[string]bool)
+	for _, mw := range middlewares {
+		mws[mw] = true
+	}
+	if final != "" {
+		mws[final] = true
+	}
+	for _, g := range groups {
+		for _, mw := range g.Middlewares {
+			mws[mw] = true
+		}
+	}
+	for _, rt := range routes {
+		s.Methods[rt.methodLabel()]++
+		segs := strings.Split(strings.Trim(rt.RoutePath, "/"), "/")
+		if segs[0] == "" {
+			segs = nil
+		}
+		s.Depth = max(s.Depth, len(segs))
+		for i, seg := range segs {
+			if strings.HasPrefix(seg, "{") {
+				params[strings.Join(segs[:i+1], "/")] = true
+			}
+		}
+		for _, mw := range rt.Middlewares {
+			mws[mw] = true
+		}
+	}
+	s.Params, s.Middlewares = len(params), len(mws)
+	return s
+}
+
+// printStats writes s to stderr, as a single info line with a stats field
+// in JSON mode.
+func (l *logger) printStats(s apiStats) {
+	if l.json {
+		line, _ := json.Marshal(struct {
+			Level string   `json:"level"`
+			Msg   string   `json:"msg"`
+			Stats apiStats `json:"stats"`
+		}{"info", "stats", s})
+		fmt.Fprintln(l.stderr, string(line))
+		return
+	}
+
+	methods := make([]string, 0, len(s.Methods))
+	for m := range s.Methods {
+		methods = append(methods, m)
+	}
+	slices.Sort(methods)
+	perMethod := make([]string, len(methods))
+	for i, m := range methods {
+		perMethod[i] = fmt.Sprintf("%s %d", m, s.Methods[m])
+	}
+
+	fmt.Fprintf(l.stderr, "routes:          %d", s.Routes)
+	if len(perMethod) > 0 {
+		fmt.Fprintf(l.stderr, " (%s)", strings.Join(perMethod, ", "))
+	}
+	fmt.Fprintln(l.stderr)
+	fmt.Fprintf(l.stderr, "groups:          %d\n", s.Groups)
+	fmt.Fprintf(l.stderr, "dynamic params:  %d\n", s.Params)
+	fmt.Fprintf(l.stderr, "deepest nesting: %d\n", s.Depth)
+	fmt.Fprintf(l.stderr, "middlewares:     %d\n", s.Middlewares)
+	fmt.Fprintf(l.stderr, "redirects:       %d\n", s.Redirects)
+}
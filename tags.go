@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+const tagGroupTemplate = `// Code generated by fsrouter; DO NOT EDIT.
+
+//go:build {{.Tag}}
+
+package {{.Package}}
+
+import (
+{{range .StdImports}}	"{{.}}"
+{{end}}{{range .Imports}}{{if .Comment}}
+	// {{.Comment}}
+{{end}}	{{.Alias}} "{{.Path}}"
+{{end}}
+	"github.com/gorilla/mux"
+)
+
+func init() {
+	buildTagRoutes = append(buildTagRoutes, {{.Func}})
+{{if .HandlerMap}}{{range .Routes}}	Handlers[{{handlerKey .}}] = {{.NewHandlerFunc}}
+{{end}}{{end}}}
+
+// {{.Func}} registers the route groups only built with the {{.Tag}} tag
+func {{.Func}}(r *mux.Router, enabled map[string]bool, raw bool) {
+{{template "groupRoutes" .}}}
+`
+
+// groupRoutesTemplate is the body of a function registering route groups
+// and their routes on r, skipping middleware when raw is set. It serves the
+// files of -tagGroups and the version functions of -versionDirs.
+const groupRoutesTemplate = `{{range .Receivers}}	{{.Var}} := {{.Alias}}.New()
+{{end}}{{range .Groups}}
+	// Route group for {{.Name}}{{if .Doc}}
+	// {{.Doc}}{{end}}
+	{{.Var}} := {{.Parent}}.PathPrefix("{{.Prefix}}").Subrouter()
+{{if .MethodNotAllowed}}	{{.Var}}.MethodNotAllowedHandler = http.HandlerFunc({{.MethodNotAllowed}})
+{{end}}{{if .Middlewares}}	if !raw {
+{{$router := .Var}}{{range .Middlewares}}		{{$router}}.Use({{.}})
+{{end}}	}
+{{end}}{{end}}
+{{range .Routes}}{{$in := ""}}{{if .Guard}}{{$in = "\t"}}	if {{.Guard}} {
+{{end}}{{if not .HandlerExpr}}{{$in}}	{{.Router}}.HandleFunc("{{.SubPath}}", {{.HandlerFunc}}){{if .Method}}.Methods("{{.Method}}"){{end}}{{.Matchers}}
+{{else if $.Profile}}{{$in}}	if raw {
+{{$in}}		{{.Router}}.HandleFunc("{{.SubPath}}", {{.HandlerFunc}}){{if .Method}}.Methods("{{.Method}}"){{end}}{{.Matchers}}
+{{$in}}	} else {
+{{$in}}		{{.Router}}.Handle("{{.SubPath}}", {{.HandlerExpr}}){{if .Method}}.Methods("{{.Method}}"){{end}}{{.Matchers}}
+{{$in}}	}
+{{else}}{{$in}}	{{.Router}}.Handle("{{.SubPath}}", {{.HandlerExpr}}){{if .Method}}.Methods("{{.Method}}"){{end}}{{.Matchers}}
+{{end}}{{if .Guard}}	}
+{{end}}{{end}}{{if .CORSRouters}}
+	// Preflight requests for paths without an OPTIONS handler
+{{range .CORSRouters}}	{{.}}.Methods(http.MethodOptions).Headers("Access-Control-Request-Method", "").HandlerFunc(corsPreflight)
+{{end}}{{end}}`
+
+// tagGroupFile is a file holding the groups that -tagGroups gates behind
+// one build tag.
+type tagGroupFile struct {
+	Tag         string
+	Path        string
+	Groups      []group
+	Routes      []route
+	CORSRouters []string
+}
+
+// assignGates sets the build tag of each group named by -tagGroups and of
+// the groups nested in it. Only top-level groups can be gated, since the
+// generated file hangs its subrouters off the top-level router.
+func assignGates(groups []group, tagGroups map[string]string) error {
+	for name, tag := range tagGroups {
+		if !isBuildTag(tag) || tag == devBuildTag {
+			return fmt.Errorf("invalid build tag %q for %s, want an identifier other than %q", tag, name, devBuildTag)
+		}
+		if !slices.ContainsFunc(groups, func(g group) bool { return g.Name == name && g.Parent == "r" }) {
+			return fmt.Errorf("%s is not a top-level route group", name)
+		}
+	}
+
+	gates := make(map[string]string)
+	for i, g := range groups {
+		if g.Parent == "r" {
+			groups[i].Gate = tagGroups[g.Name]
+		} else {
+			groups[i].Gate = gates[g.Parent]
+		}
+		gates[g.Var] = groups[i].Gate
+	}
+	return nil
+}
+
+// isBuildTag reports whether tag can be used in a //go:build line and a file
+// name.
+func isBuildTag(tag string) bool {
+	if tag == "" {
+		return false
+	}
+	for _, c := range tag {
+		if c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// tagOutPath derives a gated file's path from -out, e.g. routes_gen.go
+// becomes routes_admin_gen.go for the admin tag.
+func tagOutPath(out, tag string) string {
+	if strings.HasSuffix(out, "_gen.go") {
+		return strings.TrimSuffix(out, "_gen.go") + "_" + tag + "_gen.go"
+	}
+	return strings.TrimSuffix(out, ".go") + "_" + tag + ".go"
+}
+
+// splitGated separates the gated groups, routes and CORS routers from those
+// registered by the main file, returning the gated ones grouped by tag in
+// tag order.
+func splitGated(out string, routes []route, groups []group, corsRouters []string) ([]route, []group, []string, []tagGroupFile) {
+	gates := make(map[string]string)
+	byTag := make(map[string]*tagGroupFile)
+	file := func(tag string) *tagGroupFile {
+		if byTag[tag] == nil {
+			byTag[tag] = &tagGroupFile{Tag: tag, Path: tagOutPath(out, tag)}
+		}
+		return byTag[tag]
+	}
+
+	var mainGroups []group
+	for _, g := range groups {
+		gates[g.Var] = g.Gate
+		if g.Gate == "" {
+			mainGroups = append(mainGroups, g)
+			continue
+		}
+		f := file(g.Gate)
+		f.Groups = append(f.Groups, g)
+	}
+
+	var mainRoutes []route
+	for _, rt := range routes {
+		if gate := gates[rt.Router]; gate != "" {
+			f := file(gate)
+			f.Routes = append(f.Routes, rt)
+			continue
+		}
+		mainRoutes = append(mainRoutes, rt)
+	}
+
+	var mainCORS []string
+	for _, v := range corsRouters {
+		if gate := gates[v]; gate != "" {
+			f := file(gate)
+			f.CORSRouters = append(f.CORSRouters, v)
+			continue
+		}
+		mainCORS = append(mainCORS, v)
+	}
+
+	var files []tagGroupFile
+	for _, f := range byTag {
+		files = append(files, *f)
+	}
+	slices.SortFunc(files, func(a, b tagGroupFile) int { return strings.Compare(a.Tag, b.Tag) })
+	return mainRoutes, mainGroups, mainCORS, files
+}
+
+// writeTagGroupFile generates a gated file, importing only the handler
+// packages and the standard library or middleware packages it refers to.
+func writeTagGroupFile(w *outputWriter, f tagGroupFile, pkg, middlewarePkg string, profile, groupImports, handlerMap bool) error {
+	var emitted []string
+	for _, g := range f.Groups {
+		emitted = append(emitted, g.Middlewares...)
+	}
+	for _, rt := range f.Routes {
+		emitted = append(emitted, rt.HandlerExpr, rt.Guard)
+	}
+	if len(f.CORSRouters) > 0 {
+		emitted = append(emitted, "http.MethodOptions")
+	}
+
+	var stdImports []string
+	if usesPackage("http", emitted) {
+		stdImports = append(stdImports, "net/http")
+	}
+	if usesPackage("time", emitted) {
+		stdImports = append(stdImports, "time")
+	}
+	if usesPackage("os", emitted) {
+		stdImports = append(stdImports, "os")
+	}
+	imports := handlerImports(f.Routes, groupImports)
+	if middlewarePkg != "" && usesPackage("middleware", emitted) {
+		imports = append(imports, packageImport(middlewarePkg, "middleware", groupImports))
+	}
+
+	var buf bytes.Buffer
+	tmpl := template.Must(template.New("tagGroup").Funcs(template.FuncMap{"handlerKey": handlerKey}).Parse(tagGroupTemplate))
+	template.Must(tmpl.New("groupRoutes").Parse(groupRoutesTemplate))
+	err := tmpl.Execute(&buf, struct {
+		tagGroupFile
+		Package    string
+		Func       string
+		StdImports []string
+		Imports    []importEntry
+		Profile    bool
+		HandlerMap bool
+		Receivers  []receiverVar
+	}{
+		tagGroupFile: f,
+		Package:      pkg,
+		Func:         "register" + exportedName(f.Tag) + "Routes",
+		StdImports:   stdImports,
+		Imports:      imports,
+		Profile:      profile,
+		HandlerMap:   handlerMap,
+		Receivers:    receiverVars(f.Routes),
+	})
+	if err != nil {
+		return err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("formatting generated %s file: %w", f.Tag, err)
+	}
+	return w.write(f.Path, src)
+}
+
+// removeStaleTagGroupFiles deletes gated files generated for tags that
+// -tagGroups no longer lists, since they refer to a hook the main file may
+// no longer declare. Files not generated by fsrouter are left alone.
+func removeStaleTagGroupFiles(w *outputWriter, out string, current []tagGroupFile) error {
+	matches, err := filepath.Glob(tagOutPath(out, "*"))
+	if err != nil {
+		return err
+	}
+	for _, m := range matches {
+		if m == devOutPath(out) || slices.ContainsFunc(current, func(f tagGroupFile) bool { return f.Path == m }) {
+			continue
+		}
+		data, err := os.ReadFile(m)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if bytes.HasPrefix(data, []byte("// Code generated by fsrouter; DO NOT EDIT.")) && bytes.Contains(data, []byte("buildTagRoutes = append(")) {
+			if err := w.remove(m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// printTree renders the discovered groups, routes and redirects as an ASCII
+// tree, starting from the top-level router.
+func printTree(w io.Writer, routes []route, groups []group, redirects []redirect, middlewares []string, finalMiddleware string) {
+	fmt.Fprintf(w, "r%s\n", middlewareLabel(middlewares))
+	if finalMiddleware != "" {
+		fmt.Fprintf(w, "final middleware: %s\n", finalMiddleware)
+	}
+	printTreeChildren(w, "", "r", routes, groups, redirects)
+}
+
+func printTreeChildren(w io.Writer, indent, router string, routes []route, groups []group, redirects []redirect) {
+	var lines []string
+	var nested []string
+	if router == "r" {
+		for _, rd := range redirects {
+			lines = append(lines, fmt.Sprintf("REDIRECT %s -> %s (%d)", rd.From, rd.To, rd.Status))
+			nested = append(nested, "")
+		}
+	}
+	for _, g := range groups {
+		if g.Parent == router {
+			line := fmt.Sprintf("%s (%s)%s", g.Name, g.Prefix, middlewareLabel(g.Middlewares))
+			if g.Gate != "" && g.Parent == "r" {
+				line += " //go:build " + g.Gate
+			}
+			lines = append(lines, line)
+			nested = append(nested, g.Var)
+		}
+	}
+	for _, rt := range routes {
+		if rt.Router == router {
+			lines = append(lines, fmt.Sprintf("%s %s -> %s.%s%s", rt.methodLabel(), rt.RoutePath, rt.Alias, rt.Handler, middlewareLabel(rt.Middlewares)))
+			nested = append(nested, "")
+		}
+	}
+
+	for i, line := range lines {
+		branch, next := "├── ", "│   "
+		if i == len(lines)-1 {
+			branch, next = "└── ", "    "
+		}
+		fmt.Fprintf(w, "%s%s%s\n", indent, branch, line)
+		if nested[i] != "" {
+			printTreeChildren(w, indent+next, nested[i], routes, groups, redirects)
+		}
+	}
+}
+
+func middlewareLabel(middlewares []string) string {
+	if len(middlewares) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(middlewares, ", ") + "]"
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+const tsTemplate = `// Code generated by fsrouter; DO NOT EDIT.
+{{range .}}
+// {{.Methods}} {{.Route}}
+{{if .Params}}export const {{.Name}} = ({{range $i, $p := .Params}}{{if $i}}, {{end}}{{$p}}: string{{end}}): string =>
+  ` + "`" + `{{.Template}}` + "`" + `;
+{{else}}export const {{.Name}} = {{printf "%q" .Route}} as const;
+{{end}}{{end}}`
+
+// tsRoute is one exported constant of the -genTS module, a string for
+// static paths and a function interpolating the params of dynamic ones.
+type tsRoute struct {
+	Name     string
+	Methods  string
+	Route    string
+	Params   []string
+	Template string
+}
+
+// tsReserved are the JavaScript reserved words, which cannot name a
+// constant or parameter.
+var tsReserved = map[string]bool{
+	"await": true, "break": true, "case": true, "catch": true, "class": true,
+	"const": true, "continue": true, "debugger": true, "default": true,
+	"delete": true, "do": true, "else": true, "enum": true, "export": true,
+	"extends": true, "false": true, "finally": true, "for": true,
+	"function": true, "if": true, "implements": true, "import": true,
+	"in": true, "instanceof": true, "interface": true, "let": true,
+	"new": true, "null": true, "package": true, "private": true,
+	"protected": true, "public": true, "return": true, "static": true,
+	"super": true, "switch": true, "this": true, "throw": true, "true": true,
+	"try": true, "typeof": true, "var": true, "void": true, "while": true,
+	"with": true, "yield": true,
+}
+
+// writeTS generates a TypeScript module exporting each route path once and
+// returns the number of paths. GET /users/{userId} becomes
+// export const usersUserId = (userId: string): string => `/users/${...}`.
+func writeTS(w *outputWriter, out string, routes []route) (int, error) {
+	var paths []*tsRoute
+	byPath := make(map[string]*tsRoute)
+	owners := make(map[string]string)
+
+	for _, rt := range routes {
+		if rt.Internal {
+			continue
+		}
+		if p, ok := byPath[rt.RoutePath]; ok {
+			p.Methods += ", " + rt.methodLabel()
+			continue
+		}
+		p := newTSRoute(rt.RoutePath)
+		if prev, ok := owners[p.Name]; ok {
+			return 0, fmt.Errorf("TypeScript constant %s would be generated for both %s and %s", p.Name, prev, rt.RoutePath)
+		}
+		owners[p.Name] = rt.RoutePath
+		p.Methods = rt.methodLabel()
+		byPath[rt.RoutePath] = p
+		paths = append(paths, p)
+	}
+
+	var buf bytes.Buffer
+	tmpl := template.Must(template.New("ts").Parse(tsTemplate))
+	if err := tmpl.Execute(&buf, paths); err != nil {
+		return 0, err
+	}
+	return len(paths), w.write(out, buf.Bytes())
+}
+
+// newTSRoute derives the constant for a route path. Names are camelCased
+// path segments without Go initialisms, as is usual in TypeScript.
+func newTSRoute(routePath string) *tsRoute {
+	p := &tsRoute{Route: routePath}
+	var words []string
+	var tmpl strings.Builder
+	for _, seg := range strings.Split(strings.Trim(routePath, "/"), "/") {
+		if seg == "" {
+			continue
+		}
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			param, _, _ := strings.Cut(seg[1:len(seg)-1], ":")
+			words = append(words, identWords(param)...)
+			arg := tsIdent(identWords(param), "param", "Param")
+			p.Params = append(p.Params, arg)
+			tmpl.WriteString("/${encodeURIComponent(" + arg + ")}")
+			continue
+		}
+		words = append(words, identWords(seg)...)
+		tmpl.WriteString("/" + strings.NewReplacer("`", "\\`", "$", "\\$", "\\", "\\\\").Replace(seg))
+	}
+	p.Name = tsIdent(words, "root", "Path")
+	p.Template = tmpl.String()
+	return p
+}
+
+// tsIdent joins words into a camelCase identifier, using empty for no
+// words and adding suffix to reserved words.
+func tsIdent(words []string, empty, suffix string) string {
+	if len(words) == 0 {
+		return empty
+	}
+	var b strings.Builder
+	for i, w := range words {
+		r := []rune(strings.ToLower(w))
+		if i > 0 {
+			r[0] = unicode.ToUpper(r[0])
+		}
+		b.WriteString(string(r))
+	}
+	name := b.String()
+	if unicode.IsDigit([]rune(name)[0]) {
+		name = "p" + name
+	}
+	if tsReserved[name] {
+		name += suffix
+	}
+	return name
+}
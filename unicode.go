@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// escapeNonASCII percent-encodes the bytes in the literal segments of a
+// route path that URL.EscapedPath escapes but _path segments may hold:
+// non-ASCII letters and digits. -encodedPath routes are matched against
+// the escaped path, so their literals must be escaped the same way.
+// {param} segments are kept, since their names are not matched.
+func escapeNonASCII(p string) string {
+	segs := strings.Split(p, "/")
+	for i, seg := range segs {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			continue
+		}
+		var b strings.Builder
+		for j := 0; j < len(seg); j++ {
+			if c := seg[j]; c >= 0x80 {
+				fmt.Fprintf(&b, "%%%02X", c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+		segs[i] = b.String()
+	}
+	return strings.Join(segs, "/")
+}
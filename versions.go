@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// versionRouter is a version directory registered by its own function for
+// -versionDirs, with the groups, routes and CORS routers under it.
+type versionRouter struct {
+	Name        string
+	Func        string
+	Prefix      string
+	Groups      []group
+	Routes      []route
+	CORSRouters []string
+	Receivers   []receiverVar
+	Profile     bool
+}
+
+// isVersionDir reports whether a top-level directory name is an API version
+// for -versionDirs: v followed by a number and optionally a lowercase
+// suffix, such as v1, v2 or v3beta1.
+func isVersionDir(name string) bool {
+	if len(name) < 2 || name[0] != 'v' || name[1] < '0' || name[1] > '9' {
+		return false
+	}
+	return strings.IndexFunc(name[1:], notLowerAlnum) < 0
+}
+
+// assignVersions sets the Version of each top-level version group to the
+// name its register function is built from, such as V1, and of the groups
+// nested in it. It returns the number of version groups. Versions gated by
+// -tagGroups are an error, since the gated file registers them itself.
+func assignVersions(groups []group) (int, error) {
+	n := 0
+	versions := make(map[string]string)
+	for i, g := range groups {
+		switch {
+		case g.Parent != "r":
+			groups[i].Version = versions[g.Parent]
+		case isVersionDir(g.Name) && g.Gate != "":
+			return 0, fmt.Errorf("%s is compiled in by the %s build tag, version directories must always be", g.Name, g.Gate)
+		case isVersionDir(g.Name):
+			groups[i].Version = exportedName(g.Name)
+			n++
+		}
+		versions[g.Var] = groups[i].Version
+	}
+	return n, nil
+}
+
+// splitVersions separates the groups, routes and CORS routers of version
+// directories from the rest. The top-level group of each version stays in
+// the returned groups, marking where RegisterRoutes calls its function.
+func splitVersions(routes []route, groups []group, corsRouters []string, profile bool) ([]route, []group, []string, []versionRouter) {
+	versions := make(map[string]string)
+	var byVersion []*versionRouter
+	index := make(map[string]*versionRouter)
+
+	var mainGroups []group
+	for _, g := range groups {
+		versions[g.Var] = g.Version
+		if g.Version == "" {
+			mainGroups = append(mainGroups, g)
+			continue
+		}
+		if g.Parent == "r" {
+			mainGroups = append(mainGroups, g)
+			v := &versionRouter{Name: g.Name, Func: g.Version, Prefix: g.Prefix, Profile: profile}
+			index[g.Version] = v
+			byVersion = append(byVersion, v)
+		}
+		v := index[g.Version]
+		v.Groups = append(v.Groups, g)
+	}
+
+	var mainRoutes []route
+	for _, rt := range routes {
+		if version := versions[rt.Router]; version != "" {
+			v := index[version]
+			v.Routes = append(v.Routes, rt)
+			continue
+		}
+		mainRoutes = append(mainRoutes, rt)
+	}
+
+	var mainCORS []string
+	for _, r := range corsRouters {
+		if version := versions[r]; version != "" {
+			v := index[version]
+			v.CORSRouters = append(v.CORSRouters, r)
+			continue
+		}
+		mainCORS = append(mainCORS, r)
+	}
+
+	var routers []versionRouter
+	for _, v := range byVersion {
+		v.Receivers = receiverVars(v.Routes)
+		routers = append(routers, *v)
+	}
+	return mainRoutes, mainGroups, mainCORS, routers
+}
+
+// versionsTemplate declares, for each version directory, the exported
+// function building a router of its own and the function registering its
+// groups, which RegisterRoutes calls too.
+const versionsTemplate = `{{range $v := .Versions}}
+// Register{{$v.Func}}Routes creates and returns a router with only the routes of
+// the {{$v.Name}} version directory, under {{$v.Prefix}} as in RegisterRoutes, for serving
+// the version on its own{{if $.Tagged}}.
+// Tagged routes are only registered when one of their tags is requested{{end}}{{if $.RouterOptions}}.
+// The Options replace its 404 handler or add global middleware{{end}}
+func Register{{$v.Func}}Routes({{if $.Tagged}}tags ...string{{else if $.RouterOptions}}opts ...Option{{end}}) *mux.Router {
+{{if $.RouterOptions}}	o := applyOptions(opts)
+{{end}}	r := mux.NewRouter()
+{{if $.EncodedPath}}	r.UseEncodedPath()
+{{end}}{{if $.Tagged}}
+	enabled := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		enabled[tag] = true
+	}
+{{end}}
+	// Default 404 handler
+	r.NotFoundHandler = {{if $.ProxyFallback}}proxyFallback(){{else}}http.HandlerFunc({{if $.NotFound}}{{$.NotFound}}{{else}}defaultNotFoundHandler{{end}}){{end}}
+{{if $.RouterOptions}}	if o.notFound != nil {
+		r.NotFoundHandler = o.notFound
+	}
+{{end}}{{if $.MethodNotAllowed}}	r.MethodNotAllowedHandler = http.HandlerFunc({{$.MethodNotAllowed}})
+{{end}}
+	// Global middleware (applied to all routes)
+{{range $.Middlewares}}	r.Use({{.}})
+{{end}}{{if $.RouterOptions}}	for _, mw := range o.middlewares {
+		r.Use(mw)
+	}
+{{end}}{{if $.DevMiddlewares}}	if devMiddlewareHook != nil {
+		devMiddlewareHook(r)
+	}
+{{end}}
+	add{{$v.Func}}Routes(r, {{if $.Tagged}}enabled{{else}}nil{{end}}, false)
+	return r
+}
+
+// add{{$v.Func}}Routes registers the route groups of the {{$v.Name}} version directory
+// on r, for RegisterRoutes and Register{{$v.Func}}Routes
+func add{{$v.Func}}Routes(r *mux.Router, enabled map[string]bool, raw bool) {
+{{template "groupRoutes" $v}}}
+{{end}}`